@@ -0,0 +1,178 @@
+// Package boltcache 基于bbolt的持久化本地缓存，进程重启后缓存依然可用
+// 独立为单独的module，只有引入本包的调用方才会依赖bbolt
+package boltcache
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kakkk/gopkg/cachex"
+)
+
+// +------------------------+----------------+
+// | ExpireAt               | Value          |
+// +------------------------+----------------+
+// ↑                        ↑
+// 第0字节                  第8字节
+//
+// ExpireAt为过期时间的UnixNano，0表示不过期
+
+const expireAtSize = 8
+
+var defaultBucket = []byte("cachex")
+
+// BoltCacher 基于bbolt的Cacher实现，过期的key在读取时惰性删除
+type BoltCacher struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+var _ cachex.Cacher = (*BoltCacher)(nil)
+
+// NewBoltCacher 打开(不存在时创建)path对应的bbolt文件作为缓存
+// 同一个文件同时只能被一个进程打开，使用完需要调用Close
+func NewBoltCacher(path string) (*BoltCacher, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt open error: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(defaultBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("bolt create bucket error: %w", err)
+	}
+	return &BoltCacher{
+		db:     db,
+		bucket: defaultBucket,
+	}, nil
+}
+
+// Close 关闭bbolt文件
+func (b *BoltCacher) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltCacher) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := b.MGet(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	return res[key], nil
+}
+
+func (b *BoltCacher) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(keys))
+	expired := make([]string, 0)
+	now := time.Now().UnixNano()
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		for _, key := range keys {
+			data := bucket.Get([]byte(key))
+			if len(data) < expireAtSize {
+				result[key] = nil
+				continue
+			}
+			expireAt := int64(binary.LittleEndian.Uint64(data[:expireAtSize]))
+			if expireAt > 0 && expireAt <= now {
+				result[key] = nil
+				expired = append(expired, key)
+				continue
+			}
+			// bbolt返回的数据只在事务内有效，需要拷贝
+			result[key] = append([]byte(nil), data[expireAtSize:]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bolt get error: %w", err)
+	}
+	// 惰性删除已过期的key，删除失败不影响读取结果
+	if len(expired) > 0 {
+		_ = b.deleteExpired(expired)
+	}
+	return result, nil
+}
+
+func (b *BoltCacher) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	return b.MSet(ctx, map[string][]byte{key: val}, ttl)
+}
+
+func (b *BoltCacher) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl).UnixNano()
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		for key, val := range kvs {
+			data := make([]byte, expireAtSize+len(val))
+			binary.LittleEndian.PutUint64(data[:expireAtSize], uint64(expireAt))
+			copy(data[expireAtSize:], val)
+			if err := bucket.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("bolt set error: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltCacher) Delete(ctx context.Context, key string) error {
+	return b.MDelete(ctx, []string{key})
+}
+
+func (b *BoltCacher) MDelete(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		for _, key := range keys {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("bolt delete error: %w", err)
+	}
+	return nil
+}
+
+// deleteExpired 删除已过期的key，删除前再次检查，避免删掉期间被重新写入的值
+func (b *BoltCacher) deleteExpired(keys []string) error {
+	now := time.Now().UnixNano()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		for _, key := range keys {
+			data := bucket.Get([]byte(key))
+			if len(data) < expireAtSize {
+				continue
+			}
+			expireAt := int64(binary.LittleEndian.Uint64(data[:expireAtSize]))
+			if expireAt > 0 && expireAt <= now {
+				if err := bucket.Delete([]byte(key)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
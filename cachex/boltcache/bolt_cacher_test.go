@@ -0,0 +1,93 @@
+package boltcache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltCacher(t *testing.T) {
+	ctx := context.Background()
+	cacher, err := NewBoltCacher(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer cacher.Close()
+
+	t.Run("set get delete", func(t *testing.T) {
+		require.NoError(t, cacher.Set(ctx, "k1", []byte("v1"), time.Minute))
+		got, err := cacher.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), got)
+
+		got, err = cacher.Get(ctx, "not-exist")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+
+		require.NoError(t, cacher.Delete(ctx, "k1"))
+		got, err = cacher.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("mset mget mdelete", func(t *testing.T) {
+		require.NoError(t, cacher.MSet(ctx, map[string][]byte{"m1": []byte("a"), "m2": []byte("b")}, time.Minute))
+		got, err := cacher.MGet(ctx, []string{"m1", "m2", "m3"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"m1": []byte("a"), "m2": []byte("b"), "m3": nil}, got)
+
+		require.NoError(t, cacher.MDelete(ctx, []string{"m1", "m2"}))
+		got, err = cacher.MGet(ctx, []string{"m1", "m2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"m1": nil, "m2": nil}, got)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		require.NoError(t, cacher.Set(ctx, "short", []byte("v"), 50*time.Millisecond))
+		require.NoError(t, cacher.Set(ctx, "forever", []byte("v"), 0))
+		time.Sleep(100 * time.Millisecond)
+
+		got, err := cacher.Get(ctx, "short")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+		got, err = cacher.Get(ctx, "forever")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v"), got)
+
+		// 读取时已惰性删除
+		err = cacher.db.View(func(tx *bolt.Tx) error {
+			assert.Nil(t, tx.Bucket(cacher.bucket).Get([]byte("short")))
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ctx canceled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := cacher.Get(cctx, "k1")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, cacher.Set(cctx, "k1", []byte("v"), time.Minute), context.Canceled)
+	})
+}
+
+func TestBoltCacherReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	cacher, err := NewBoltCacher(path)
+	require.NoError(t, err)
+	require.NoError(t, cacher.Set(ctx, "persist", []byte("v"), time.Minute))
+	require.NoError(t, cacher.Close())
+
+	// 重新打开后数据依然存在
+	cacher, err = NewBoltCacher(path)
+	require.NoError(t, err)
+	defer cacher.Close()
+	got, err := cacher.Get(ctx, "persist")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), got)
+}
@@ -8,26 +8,78 @@ import (
 )
 
 type builder[K any, V any] struct {
-	namespace string              // 命名空间，用于区分key
-	codec     Codec[V]            // 编解码
-	expireTTL time.Duration       // 缓存过期时间
-	delTTL    time.Duration       // 缓存删除时间
-	logger    Logger              // logger
-	l1        Cacher              // 一级缓存
-	l2        Cacher              // 二级缓存
-	genKeyFn  GenKeyFn[K]         // 生成缓存key函数
-	loaderFn  LoaderFn[K, V]      // 单个回源函数
-	mLoaderFn MultiLoaderFn[K, V] // 批量回源函数
-	cacheNil  bool                // 是否缓存空值
-	ss        SourceStrategy      // 缓存策略
+	namespace       string               // 命名空间，用于区分key
+	escapeNS        bool                 // 是否转义命名空间中的分隔符
+	codec           Codec[V]             // 编解码
+	expireTTL       time.Duration        // 缓存过期时间
+	delTTL          time.Duration        // 缓存删除时间
+	logger          Logger               // logger
+	l1              Cacher               // 一级缓存
+	l2              Cacher               // 二级缓存
+	genKeyFn        GenKeyFn[K]          // 生成缓存key函数
+	batchKeyFn      BatchKeyFn[K]        // 生成批量回源singleflight key函数
+	loaderFn        LoaderFn[K, V]       // 单个回源函数
+	mLoaderFn       MultiLoaderFn[K, V]  // 批量回源函数
+	mLoaderFnE      MultiLoaderFnE[K, V] // 可部分失败的批量回源函数
+	fbLoaderFn      LoaderFn[K, V]       // 备用回源函数
+	cacheNil        bool                 // 是否缓存空值
+	ss              SourceStrategy       // 缓存策略
+	errHandler      CacheErrorHandlerFn  // 读缓存错误处理
+	onSerErr        CodecErrorFn         // 序列化失败回调
+	onDeserErr      CodecErrorFn         // 反序列化失败回调
+	requireCache    bool                 // 是否必须配置缓存
+	reloadOnCorrupt bool                 // 反序列化失败时是否当作未命中
+	corruptMetrics  CodecErrorFn         // 反序列化失败当作未命中时的指标上报
+	warmConcurrency int                  // 预热并发数
+	dedupeBackfill  bool                 // 是否合并L1回填写入
+	schemaVersion   uint8                // 缓存值的schema版本
+	delBatchSize    int                  // MDel每批删除的key数量
+	maxStaleness    time.Duration        // ExpiredBackup兜底时允许的最大过期时长
+	ttlJitter       time.Duration        // 删除时间增加的最大随机值
+	jitterRand      func(int64) int64    // 删除时间随机值的来源
+	loaderLock      LoaderLocker         // 缓存未命中时回源前获取的分布式锁
+	loaderLockTTL   time.Duration        // 回源锁的过期时间，也是等待锁的最长时间
+}
+
+// BaseConfig 与类型参数无关的builder配置，通过CacheBuilder.Base获取
+// 共享命名空间的缓存需要保证生成的key不重复，否则应在Derive后使用WithNamespace区分
+type BaseConfig struct {
+	namespace       string
+	escapeNS        bool
+	expireTTL       time.Duration
+	delTTL          time.Duration
+	logger          Logger
+	l1              Cacher
+	l2              Cacher
+	cacheNil        bool
+	ss              SourceStrategy
+	errHandler      CacheErrorHandlerFn
+	onSerErr        CodecErrorFn
+	onDeserErr      CodecErrorFn
+	requireCache    bool
+	reloadOnCorrupt bool
+	corruptMetrics  CodecErrorFn
+	warmConcurrency int
+	dedupeBackfill  bool
+	schemaVersion   uint8
+	delBatchSize    int
+	maxStaleness    time.Duration
+	ttlJitter       time.Duration
+	jitterRand      func(int64) int64
+	loaderLock      LoaderLocker
+	loaderLockTTL   time.Duration
 }
 
 func newBuilder[K any, V any]() CacheBuilder[K, V] {
 	return &builder[K, V]{
-		namespace: "default",
-		codec:     NewCodecJsonSonic[V](),
-		ss:        SourceStrategyCacheFirst,
-		logger:    newDefaultLogger(),
+		namespace:       "default",
+		codec:           NewCodecJsonSonic[V](),
+		ss:              SourceStrategyCacheFirst,
+		logger:          newDefaultLogger(),
+		reloadOnCorrupt: true,
+		dedupeBackfill:  true,
+		delBatchSize:    defaultDelBatchSize,
+		ttlJitter:       defaultTTLJitter,
 	}
 }
 
@@ -37,6 +89,12 @@ func (b *builder[K, V]) WithNamespace(namespace string) CacheBuilder[K, V] {
 	return bb
 }
 
+func (b *builder[K, V]) WithEscapeNamespace(escape bool) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.escapeNS = escape
+	return bb
+}
+
 func (b *builder[K, V]) WithExpireTTL(ttl time.Duration) CacheBuilder[K, V] {
 	bb := b.copy()
 	bb.expireTTL = ttl
@@ -73,6 +131,12 @@ func (b *builder[K, V]) WithGenKeyFn(fn GenKeyFn[K]) CacheBuilder[K, V] {
 	return bb
 }
 
+func (b *builder[K, V]) WithBatchKeyFn(fn BatchKeyFn[K]) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.batchKeyFn = fn
+	return bb
+}
+
 func (b *builder[K, V]) WithLoader(fn LoaderFn[K, V]) CacheBuilder[K, V] {
 	bb := b.copy()
 	bb.loaderFn = fn
@@ -85,6 +149,18 @@ func (b *builder[K, V]) WithMultiLoader(fn MultiLoaderFn[K, V]) CacheBuilder[K,
 	return bb
 }
 
+func (b *builder[K, V]) WithMultiLoaderE(fn MultiLoaderFnE[K, V]) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.mLoaderFnE = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithFallbackLoader(fn LoaderFn[K, V]) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.fbLoaderFn = fn
+	return bb
+}
+
 func (b *builder[K, V]) WithSourceStrategy(ss SourceStrategy) CacheBuilder[K, V] {
 	bb := b.copy()
 	bb.ss = ss
@@ -103,6 +179,162 @@ func (b *builder[K, V]) WithCodec(codec Codec[V]) CacheBuilder[K, V] {
 	return bb
 }
 
+func (b *builder[K, V]) WithAutoCodec() CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.codec = NewCodecAuto[V]()
+	return bb
+}
+
+func (b *builder[K, V]) WithCacheErrorHandler(fn CacheErrorHandlerFn) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.errHandler = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithOnSerializeError(fn CodecErrorFn) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.onSerErr = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithOnDeserializeError(fn CodecErrorFn) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.onDeserErr = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithRequireCache(require bool) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.requireCache = require
+	return bb
+}
+
+func (b *builder[K, V]) WithReloadOnCorrupt(reload bool) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.reloadOnCorrupt = reload
+	return bb
+}
+
+func (b *builder[K, V]) WithCorruptMetrics(fn CodecErrorFn) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.corruptMetrics = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithWarmConcurrency(n int) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.warmConcurrency = n
+	return bb
+}
+
+func (b *builder[K, V]) WithDedupeBackfill(dedupe bool) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.dedupeBackfill = dedupe
+	return bb
+}
+
+func (b *builder[K, V]) WithSchemaVersion(v uint8) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.schemaVersion = v
+	return bb
+}
+
+func (b *builder[K, V]) WithDelBatchSize(n int) CacheBuilder[K, V] {
+	bb := b.copy()
+	if n > 0 {
+		bb.delBatchSize = n
+	}
+	return bb
+}
+
+func (b *builder[K, V]) WithMaxStaleness(d time.Duration) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.maxStaleness = d
+	return bb
+}
+
+func (b *builder[K, V]) WithTTLJitter(jitter time.Duration) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.ttlJitter = max(jitter, 0)
+	return bb
+}
+
+func (b *builder[K, V]) WithJitterRand(fn func(int64) int64) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.jitterRand = fn
+	return bb
+}
+
+func (b *builder[K, V]) WithLoaderLock(locker LoaderLocker, ttl time.Duration) CacheBuilder[K, V] {
+	bb := b.copy()
+	bb.loaderLock = locker
+	bb.loaderLockTTL = ttl
+	return bb
+}
+
+func (b *builder[K, V]) Base() *BaseConfig {
+	return &BaseConfig{
+		namespace:       b.namespace,
+		escapeNS:        b.escapeNS,
+		expireTTL:       b.expireTTL,
+		delTTL:          b.delTTL,
+		logger:          b.logger,
+		l1:              b.l1,
+		l2:              b.l2,
+		cacheNil:        b.cacheNil,
+		ss:              b.ss,
+		errHandler:      b.errHandler,
+		onSerErr:        b.onSerErr,
+		onDeserErr:      b.onDeserErr,
+		requireCache:    b.requireCache,
+		reloadOnCorrupt: b.reloadOnCorrupt,
+		corruptMetrics:  b.corruptMetrics,
+		warmConcurrency: b.warmConcurrency,
+		dedupeBackfill:  b.dedupeBackfill,
+		schemaVersion:   b.schemaVersion,
+		delBatchSize:    b.delBatchSize,
+		maxStaleness:    b.maxStaleness,
+		ttlJitter:       b.ttlJitter,
+		jitterRand:      b.jitterRand,
+		loaderLock:      b.loaderLock,
+		loaderLockTTL:   b.loaderLockTTL,
+	}
+}
+
+// deriveBuilder 以base创建builder，base为nil时等同于newBuilder
+func deriveBuilder[K any, V any](base *BaseConfig) CacheBuilder[K, V] {
+	if base == nil {
+		return newBuilder[K, V]()
+	}
+	return &builder[K, V]{
+		namespace:       base.namespace,
+		escapeNS:        base.escapeNS,
+		codec:           NewCodecJsonSonic[V](),
+		expireTTL:       base.expireTTL,
+		delTTL:          base.delTTL,
+		logger:          base.logger,
+		l1:              base.l1,
+		l2:              base.l2,
+		cacheNil:        base.cacheNil,
+		ss:              base.ss,
+		errHandler:      base.errHandler,
+		onSerErr:        base.onSerErr,
+		onDeserErr:      base.onDeserErr,
+		requireCache:    base.requireCache,
+		reloadOnCorrupt: base.reloadOnCorrupt,
+		corruptMetrics:  base.corruptMetrics,
+		warmConcurrency: base.warmConcurrency,
+		dedupeBackfill:  base.dedupeBackfill,
+		schemaVersion:   base.schemaVersion,
+		delBatchSize:    base.delBatchSize,
+		maxStaleness:    base.maxStaleness,
+		ttlJitter:       base.ttlJitter,
+		jitterRand:      base.jitterRand,
+		loaderLock:      base.loaderLock,
+		loaderLockTTL:   base.loaderLockTTL,
+	}
+}
+
 func (b *builder[K, V]) Build() (CacheX[K, V], error) {
 	bb := b.copy()
 	// 命名空间不能为空
@@ -117,41 +349,99 @@ func (b *builder[K, V]) Build() (CacheX[K, V], error) {
 	if bb.l2 != nil && bb.l1 == nil {
 		return nil, fmt.Errorf("l1 cacher not set")
 	}
+	// 要求必须有缓存时，l1 l2 都为空
+	if bb.requireCache && bb.l1 == nil && bb.l2 == nil {
+		return nil, fmt.Errorf("cache required but l1 and l2 cacher not set")
+	}
+	// 回源锁需要有过期时间
+	if bb.loaderLock != nil && bb.loaderLockTTL <= 0 {
+		return nil, fmt.Errorf("loader lock ttl must be positive")
+	}
 	// l1 l2 loader mLoader 都为空
-	if bb.loaderFn == nil && bb.mLoaderFn == nil && b.l1 == nil && b.l2 == nil {
+	if bb.loaderFn == nil && bb.mLoaderFn == nil && bb.mLoaderFnE == nil && b.l1 == nil && b.l2 == nil {
 		return nil, fmt.Errorf("cacher and loader not set")
 	}
 
+	cache := newWrapper[V](bb.l1, bb.l2, bb.delTTL, bb.codec, bb.logger)
+	if bb.errHandler != nil {
+		cache.errHandler = bb.errHandler
+	}
+	cache.onSerErr = bb.onSerErr
+	cache.onDeserErr = bb.onDeserErr
+	cache.reloadOnCorrupt = bb.reloadOnCorrupt
+	cache.corruptMetrics = bb.corruptMetrics
+	cache.dedupeBackfill = bb.dedupeBackfill
+	cache.schemaVersion = bb.schemaVersion
+	cache.ttlJitter = bb.ttlJitter
+	if bb.jitterRand != nil {
+		cache.randInt63n = bb.jitterRand
+	}
+
 	cx := &cachex[K, V]{
-		namespace: bb.namespace,
-		codec:     bb.codec,
-		expireTTL: bb.expireTTL,
-		logger:    bb.logger,
-		cache:     newWrapper[V](bb.l1, bb.l2, bb.delTTL, bb.codec, bb.logger),
-		genKeyFn:  bb.genKeyFn,
-		loaderFn:  bb.loaderFn,
-		mLoaderFn: bb.mLoaderFn,
-		cacheNil:  bb.cacheNil,
-		group:     singleflight.Group{},
-		mGroup:    singleflight.Group{},
-		ss:        bb.ss,
+		namespace:       bb.cacheNamespace(),
+		codec:           bb.codec,
+		expireTTL:       bb.expireTTL,
+		logger:          bb.logger,
+		cache:           cache,
+		genKeyFn:        bb.genKeyFn,
+		batchKeyFn:      bb.batchKeyFn,
+		loaderFn:        bb.loaderFn,
+		mLoaderFn:       bb.mLoaderFn,
+		mLoaderFnE:      bb.mLoaderFnE,
+		fbLoaderFn:      bb.fbLoaderFn,
+		cacheNil:        bb.cacheNil,
+		group:           singleflight.Group{},
+		mGroup:          singleflight.Group{},
+		ss:              bb.ss,
+		warmConcurrency: bb.warmConcurrency,
+		delBatchSize:    bb.delBatchSize,
+		maxStaleness:    bb.maxStaleness,
+		loaderLock:      bb.loaderLock,
+		loaderLockTTL:   bb.loaderLockTTL,
 	}
 	return cx, nil
 }
 
+// cacheNamespace 缓存key使用的命名空间，开启转义时转义分隔符
+func (b *builder[K, V]) cacheNamespace() string {
+	if b.escapeNS {
+		return escapeNamespace(b.namespace)
+	}
+	return b.namespace
+}
+
 func (b *builder[K, V]) copy() *builder[K, V] {
 	return &builder[K, V]{
-		namespace: b.namespace,
-		codec:     b.codec,
-		expireTTL: b.expireTTL,
-		delTTL:    b.delTTL,
-		logger:    b.logger,
-		l1:        b.l1,
-		l2:        b.l2,
-		genKeyFn:  b.genKeyFn,
-		loaderFn:  b.loaderFn,
-		mLoaderFn: b.mLoaderFn,
-		cacheNil:  b.cacheNil,
-		ss:        b.ss,
+		namespace:       b.namespace,
+		escapeNS:        b.escapeNS,
+		codec:           b.codec,
+		expireTTL:       b.expireTTL,
+		delTTL:          b.delTTL,
+		logger:          b.logger,
+		l1:              b.l1,
+		l2:              b.l2,
+		genKeyFn:        b.genKeyFn,
+		batchKeyFn:      b.batchKeyFn,
+		loaderFn:        b.loaderFn,
+		mLoaderFn:       b.mLoaderFn,
+		mLoaderFnE:      b.mLoaderFnE,
+		fbLoaderFn:      b.fbLoaderFn,
+		cacheNil:        b.cacheNil,
+		ss:              b.ss,
+		errHandler:      b.errHandler,
+		onSerErr:        b.onSerErr,
+		onDeserErr:      b.onDeserErr,
+		requireCache:    b.requireCache,
+		reloadOnCorrupt: b.reloadOnCorrupt,
+		corruptMetrics:  b.corruptMetrics,
+		warmConcurrency: b.warmConcurrency,
+		dedupeBackfill:  b.dedupeBackfill,
+		schemaVersion:   b.schemaVersion,
+		delBatchSize:    b.delBatchSize,
+		maxStaleness:    b.maxStaleness,
+		ttlJitter:       b.ttlJitter,
+		jitterRand:      b.jitterRand,
+		loaderLock:      b.loaderLock,
+		loaderLockTTL:   b.loaderLockTTL,
 	}
 }
@@ -2,9 +2,13 @@ package cachex
 
 import (
 	"context"
+	"math"
 	"time"
 )
 
+// TTLNoExpiration CacheX.TTL返回该值表示缓存不会业务过期
+const TTLNoExpiration time.Duration = math.MaxInt64
+
 type SourceStrategy int64
 
 const (
@@ -15,34 +19,117 @@ const (
 	SourceStrategyExpiredBackup SourceStrategy = 5 // 缓存优先，回源失败使用缓存兜底
 )
 
+// Source Get结果的来源
+type Source int64
+
+const (
+	SourceMiss   Source = 0 // 未命中，仅缓存策略下缓存不存在
+	SourceL1     Source = 1 // L1缓存
+	SourceL2     Source = 2 // L2缓存
+	SourceLoader Source = 3 // 回源
+	SourceNil    Source = 4 // 缓存的空值，不区分L1、L2
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceL1:
+		return "L1"
+	case SourceL2:
+		return "L2"
+	case SourceLoader:
+		return "LOADER"
+	case SourceNil:
+		return "NIL"
+	default:
+		return "MISS"
+	}
+}
+
+// fromCache 结果是否来自缓存(包括缓存的空值)
+func (s Source) fromCache() bool {
+	return s == SourceL1 || s == SourceL2 || s == SourceNil
+}
+
 type LoaderFn[K, V any] func(ctx context.Context, key K) (*V, error)
 type MultiLoaderFn[K, V any] func(ctx context.Context, keys []K) ([]*V, error)
 type GenKeyFn[K any] func(key K) string
 
+// BatchKeyFn 生成批量回源的singleflight key，相同的key只回源一次，默认拼接所有缓存key
+// 不同的批次应生成不同的key，如hash冲突导致共享的结果不包含本次的key时，不合并直接回源
+type BatchKeyFn[K any] func(keys []K) string
+
+// MultiLoaderFnE 可以表达部分失败的批量回源函数，values、errs与keys一一对应
+// errs[i]不为nil表示keys[i]回源失败，此时values[i]被忽略
+// 部分失败时，成功的key正常写入缓存，MGet返回成功的结果和*MultiLoadError，失败的key结果为nil或缓存兜底的值
+type MultiLoaderFnE[K, V any] func(ctx context.Context, keys []K) ([]*V, []error)
+
+// CacheErrorHandlerFn 读缓存出错时的处理函数，op为操作名(get/mget)
+// 返回true表示当作未命中继续处理，返回false表示将错误返回给调用方
+type CacheErrorHandlerFn func(ctx context.Context, op string, err error) bool
+
+// CodecErrorFn 缓存值序列化/反序列化失败时的回调，可用于上报监控
+type CodecErrorFn func(ctx context.Context, key string, err error)
+
 type CacheBuilder[K, V any] interface {
-	WithNamespace(namespace string) CacheBuilder[K, V]         // 设置命名空间，用于区分不同缓存
-	WithExpireTTL(ttl time.Duration) CacheBuilder[K, V]        // 设置缓存失效时间
-	WithDelTTL(ttl time.Duration) CacheBuilder[K, V]           // 缓存删除时间
-	WithLogger(logger Logger) CacheBuilder[K, V]               // logger
-	WithL1(cacher Cacher) CacheBuilder[K, V]                   // 设置一级缓存
-	WithL2(cacher Cacher) CacheBuilder[K, V]                   // 设置二级缓存
-	WithGenKeyFn(fn GenKeyFn[K]) CacheBuilder[K, V]            // 设置缓存Key生成函数
-	WithLoader(fn LoaderFn[K, V]) CacheBuilder[K, V]           // 设置单个回源
-	WithMultiLoader(fn MultiLoaderFn[K, V]) CacheBuilder[K, V] // 设置批量回源
-	WithSourceStrategy(ss SourceStrategy) CacheBuilder[K, V]   // 设置回源策略
-	WithCacheNil(cacheNil bool) CacheBuilder[K, V]             // 设置是否缓存空值，即回源若不存在，则缓存空值
-	WithCodec(codec Codec[V]) CacheBuilder[K, V]               // 编解码
-	Build() (CacheX[K, V], error)                              // 创建缓存实例
+	WithNamespace(namespace string) CacheBuilder[K, V]               // 设置命名空间，用于区分不同缓存
+	WithExpireTTL(ttl time.Duration) CacheBuilder[K, V]              // 设置缓存失效时间
+	WithDelTTL(ttl time.Duration) CacheBuilder[K, V]                 // 缓存删除时间
+	WithLogger(logger Logger) CacheBuilder[K, V]                     // logger
+	WithL1(cacher Cacher) CacheBuilder[K, V]                         // 设置一级缓存
+	WithL2(cacher Cacher) CacheBuilder[K, V]                         // 设置二级缓存
+	WithGenKeyFn(fn GenKeyFn[K]) CacheBuilder[K, V]                  // 设置缓存Key生成函数
+	WithBatchKeyFn(fn BatchKeyFn[K]) CacheBuilder[K, V]              // 设置批量回源的singleflight key生成函数，如对keys计算hash，避免大批量时拼接过长的字符串
+	WithLoader(fn LoaderFn[K, V]) CacheBuilder[K, V]                 // 设置单个回源
+	WithMultiLoader(fn MultiLoaderFn[K, V]) CacheBuilder[K, V]       // 设置批量回源
+	WithMultiLoaderE(fn MultiLoaderFnE[K, V]) CacheBuilder[K, V]     // 设置可部分失败的批量回源，优先于WithMultiLoader，失败的key通过*MultiLoadError返回
+	WithFallbackLoader(fn LoaderFn[K, V]) CacheBuilder[K, V]         // 设置备用回源，单个回源返回ErrNotFound或nil、批量回源某个key为nil或失败原因为ErrNotFound时逐个使用
+	WithSourceStrategy(ss SourceStrategy) CacheBuilder[K, V]         // 设置回源策略
+	WithCacheNil(cacheNil bool) CacheBuilder[K, V]                   // 设置是否缓存空值，即回源若不存在，则缓存空值
+	WithCodec(codec Codec[V]) CacheBuilder[K, V]                     // 编解码
+	WithAutoCodec() CacheBuilder[K, V]                               // 按V的类型选择编解码，[]byte、string直接存储原始数据，其他类型使用sonic json，见NewCodecAuto
+	WithCacheErrorHandler(fn CacheErrorHandlerFn) CacheBuilder[K, V] // 设置读缓存错误处理，默认打印日志并当作未命中
+	WithOnSerializeError(fn CodecErrorFn) CacheBuilder[K, V]         // 设置序列化失败回调，失败的key不写入缓存
+	WithOnDeserializeError(fn CodecErrorFn) CacheBuilder[K, V]       // 设置反序列化失败回调，失败的key当作未命中
+	WithReloadOnCorrupt(reload bool) CacheBuilder[K, V]              // 设置缓存值反序列化失败时是否当作未命中并回源覆盖，默认true，false时返回错误
+	WithCorruptMetrics(fn CodecErrorFn) CacheBuilder[K, V]           // 设置缓存值反序列化失败、当作未命中跳过时的指标上报，每个跳过的key调用一次
+	WithRequireCache(require bool) CacheBuilder[K, V]                // 设置是否必须配置缓存，为true时未设置L1和L2则Build报错
+	WithWarmConcurrency(n int) CacheBuilder[K, V]                    // 设置Warm预热时批次间的并发数，默认1
+	WithDedupeBackfill(dedupe bool) CacheBuilder[K, V]               // 设置是否合并同一个key并发的L1回填写入，默认true
+	WithSchemaVersion(v uint8) CacheBuilder[K, V]                    // 设置缓存值的schema版本，结构变更时升级，版本不一致的值当作未命中并回源，默认0
+	WithDelBatchSize(n int) CacheBuilder[K, V]                       // 设置MDel每批删除的key数量，默认1000
+	WithMaxStaleness(d time.Duration) CacheBuilder[K, V]             // 设置ExpiredBackup兜底时缓存允许的最大过期时长，超过时返回回源错误，默认0不限制
+	WithTTLJitter(jitter time.Duration) CacheBuilder[K, V]           // 设置删除时间增加的最大随机值，防止集中过期，0表示不增加，默认1s
+	WithJitterRand(fn func(int64) int64) CacheBuilder[K, V]          // 设置删除时间随机值的来源，返回[0,n)的随机数，需并发安全，用于复现固定的随机序列，默认rand.Int63n
+	// WithEscapeNamespace 设置是否转义命名空间中的":"和"\"，默认不转义
+	// 不转义时命名空间"a"+key"b:c"与命名空间"a:b"+key"c"会生成相同的缓存key；开启后包含这两个字符的命名空间生成的key会变化，已有的缓存全部未命中
+	WithEscapeNamespace(escape bool) CacheBuilder[K, V]
+	// WithLoaderLock 设置回源锁，默认不加锁。缓存优先、过期兜底策略下单个key未命中时，先获取分布式锁再回源，其他进程最多等待ttl后重新读缓存
+	WithLoaderLock(locker LoaderLocker, ttl time.Duration) CacheBuilder[K, V]
+	Base() *BaseConfig            // 提取与类型参数无关的配置，用于Derive创建其他类型的缓存
+	Build() (CacheX[K, V], error) // 创建缓存实例
 }
 
 type CacheX[K, V any] interface {
 	WithSourceStrategy(ss SourceStrategy) CacheX[K, V]
 	Get(ctx context.Context, key K) (*V, error)
+	GetWithStrategy(ctx context.Context, key K, ss SourceStrategy) (*V, error)                      // 本次调用使用指定的回源策略，不创建新实例
+	GetE(ctx context.Context, key K) (*V, bool, error)                                              // bool表示结果是否来自缓存，可区分缓存的空值和未命中
+	GetWithSource(ctx context.Context, key K) (*V, Source, error)                                   // Source表示结果来自L1、L2、回源还是缓存的空值，用于排查或设置X-Cache等响应头
+	GetOrSet(ctx context.Context, key K, valueFn func(ctx context.Context) (*V, error)) (*V, error) // 缓存未命中时使用valueFn获取并写入缓存，不使用loader
+	GetBypassLocal(ctx context.Context, key K) (*V, error)                                          // 跳过L1，读L2未命中时回源，结果写入L1、L2，用于排查本地缓存数据
 	Set(ctx context.Context, key K, value *V) error
 	Del(ctx context.Context, key K) error
 	MGet(ctx context.Context, keys []K) ([]*V, error)
+	MGetWithStrategy(ctx context.Context, keys []K, ss SourceStrategy) ([]*V, error) // 本次调用使用指定的回源策略，不创建新实例
 	MSet(ctx context.Context, keys []K, values []*V) error
+	MSetWithTTL(ctx context.Context, keys []K, values []*V, ttls []time.Duration) error // 每个key使用各自的失效时间，大于WithDelTTL时返回ErrTTLExceedsDelTTL且不写入
 	MDel(ctx context.Context, keys []K) error
+	TTL(ctx context.Context, key K) (time.Duration, error) // 缓存剩余的业务过期时间，不回源，已过期时小于等于0，不过期返回TTLNoExpiration，未命中返回ErrCacheMiss
+	Warm(ctx context.Context, keys []K) error              // 预热，回源指定的key并写入所有级别缓存，用于启动或清空缓存后避免冷启动击穿
+	HealthCheck(ctx context.Context) error                 // 检查L1、L2缓存是否可用，未实现HealthChecker的Cacher视为可用
+	// CompareAndSet 缓存的值与old相同时原子地替换为new，返回是否替换，按序列化结果比较，old为nil时匹配缓存的空值
+	// 以L2为准，替换成功后覆盖L1；缓存不存在或已过期时返回false，不回源
+	CompareAndSet(ctx context.Context, key K, old, new *V) (bool, error)
 }
 
 type Cacher interface {
@@ -54,12 +141,64 @@ type Cacher interface {
 	MDelete(ctx context.Context, keys []string) error
 }
 
+// CompareAndSwapper Cacher可选实现，用于CompareAndSet，L2(未配置L2时为L1)需要实现
+type CompareAndSwapper interface {
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) // key当前的值与old相同时写入new，返回是否写入，key不存在时返回false
+}
+
+// HealthChecker Cacher可选实现，用于检查缓存后端是否可用，如启动时检查redis连接
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+// LoaderLocker 回源锁，缓存未命中时先获取锁再回源，用于多个进程之间合并回源
+// dlock.Locker可通过github.com/kakkk/gopkg/dlock/cachexlock适配
+type LoaderLocker interface {
+	// Acquire 获取key的锁，最多等待ttl，ttl同时是锁的过期时间，返回释放锁的函数
+	Acquire(ctx context.Context, key string, ttl time.Duration) (unlock func(ctx context.Context) error, err error)
+}
+
 type Logger interface {
 	Infof(ctx context.Context, format string, v ...interface{})
 	Warnf(ctx context.Context, format string, v ...interface{})
 	Errorf(ctx context.Context, format string, v ...interface{})
 }
 
+// FieldLogger Logger可选实现，实现时cachex内部的日志通过该接口输出结构化字段，便于按key、命名空间过滤
+// fields包含op(操作名)、layer(缓存级别l1/l2)、key、error等，不同日志的字段不完全相同
+type FieldLogger interface {
+	WarnWithFields(ctx context.Context, msg string, fields map[string]interface{})
+}
+
 func New[K, V any]() CacheBuilder[K, V] {
 	return newBuilder[K, V]()
 }
+
+// Derive 以base为基础创建builder，用于多个不同类型的缓存共享命名空间、logger、缓存、TTL等配置
+// 编解码、key生成函数、回源函数与类型参数相关，不在base中，codec默认使用sonic json
+func Derive[K, V any](base *BaseConfig) CacheBuilder[K, V] {
+	return deriveBuilder[K, V](base)
+}
+
+// MGetMap 批量获取，返回原始key到值的映射，重复的key只查询一次
+// CacheX的K不要求comparable，因此以函数形式提供；部分回源失败时与MGet一致，返回成功的结果和*MultiLoadError
+func MGetMap[K comparable, V any](ctx context.Context, cx CacheX[K, V], keys []K) (map[K]*V, error) {
+	uniq := make([]K, 0, len(keys))
+	seen := make(map[K]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		uniq = append(uniq, key)
+	}
+	vals, err := cx.MGet(ctx, uniq)
+	if vals == nil && err != nil {
+		return nil, err
+	}
+	result := make(map[K]*V, len(uniq))
+	for i, key := range uniq {
+		result[key] = vals[i]
+	}
+	return result, err
+}
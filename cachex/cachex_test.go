@@ -2,10 +2,19 @@ package cachex
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/bytedance/gg/gptr"
+	"github.com/bytedance/gg/gslice"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -72,3 +81,1991 @@ func TestCachex_Get(t *testing.T) {
 		})
 	})
 }
+
+func TestCachex_GetOrSet(t *testing.T) {
+	ctx := context.Background()
+	loaderCalled := 0
+	cx, err := New[string, string]().
+		WithLoader(func(ctx context.Context, key string) (*string, error) {
+			loaderCalled++
+			return gptr.Of("from_loader"), nil
+		}).
+		WithL1(NewLocalCacher(1)).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithExpireTTL(time.Minute).
+		WithDelTTL(time.Minute).
+		Build()
+	assert.NoError(t, err)
+
+	t.Run("value fn used and cached", func(t *testing.T) {
+		called := 0
+		valueFn := func(ctx context.Context) (*string, error) {
+			called++
+			return gptr.Of("from_value_fn"), nil
+		}
+		got, err := cx.GetOrSet(ctx, "get_or_set", valueFn)
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_value_fn"), got)
+		got, err = cx.GetOrSet(ctx, "get_or_set", valueFn)
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_value_fn"), got)
+		assert.Equal(t, 1, called)
+		// Get 直接命中 GetOrSet 写入的缓存
+		got, err = cx.Get(ctx, "get_or_set")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_value_fn"), got)
+		assert.Equal(t, 0, loaderCalled)
+	})
+
+	t.Run("value fn error", func(t *testing.T) {
+		got, err := cx.GetOrSet(ctx, "get_or_set_err", func(ctx context.Context) (*string, error) {
+			return nil, assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, got)
+	})
+
+	t.Run("value fn nil", func(t *testing.T) {
+		_, err := cx.GetOrSet(ctx, "get_or_set_nil", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestCachex_GetE(t *testing.T) {
+	ctx := context.Background()
+	b := New[string, string]().
+		WithLoader(func(ctx context.Context, key string) (*string, error) {
+			if key == "exist" {
+				return gptr.Of("from_loader"), nil
+			}
+			return nil, nil
+		}).
+		WithL1(NewLocalCacher(1)).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithCacheNil(true).
+		WithExpireTTL(time.Minute).
+		WithDelTTL(time.Minute)
+	cx, err := b.Build()
+	assert.NoError(t, err)
+
+	t.Run("genuine miss, loader returns nil", func(t *testing.T) {
+		got, found, err := cx.GetE(ctx, "not_exist")
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Nil(t, got)
+	})
+	t.Run("cached nil", func(t *testing.T) {
+		got, found, err := cx.GetE(ctx, "not_exist")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Nil(t, got)
+	})
+	t.Run("cached value", func(t *testing.T) {
+		got, found, err := cx.GetE(ctx, "exist")
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, gptr.Of("from_loader"), got)
+		got, found, err = cx.GetE(ctx, "exist")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, gptr.Of("from_loader"), got)
+	})
+	t.Run("cache only miss", func(t *testing.T) {
+		cacheOnly, err := b.WithSourceStrategy(SourceStrategyCacheOnly).Build()
+		assert.NoError(t, err)
+		got, found, err := cacheOnly.GetE(ctx, "other")
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Nil(t, got)
+	})
+}
+
+func TestCachex_GetWithSource(t *testing.T) {
+	ctx := context.Background()
+	l1 := NewLocalCacher(1)
+	b := New[string, string]().
+		WithLoader(func(ctx context.Context, key string) (*string, error) {
+			if key == "exist" {
+				return gptr.Of("from_loader"), nil
+			}
+			return nil, nil
+		}).
+		WithL1(l1).
+		WithL2(NewSyncMapCacher()).
+		WithNamespace("source").
+		WithGenKeyFn(func(key string) string { return key }).
+		WithCacheNil(true).
+		WithExpireTTL(time.Minute).
+		WithDelTTL(time.Minute)
+	cx, err := b.Build()
+	assert.NoError(t, err)
+
+	t.Run("loader", func(t *testing.T) {
+		got, src, err := cx.GetWithSource(ctx, "exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceLoader, src)
+		assert.Equal(t, gptr.Of("from_loader"), got)
+	})
+	t.Run("l1", func(t *testing.T) {
+		got, src, err := cx.GetWithSource(ctx, "exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceL1, src)
+		assert.Equal(t, gptr.Of("from_loader"), got)
+	})
+	t.Run("l2", func(t *testing.T) {
+		// 只删除L1
+		assert.NoError(t, l1.Delete(ctx, "source"+keySeparator+"exist"))
+		got, src, err := cx.GetWithSource(ctx, "exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceL2, src)
+		assert.Equal(t, gptr.Of("from_loader"), got)
+		// 已回填L1
+		_, src, err = cx.GetWithSource(ctx, "exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceL1, src)
+	})
+	t.Run("nil", func(t *testing.T) {
+		got, src, err := cx.GetWithSource(ctx, "not_exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceLoader, src)
+		assert.Nil(t, got)
+		got, src, err = cx.GetWithSource(ctx, "not_exist")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceNil, src)
+		assert.Nil(t, got)
+	})
+	t.Run("miss", func(t *testing.T) {
+		cacheOnly, err := b.WithSourceStrategy(SourceStrategyCacheOnly).Build()
+		assert.NoError(t, err)
+		got, src, err := cacheOnly.GetWithSource(ctx, "other")
+		assert.NoError(t, err)
+		assert.Equal(t, SourceMiss, src)
+		assert.Equal(t, "MISS", src.String())
+		assert.Nil(t, got)
+	})
+}
+
+func TestCachex_CacheErrorHandler(t *testing.T) {
+	ctx := context.Background()
+	loaderFn := func(ctx context.Context, key string) (*string, error) { return gptr.Of("from_source"), nil }
+	genKeyFn := func(key string) string { return key }
+
+	t.Run("default swallow", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).Times(1)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).Times(1)
+		l1.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		l1.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		cx, err := New[string, string]().WithLoader(loaderFn).WithL1(l1).WithGenKeyFn(genKeyFn).Build()
+		assert.NoError(t, err)
+		got, err := cx.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), got)
+		gots, err := cx.MGet(ctx, []string{"test"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("from_source")}, gots)
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).Times(1)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).Times(1)
+		ops := make([]string, 0)
+		handler := func(ctx context.Context, op string, err error) bool {
+			ops = append(ops, op)
+			return false
+		}
+		loaderCalled := false
+		cx, err := New[string, string]().
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				loaderCalled = true
+				return gptr.Of("from_source"), nil
+			}).
+			WithL1(l1).
+			WithGenKeyFn(genKeyFn).
+			WithCacheErrorHandler(handler).
+			Build()
+		assert.NoError(t, err)
+		got, err := cx.Get(ctx, "test")
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, got)
+		gots, err := cx.MGet(ctx, []string{"test"})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, gots)
+		assert.False(t, loaderCalled)
+		assert.Equal(t, []string{"get", "mget"}, ops)
+	})
+}
+
+func TestCachex_LengthMismatch(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+
+	t.Run("mset", func(t *testing.T) {
+		cx, err := New[string, string]().WithL1(NewLocalCacher(1)).WithGenKeyFn(genKeyFn).Build()
+		assert.NoError(t, err)
+		err = cx.MSet(ctx, []string{"k1", "k2"}, []*string{gptr.Of("v1")})
+		assert.ErrorIs(t, err, ErrKeyValueLengthMismatch)
+	})
+
+	t.Run("mloader", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				return []*string{gptr.Of("v1")}, nil
+			}).
+			WithGenKeyFn(genKeyFn).
+			WithSourceStrategy(SourceStrategySourceOnly).
+			Build()
+		assert.NoError(t, err)
+		_, err = cx.MGet(ctx, []string{"k1", "k2"})
+		assert.ErrorIs(t, err, ErrLoaderResultMismatch)
+	})
+}
+
+func TestCachex_ContextCanceled(t *testing.T) {
+	loaderCalled := false
+	mLoaderCalled := false
+	b := New[string, string]().
+		WithLoader(func(ctx context.Context, key string) (*string, error) {
+			loaderCalled = true
+			return gptr.Of("from_source"), nil
+		}).
+		WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+			mLoaderCalled = true
+			return make([]*string, len(keys)), nil
+		}).
+		WithL1(NewLocalCacher(1)).
+		WithGenKeyFn(func(key string) string { return key })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, ss := range []SourceStrategy{
+		SourceStrategyCacheFirst,
+		SourceStrategySourceFirst,
+		SourceStrategyCacheOnly,
+		SourceStrategySourceOnly,
+		SourceStrategyExpiredBackup,
+	} {
+		cx, err := b.WithSourceStrategy(ss).Build()
+		assert.NoError(t, err)
+		got, err := cx.Get(ctx, "test")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+		gots, err := cx.MGet(ctx, []string{"test"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, gots)
+	}
+	assert.False(t, loaderCalled)
+	assert.False(t, mLoaderCalled)
+}
+
+// failCodec 对指定值序列化失败，对非法数据反序列化失败
+type failCodec struct {
+	Codec[string]
+}
+
+func (c failCodec) Marshal(v *string) ([]byte, error) {
+	if *v == "bad" {
+		return nil, assert.AnError
+	}
+	return c.Codec.Marshal(v)
+}
+
+func TestCachex_CodecErrorCallback(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	codec := failCodec{Codec: NewCodecJsonStd[string]()}
+
+	t.Run("serialize", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		serKeys := make([]string, 0)
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithCodec(codec).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) { return gptr.Of(key), nil }).
+			WithOnSerializeError(func(ctx context.Context, key string, err error) {
+				assert.ErrorIs(t, err, assert.AnError)
+				serKeys = append(serKeys, key)
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		// 序列化失败的key跳过写入，其他key正常写入
+		err = cx.MSet(ctx, []string{"bad", "good"}, []*string{gptr.Of("bad"), gptr.Of("good")})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"default:bad"}, serKeys)
+		got, err := l1.Get(ctx, "default:bad")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+		got, err = l1.Get(ctx, "default:good")
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+
+		// 回源结果序列化失败，依然返回回源结果
+		val, err := cx.Get(ctx, "bad")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("bad"), val)
+		assert.Equal(t, []string{"default:bad", "default:bad"}, serKeys)
+	})
+
+	t.Run("deserialize", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		deserKeys := make([]string, 0)
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithCodec(codec).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) { return gptr.Of("from_source"), nil }).
+			WithOnDeserializeError(func(ctx context.Context, key string, err error) {
+				deserKeys = append(deserKeys, key)
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		// 头部合法但value无法解析
+		corrupt := mustSerialize(t, Codec[string](codec), newEntry(gptr.Of("x"), 0))
+		corrupt = append(corrupt[:bytesHeaderSize:bytesHeaderSize], []byte("{not json")...)
+		assert.NoError(t, l1.Set(ctx, "default:corrupt", corrupt, time.Minute))
+		// 长度不足头部
+		assert.NoError(t, l1.Set(ctx, "default:short", []byte("x"), time.Minute))
+
+		// 反序列化失败当作未命中，走回源
+		val, err := cx.Get(ctx, "corrupt")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), val)
+		assert.NoError(t, l1.Set(ctx, "default:corrupt", corrupt, time.Minute))
+		vals, err := cx.MGet(ctx, []string{"corrupt", "short"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("from_source"), gptr.Of("from_source")}, vals)
+		assert.ElementsMatch(t, []string{"default:corrupt", "default:corrupt", "default:short"}, deserKeys)
+	})
+}
+
+func TestCachex_MSetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	cx, err := New[string, string]().
+		WithL1(NewLocalCacher(1)).
+		WithGenKeyFn(genKeyFn).
+		WithDelTTL(time.Minute).
+		WithSourceStrategy(SourceStrategyCacheOnly).
+		Build()
+	assert.NoError(t, err)
+
+	t.Run("length mismatch", func(t *testing.T) {
+		err := cx.MSetWithTTL(ctx, []string{"a", "b"}, []*string{gptr.Of("a"), gptr.Of("b")}, []time.Duration{time.Minute})
+		assert.ErrorIs(t, err, ErrKeyValueLengthMismatch)
+	})
+
+	t.Run("ttl exceeds del ttl", func(t *testing.T) {
+		err := cx.MSetWithTTL(ctx, []string{"a", "b"}, []*string{gptr.Of("a"), gptr.Of("b")}, []time.Duration{time.Second, time.Hour})
+		assert.ErrorIs(t, err, ErrTTLExceedsDelTTL)
+		got, err := cx.MGet(ctx, []string{"a", "b"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, nil}, got)
+	})
+
+	t.Run("per key ttl", func(t *testing.T) {
+		err := cx.MSetWithTTL(ctx,
+			[]string{"short", "long", "forever"},
+			[]*string{gptr.Of("short"), gptr.Of("long"), gptr.Of("forever")},
+			[]time.Duration{50 * time.Millisecond, 300 * time.Millisecond, 0})
+		assert.NoError(t, err)
+		got, err := cx.MGet(ctx, []string{"short", "long", "forever"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("short"), gptr.Of("long"), gptr.Of("forever")}, got)
+
+		time.Sleep(100 * time.Millisecond)
+		got, err = cx.MGet(ctx, []string{"short", "long", "forever"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, gptr.Of("long"), gptr.Of("forever")}, got)
+
+		time.Sleep(300 * time.Millisecond)
+		got, err = cx.MGet(ctx, []string{"short", "long", "forever"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, nil, gptr.Of("forever")}, got)
+	})
+}
+
+func TestCachex_FallbackLoader(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+
+	newCx := func(l1 Cacher, primary LoaderFn[string, string], fallbackCalls *int) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(primary).
+			WithFallbackLoader(func(ctx context.Context, key string) (*string, error) {
+				*fallbackCalls++
+				return gptr.Of("from_fallback"), nil
+			}).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	t.Run("primary not found", func(t *testing.T) {
+		calls := 0
+		l1 := NewLocalCacher(1)
+		cx := newCx(l1, func(ctx context.Context, key string) (*string, error) {
+			return nil, fmt.Errorf("query replica: %w", ErrNotFound)
+		}, &calls)
+		got, err := cx.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_fallback"), got)
+		// 备用回源的结果已写入缓存
+		got, err = cx.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_fallback"), got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("primary nil", func(t *testing.T) {
+		calls := 0
+		cx := newCx(NewLocalCacher(1), func(ctx context.Context, key string) (*string, error) {
+			return nil, nil
+		}, &calls)
+		got, err := cx.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_fallback"), got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("primary hit", func(t *testing.T) {
+		calls := 0
+		cx := newCx(NewLocalCacher(1), func(ctx context.Context, key string) (*string, error) {
+			return gptr.Of("from_primary"), nil
+		}, &calls)
+		got, err := cx.Get(ctx, "test")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_primary"), got)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("primary other error", func(t *testing.T) {
+		calls := 0
+		cx := newCx(NewLocalCacher(1), func(ctx context.Context, key string) (*string, error) {
+			return nil, assert.AnError
+		}, &calls)
+		_, err := cx.Get(ctx, "test")
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 0, calls)
+	})
+
+	fallback := func(calls *int) LoaderFn[string, string] {
+		return func(ctx context.Context, key string) (*string, error) {
+			*calls++
+			return gptr.Of("fallback_" + key), nil
+		}
+	}
+
+	t.Run("multi loader nil", func(t *testing.T) {
+		calls := 0
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				return []*string{gptr.Of("primary_a"), nil}, nil
+			}).
+			WithFallbackLoader(fallback(&calls)).
+			Build()
+		assert.NoError(t, err)
+		got, err := cx.MGet(ctx, []string{"a", "b"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("primary_a"), gptr.Of("fallback_b")}, got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("multi loader only get", func(t *testing.T) {
+		calls := 0
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				return make([]*string, len(keys)), nil
+			}).
+			WithFallbackLoader(fallback(&calls)).
+			Build()
+		assert.NoError(t, err)
+		got, err := cx.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("fallback_a"), got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("multi loader e not found", func(t *testing.T) {
+		calls := 0
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(func(ctx context.Context, keys []string) ([]*string, []error) {
+				return make([]*string, 3), []error{nil, ErrNotFound, assert.AnError}
+			}).
+			WithFallbackLoader(fallback(&calls)).
+			Build()
+		assert.NoError(t, err)
+		got, err := cx.MGet(ctx, []string{"a", "b", "c"})
+		var mErr *MultiLoadError[string]
+		assert.ErrorAs(t, err, &mErr)
+		assert.Equal(t, []string{"c"}, mErr.Keys)
+		assert.Equal(t, []*string{gptr.Of("fallback_a"), gptr.Of("fallback_b"), nil}, got)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestCachex_WithStrategy(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	// 每次创建新的实例，缓存中有未过期的hit和已过期的expired
+	newCx := func(t *testing.T) CacheX[string, string] {
+		l1 := NewLocalCacher(1)
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(time.Minute).
+			WithExpireTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) { return gptr.Of("from_source"), nil }).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				res := make([]*string, len(keys))
+				for i := range keys {
+					res[i] = gptr.Of("from_source")
+				}
+				return res, nil
+			}).
+			Build()
+		assert.NoError(t, err)
+		hit := mustSerialize(t, NewCodecJsonSonic[string](), newEntry(gptr.Of("from_cache"), time.Minute))
+		expired := newEntry(gptr.Of("from_expired"), time.Millisecond)
+		expired.createAt -= 1000
+		assert.NoError(t, l1.Set(ctx, "default:hit", hit, time.Minute))
+		assert.NoError(t, l1.Set(ctx, "default:expired", mustSerialize(t, NewCodecJsonSonic[string](), expired), time.Minute))
+		return cx
+	}
+
+	strategies := []SourceStrategy{
+		SourceStrategyCacheFirst,
+		SourceStrategySourceFirst,
+		SourceStrategyCacheOnly,
+		SourceStrategySourceOnly,
+		SourceStrategyExpiredBackup,
+	}
+	for _, ss := range strategies {
+		for _, key := range []string{"hit", "expired", "miss"} {
+			got, err := newCx(t).GetWithStrategy(ctx, key, ss)
+			want, wantErr := newCx(t).WithSourceStrategy(ss).Get(ctx, key)
+			assert.Equal(t, wantErr, err, "ss:%d key:%s", ss, key)
+			assert.Equal(t, want, got, "ss:%d key:%s", ss, key)
+		}
+		keys := []string{"hit", "expired", "miss"}
+		got, err := newCx(t).MGetWithStrategy(ctx, keys, ss)
+		want, wantErr := newCx(t).WithSourceStrategy(ss).MGet(ctx, keys)
+		assert.Equal(t, wantErr, err, "ss:%d", ss)
+		assert.Equal(t, want, got, "ss:%d", ss)
+	}
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		_, err := newCx(t).GetWithStrategy(ctx, "hit", SourceStrategy(100))
+		assert.Error(t, err)
+		_, err = newCx(t).MGetWithStrategy(ctx, []string{"hit"}, SourceStrategy(100))
+		assert.Error(t, err)
+	})
+
+	t.Run("does not change default", func(t *testing.T) {
+		cx := newCx(t)
+		got, err := cx.GetWithStrategy(ctx, "hit", SourceStrategySourceOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), got)
+		got, err = cx.Get(ctx, "miss")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), got)
+	})
+}
+
+func benchmarkStrategyCachex(b *testing.B) CacheX[string, string] {
+	cx, err := New[string, string]().
+		WithL1(NewLocalCacher(1)).
+		WithDelTTL(time.Minute).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithLoader(func(ctx context.Context, key string) (*string, error) { return gptr.Of("from_source"), nil }).
+		Build()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = cx.Set(context.Background(), "key", gptr.Of("value")); err != nil {
+		b.Fatal(err)
+	}
+	return cx
+}
+
+func BenchmarkCachex_GetWithStrategy(b *testing.B) {
+	ctx := context.Background()
+	cx := benchmarkStrategyCachex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cx.GetWithStrategy(ctx, "key", SourceStrategyCacheOnly)
+	}
+}
+
+func BenchmarkCachex_WithSourceStrategyGet(b *testing.B) {
+	ctx := context.Background()
+	cx := benchmarkStrategyCachex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cx.WithSourceStrategy(SourceStrategyCacheOnly).Get(ctx, "key")
+	}
+}
+
+func TestCachex_SourceFirstMGet(t *testing.T) {
+	ctx := context.Background()
+	var version int64 = 1
+	var fail atomic.Bool
+	cx, err := New[string, string]().
+		WithL1(NewLocalCacher(1)).
+		WithExpireTTL(time.Minute).
+		WithDelTTL(time.Minute).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+			if fail.Load() {
+				return nil, assert.AnError
+			}
+			return gslice.Map(keys, func(k string) *string {
+				return gptr.Of(fmt.Sprintf("v%d_%s", atomic.LoadInt64(&version), k))
+			}), nil
+		}).
+		WithSourceStrategy(SourceStrategySourceFirst).
+		Build()
+	assert.NoError(t, err)
+
+	// 回源成功并写入缓存
+	got, err := cx.MGet(ctx, []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{gptr.Of("v1_a")}, got)
+
+	// 回源优先，不读缓存
+	atomic.StoreInt64(&version, 2)
+	got, err = cx.MGet(ctx, []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{gptr.Of("v2_a")}, got)
+
+	// 回源失败，缓存兜底
+	fail.Store(true)
+	got, err = cx.MGet(ctx, []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{gptr.Of("v2_a")}, got)
+
+	// 有key没有缓存兜底，返回回源错误
+	got, err = cx.MGet(ctx, []string{"a", "b"})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, []*string{gptr.Of("v2_a"), nil}, got)
+}
+
+func TestCachex_RequireCache(t *testing.T) {
+	genKeyFn := func(key string) string { return key }
+	loaderFn := func(ctx context.Context, key string) (*string, error) { return gptr.Of("from_source"), nil }
+
+	// 默认允许只有loader，没有缓存
+	_, err := New[string, string]().WithGenKeyFn(genKeyFn).WithLoader(loaderFn).Build()
+	assert.NoError(t, err)
+
+	_, err = New[string, string]().WithGenKeyFn(genKeyFn).WithLoader(loaderFn).WithRequireCache(true).Build()
+	assert.EqualError(t, err, "cache required but l1 and l2 cacher not set")
+
+	_, err = New[string, string]().WithGenKeyFn(genKeyFn).WithLoader(loaderFn).WithRequireCache(true).
+		WithL1(NewLocalCacher(1)).Build()
+	assert.NoError(t, err)
+}
+
+func TestCachex_ReloadOnCorrupt(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	corrupt := mustSerialize(t, NewCodecJsonStd[string](), newEntry(gptr.Of("x"), 0))
+	corrupt = append(corrupt[:bytesHeaderSize:bytesHeaderSize], []byte("{not json")...)
+
+	newCx := func(l1 Cacher, loaderCalls *int, reload bool, metrics CodecErrorFn) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(time.Minute).
+			WithCodec(NewCodecJsonStd[string]()).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				*loaderCalls++
+				return gptr.Of("from_source"), nil
+			}).
+			WithReloadOnCorrupt(reload).
+			WithCorruptMetrics(metrics).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	t.Run("reload and repair", func(t *testing.T) {
+		calls := 0
+		l1 := NewLocalCacher(1)
+		assert.NoError(t, l1.Set(ctx, "default:corrupt", corrupt, time.Minute))
+		var skipped []string
+		cx := newCx(l1, &calls, true, func(ctx context.Context, key string, err error) {
+			assert.Error(t, err)
+			skipped = append(skipped, key)
+		})
+
+		got, err := cx.Get(ctx, "corrupt")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), got)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, []string{"default:corrupt"}, skipped)
+
+		// 坏数据已被覆盖，再次读取命中缓存
+		raw, err := l1.Get(ctx, "default:corrupt")
+		assert.NoError(t, err)
+		assert.NotEqual(t, corrupt, raw)
+		got, err = cx.Get(ctx, "corrupt")
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_source"), got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		calls := 0
+		l1 := NewLocalCacher(1)
+		assert.NoError(t, l1.Set(ctx, "default:corrupt", corrupt, time.Minute))
+		assert.NoError(t, l1.Set(ctx, "default:good", mustSerialize(t, NewCodecJsonStd[string](), newEntry(gptr.Of("cached"), time.Minute)), time.Minute))
+		cx := newCx(l1, &calls, false, func(ctx context.Context, key string, err error) {
+			t.Errorf("unexpected corrupt metrics for %s", key)
+		})
+
+		_, err := cx.Get(ctx, "corrupt")
+		assert.ErrorContains(t, err, "deserialize key default:corrupt error")
+		assert.Equal(t, 0, calls)
+
+		// 无法解析的key单独返回错误，不影响同一批的其他key
+		got, err := cx.MGet(ctx, []string{"good", "corrupt", "missing"})
+		assert.Equal(t, []*string{gptr.Of("cached"), nil, gptr.Of("from_source")}, got)
+		var mErr *MultiLoadError[string]
+		assert.ErrorAs(t, err, &mErr)
+		assert.Equal(t, []string{"corrupt"}, mErr.Keys)
+		assert.ErrorContains(t, mErr.Errs[0], "deserialize key default:corrupt error")
+		assert.Equal(t, 1, calls)
+
+		got, err = cx.MGetWithStrategy(ctx, []string{"good", "corrupt"}, SourceStrategyCacheOnly)
+		assert.Equal(t, []*string{gptr.Of("cached"), nil}, got)
+		assert.ErrorAs(t, err, &mErr)
+		assert.Equal(t, []string{"corrupt"}, mErr.Keys)
+	})
+}
+
+func TestCachex_Warm(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	keys := make([]string, 0, 250)
+	for i := 0; i < 250; i++ {
+		keys = append(keys, fmt.Sprintf("k%d", i))
+	}
+
+	t.Run("populate all levels", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		var mu sync.Mutex
+		l1Keys := make(map[string]bool)
+		l2Keys := make(map[string]bool)
+		record := func(m map[string]bool) func(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+			return func(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+				mu.Lock()
+				defer mu.Unlock()
+				for k := range kvs {
+					m[k] = true
+				}
+				return nil
+			}
+		}
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(record(l1Keys)).Times(3)
+		l2 := NewMockCacher(ctrl)
+		l2.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(record(l2Keys)).Times(3)
+
+		var loaded int64
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithL2(l2).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				atomic.AddInt64(&loaded, int64(len(keys)))
+				return gslice.Map(keys, func(k string) *string { return gptr.Of("v_" + k) }), nil
+			}).
+			WithWarmConcurrency(2).
+			Build()
+		assert.NoError(t, err)
+
+		assert.NoError(t, cx.Warm(ctx, keys))
+		assert.Equal(t, int64(len(keys)), atomic.LoadInt64(&loaded))
+		assert.Len(t, l1Keys, len(keys))
+		assert.Len(t, l2Keys, len(keys))
+		assert.True(t, l1Keys["default:k0"])
+		assert.True(t, l2Keys["default:k249"])
+	})
+
+	t.Run("subsequent get hits cache", func(t *testing.T) {
+		loaderCalls := int64(0)
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				atomic.AddInt64(&loaderCalls, 1)
+				return gptr.Of("v_" + key), nil
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		assert.NoError(t, cx.Warm(ctx, keys))
+		assert.Equal(t, int64(len(keys)), atomic.LoadInt64(&loaderCalls))
+
+		got, fromCache, err := cx.GetE(ctx, "k42")
+		assert.NoError(t, err)
+		assert.True(t, fromCache)
+		assert.Equal(t, gptr.Of("v_k42"), got)
+		vals, err := cx.MGet(ctx, keys[:3])
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("v_k0"), gptr.Of("v_k1"), gptr.Of("v_k2")}, vals)
+		assert.Equal(t, int64(len(keys)), atomic.LoadInt64(&loaderCalls))
+	})
+
+	t.Run("loader error", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				return nil, assert.AnError
+			}).
+			Build()
+		assert.NoError(t, err)
+		assert.ErrorIs(t, cx.Warm(ctx, keys), assert.AnError)
+	})
+
+	t.Run("partial failure does not cancel other batches", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(func(ctx context.Context, keys []string) ([]*string, []error) {
+				vals := make([]*string, len(keys))
+				errs := make([]error, len(keys))
+				for i, k := range keys {
+					switch {
+					case ctx.Err() != nil:
+						errs[i] = ctx.Err()
+					case k == "k0":
+						errs[i] = assert.AnError
+					default:
+						vals[i] = gptr.Of("v_" + k)
+					}
+				}
+				return vals, errs
+			}).
+			WithWarmConcurrency(1).
+			Build()
+		assert.NoError(t, err)
+
+		// 第一批k0失败，后续批次仍回源并写入
+		err = cx.Warm(ctx, keys)
+		var mErr *MultiLoadError[string]
+		assert.ErrorAs(t, err, &mErr)
+		assert.Equal(t, []string{"k0"}, mErr.Keys)
+		assert.ErrorIs(t, err, assert.AnError)
+
+		got, err := cx.WithSourceStrategy(SourceStrategyCacheOnly).MGet(ctx, []string{"k0", "k1", "k100", "k249"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, gptr.Of("v_k1"), gptr.Of("v_k100"), gptr.Of("v_k249")}, got)
+	})
+}
+
+func TestCachex_TTL(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	newCx := func(expireTTL time.Duration) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithExpireTTL(expireTTL).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				t.Fatal("TTL should not call loader")
+				return nil, nil
+			}).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	t.Run("fresh", func(t *testing.T) {
+		cx := newCx(time.Minute)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+		ttl, err := cx.TTL(ctx, "k")
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cx := newCx(50 * time.Millisecond)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+		time.Sleep(100 * time.Millisecond)
+		ttl, err := cx.TTL(ctx, "k")
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, ttl, time.Duration(0))
+	})
+
+	t.Run("no expiration", func(t *testing.T) {
+		cx := newCx(0)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+		ttl, err := cx.TTL(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, TTLNoExpiration, ttl)
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		cx := newCx(time.Minute)
+		_, err := cx.TTL(ctx, "missing")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+}
+
+func TestCachex_SchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	l1 := NewLocalCacher(1)
+	newCx := func(version uint8, loaded *int) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				*loaded++
+				return gptr.Of(fmt.Sprintf("v%d", version)), nil
+			}).
+			WithSchemaVersion(version).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	// 旧版本写入缓存
+	loadedV0 := 0
+	cxV0 := newCx(0, &loadedV0)
+	got, err := cxV0.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, gptr.Of("v0"), got)
+	assert.Equal(t, 1, loadedV0)
+
+	// 升级版本后旧值被忽略，回源并覆盖
+	loadedV1 := 0
+	cxV1 := newCx(1, &loadedV1)
+	got, err = cxV1.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, gptr.Of("v1"), got)
+	assert.Equal(t, 1, loadedV1)
+	vals, err := cxV1.MGet(ctx, []string{"k"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*string{gptr.Of("v1")}, vals)
+	assert.Equal(t, 1, loadedV1)
+
+	// 旧版本也不会读取新版本的值
+	_, err = cxV0.TTL(ctx, "k")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestCachex_LoaderPanicRecovered(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+
+	t.Run("stale value served", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		stale := newEntry(gptr.Of("stale"), time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		assert.NoError(t, l1.Set(ctx, "default:k", mustSerialize(t, NewCodecJsonSonic[string](), stale), time.Minute))
+
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				panic("loader panic")
+			}).
+			WithSourceStrategy(SourceStrategyExpiredBackup).
+			Build()
+		assert.NoError(t, err)
+
+		// 批量回源在goroutine中执行，panic被恢复为错误，使用过期数据兜底
+		got, err := cx.MGet(ctx, []string{"k"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("stale")}, got)
+	})
+
+	t.Run("refresh expired value", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		stale := newEntry(gptr.Of("stale"), time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		assert.NoError(t, l1.Set(ctx, "default:k", mustSerialize(t, NewCodecJsonSonic[string](), stale), time.Minute))
+
+		rec := &recordLogger{}
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithLogger(rec).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				panic("loader panic")
+			}).
+			WithSourceStrategy(SourceStrategyExpiredBackup).
+			Build()
+		assert.NoError(t, err)
+
+		// 缓存过期后并发刷新，panic在singleflight内恢复，所有调用方都拿到过期数据
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := cx.Get(ctx, "k")
+				assert.NoError(t, err)
+				assert.Equal(t, gptr.Of("stale"), got)
+			}()
+		}
+		wg.Wait()
+		assert.NotEmpty(t, rec.Errors())
+		assert.Contains(t, rec.Errors()[0], "loader panic")
+
+		// 没有兜底数据时panic作为错误返回
+		_, err = cx.Get(ctx, "missing")
+		assert.ErrorIs(t, err, ErrPanicRecovered)
+	})
+
+	t.Run("warm", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+				panic("mloader panic")
+			}).
+			Build()
+		assert.NoError(t, err)
+		assert.ErrorContains(t, cx.Warm(ctx, []string{"a", "b"}), "mloader panic")
+	})
+}
+
+func TestCachex_MultiLoaderE(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key int64) string { return fmt.Sprintf("%d", key) }
+	errOdd := errors.New("odd key")
+	// 奇数key回源失败，偶数key回源成功
+	loader := func(loaded *int64) MultiLoaderFnE[int64, string] {
+		return func(ctx context.Context, keys []int64) ([]*string, []error) {
+			atomic.AddInt64(loaded, int64(len(keys)))
+			values := make([]*string, len(keys))
+			errs := make([]error, len(keys))
+			for i, key := range keys {
+				if key%2 == 1 {
+					errs[i] = fmt.Errorf("load %d: %w", key, errOdd)
+					continue
+				}
+				values[i] = gptr.Of(fmt.Sprintf("v_%d", key))
+			}
+			return values, errs
+		}
+	}
+	keys := []int64{1, 2, 3, 4}
+
+	t.Run("partial failure", func(t *testing.T) {
+		var loaded int64
+		cx, err := New[int64, string]().
+			WithL1(NewLocalCacher(1)).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(loader(&loaded)).
+			Build()
+		assert.NoError(t, err)
+
+		got, err := cx.MGet(ctx, keys)
+		assert.Equal(t, []*string{nil, gptr.Of("v_2"), nil, gptr.Of("v_4")}, got)
+		var mErr *MultiLoadError[int64]
+		assert.True(t, errors.As(err, &mErr))
+		assert.Equal(t, []int64{1, 3}, mErr.Keys)
+		assert.Len(t, mErr.Errs, 2)
+		assert.ErrorIs(t, err, errOdd)
+		assert.Equal(t, int64(4), atomic.LoadInt64(&loaded))
+
+		// 成功的key已写入缓存，失败的key没有写入
+		got, err = cx.WithSourceStrategy(SourceStrategyCacheOnly).MGet(ctx, keys)
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, gptr.Of("v_2"), nil, gptr.Of("v_4")}, got)
+
+		// 再次读取只回源失败的key
+		_, err = cx.MGet(ctx, keys)
+		assert.ErrorIs(t, err, errOdd)
+		assert.Equal(t, int64(6), atomic.LoadInt64(&loaded))
+
+		// 单个key走批量回源，失败时返回错误
+		val, err := cx.Get(ctx, int64(5))
+		assert.Nil(t, val)
+		assert.ErrorIs(t, err, errOdd)
+		val, err = cx.Get(ctx, int64(6))
+		assert.NoError(t, err)
+		assert.Equal(t, "v_6", *val)
+	})
+
+	t.Run("expired backup", func(t *testing.T) {
+		var loaded int64
+		cx, err := New[int64, string]().
+			WithL1(NewLocalCacher(1)).
+			WithExpireTTL(time.Millisecond).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(loader(&loaded)).
+			WithSourceStrategy(SourceStrategyExpiredBackup).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.MSet(ctx, []int64{1, 2}, []*string{gptr.Of("old_1"), gptr.Of("old_2")}))
+		time.Sleep(5 * time.Millisecond)
+
+		// 失败的key使用过期的缓存兜底
+		got, err := cx.MGet(ctx, keys)
+		assert.Equal(t, []*string{gptr.Of("old_1"), gptr.Of("v_2"), nil, gptr.Of("v_4")}, got)
+		assert.ErrorIs(t, err, errOdd)
+	})
+
+	t.Run("warm", func(t *testing.T) {
+		var loaded int64
+		cx, err := New[int64, string]().
+			WithL1(NewLocalCacher(1)).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(loader(&loaded)).
+			Build()
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, cx.Warm(ctx, keys), errOdd)
+		got, err := cx.WithSourceStrategy(SourceStrategyCacheOnly).MGet(ctx, keys)
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{nil, gptr.Of("v_2"), nil, gptr.Of("v_4")}, got)
+	})
+
+	t.Run("result length mismatch", func(t *testing.T) {
+		cx, err := New[int64, string]().
+			WithGenKeyFn(genKeyFn).
+			WithMultiLoaderE(func(ctx context.Context, keys []int64) ([]*string, []error) {
+				return make([]*string, len(keys)), nil
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		got, err := cx.MGet(ctx, keys)
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, ErrLoaderResultMismatch)
+	})
+}
+
+func TestCachex_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	build := func(l2 Cacher) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithL2(l2).
+			WithGenKeyFn(func(key string) string { return key }).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+	l2s := map[string]Cacher{
+		"redis":  NewRedisCacher(cli),
+		"retry":  NewRetryCacher(NewRedisCacher(cli), 2, time.Millisecond),
+		"timing": NewTimingCacher(NewRedisCacher(cli), time.Second, nil),
+		"mirror": NewMirrorCacher(NewRedisCacher(cli), NewLocalCacher(1)),
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		for name, l2 := range l2s {
+			assert.NoError(t, build(l2).HealthCheck(ctx), name)
+		}
+	})
+
+	t.Run("local only", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(func(key string) string { return key }).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.HealthCheck(ctx))
+	})
+
+	t.Run("closed client", func(t *testing.T) {
+		assert.NoError(t, cli.Close())
+		for name, l2 := range l2s {
+			err := build(l2).HealthCheck(ctx)
+			assert.ErrorIs(t, err, redis.ErrClosed, name)
+			assert.ErrorContains(t, err, "l2 ping error", name)
+		}
+	})
+}
+
+func TestCachex_Derive(t *testing.T) {
+	ctx := context.Background()
+	l1 := NewLocalCacher(1)
+	logger := &recordLogger{}
+	base := New[any, any]().
+		WithNamespace("svc").
+		WithLogger(logger).
+		WithL1(l1).
+		WithExpireTTL(time.Minute).
+		WithDelTTL(time.Hour).
+		WithCacheNil(true).
+		WithSourceStrategy(SourceStrategyExpiredBackup).
+		WithSchemaVersion(2).
+		Base()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	users, err := Derive[int64, user](base).
+		WithNamespace("svc_user").
+		WithGenKeyFn(func(key int64) string { return fmt.Sprintf("%d", key) }).
+		Build()
+	assert.NoError(t, err)
+	names, err := Derive[string, string](base).
+		WithGenKeyFn(func(key string) string { return key }).
+		Build()
+	assert.NoError(t, err)
+
+	// 两个缓存都继承了base的配置
+	u := users.(*cachex[int64, user])
+	n := names.(*cachex[string, string])
+	for _, c := range []struct {
+		expireTTL time.Duration
+		logger    Logger
+		cacheNil  bool
+		ss        SourceStrategy
+		l1        Cacher
+		delTTL    time.Duration
+		version   uint8
+	}{
+		{u.expireTTL, u.logger, u.cacheNil, u.ss, u.cache.l1, u.cache.delTTL, u.cache.schemaVersion},
+		{n.expireTTL, n.logger, n.cacheNil, n.ss, n.cache.l1, n.cache.delTTL, n.cache.schemaVersion},
+	} {
+		assert.Equal(t, time.Minute, c.expireTTL)
+		assert.Same(t, logger, c.logger)
+		assert.True(t, c.cacheNil)
+		assert.Equal(t, SourceStrategyExpiredBackup, c.ss)
+		assert.Same(t, l1, c.l1)
+		assert.Equal(t, time.Hour, c.delTTL)
+		assert.Equal(t, uint8(2), c.version)
+	}
+	// 派生后的修改不影响base和其他缓存
+	assert.Equal(t, "svc_user", u.namespace)
+	assert.Equal(t, "svc", n.namespace)
+
+	// 共享同一个L1，key按各自的命名空间区分
+	assert.NoError(t, users.Set(ctx, 1, &user{Name: "alice"}))
+	assert.NoError(t, names.Set(ctx, "1", gptr.Of("bob")))
+	raw, err := l1.Get(ctx, "svc_user:1")
+	assert.NoError(t, err)
+	assert.NotNil(t, raw)
+	raw, err = l1.Get(ctx, "svc:1")
+	assert.NoError(t, err)
+	assert.NotNil(t, raw)
+
+	gotUser, err := users.Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", gotUser.Name)
+	gotName, err := names.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", *gotName)
+
+	t.Run("nil base", func(t *testing.T) {
+		b := Derive[string, string](nil).(*builder[string, string])
+		assert.Equal(t, "default", b.namespace)
+		assert.Equal(t, SourceStrategyCacheFirst, b.ss)
+		assert.True(t, b.reloadOnCorrupt)
+	})
+}
+
+func TestCachex_MDelBatch(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	keys := make([]string, 0, 2500)
+	for i := 0; i < 2500; i++ {
+		keys = append(keys, fmt.Sprintf("k%d", i))
+	}
+
+	t.Run("delete all", func(t *testing.T) {
+		s := miniredis.RunT(t)
+		cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		counter := &cmdCounter{counts: make(map[string]int)}
+		cli.AddHook(counter)
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithL2(NewRedisCacher(cli)).
+			WithDelTTL(time.Minute).
+			WithGenKeyFn(genKeyFn).
+			WithDelBatchSize(1000).
+			Build()
+		assert.NoError(t, err)
+		values := gslice.Map(keys, func(k string) *string { return gptr.Of("v_" + k) })
+		assert.NoError(t, cx.MSet(ctx, keys, values))
+		assert.Len(t, s.Keys(), len(keys))
+
+		assert.NoError(t, cx.MDel(ctx, keys))
+		assert.Empty(t, s.Keys())
+		assert.Equal(t, 3, counter.Count("del"))
+		got, err := cx.WithSourceStrategy(SourceStrategyCacheOnly).MGet(ctx, keys)
+		assert.NoError(t, err)
+		assert.Equal(t, make([]*string, len(keys)), got)
+	})
+
+	t.Run("continue after chunk error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		var deleted [][]string
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MDelete(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, keys []string) error {
+				deleted = append(deleted, keys)
+				// 第二批失败
+				if len(deleted) == 2 {
+					return assert.AnError
+				}
+				return nil
+			}).Times(3)
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithGenKeyFn(genKeyFn).
+			WithDelBatchSize(1000).
+			Build()
+		assert.NoError(t, err)
+
+		err = cx.MDel(ctx, keys)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Len(t, deleted, 3)
+		assert.Len(t, deleted[0], 1000)
+		assert.Len(t, deleted[1], 1000)
+		assert.Len(t, deleted[2], 500)
+	})
+}
+
+func TestCachex_MaxStaleness(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	codec := NewCodecJsonSonic[string]()
+	// expiredAgo 过期时长为ago的缓存
+	expiredAgo := func(val string, ago time.Duration) []byte {
+		e := &entry[string]{
+			createAt: time.Now().Add(-time.Minute - ago).UnixMilli(),
+			ttl:      time.Minute,
+			val:      gptr.Of(val),
+		}
+		return mustSerialize(t, codec, e)
+	}
+	newCx := func(l1 Cacher, maxStaleness time.Duration) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				return nil, assert.AnError
+			}).
+			WithSourceStrategy(SourceStrategyExpiredBackup).
+			WithMaxStaleness(maxStaleness).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	t.Run("within window", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		assert.NoError(t, l1.Set(ctx, "default:k", expiredAgo("stale", 30*time.Minute), time.Minute))
+		cx := newCx(l1, time.Hour)
+
+		val, err := cx.Get(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "stale", *val)
+
+		got, err := cx.MGet(ctx, []string{"k"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*string{gptr.Of("stale")}, got)
+	})
+
+	t.Run("beyond window", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		assert.NoError(t, l1.Set(ctx, "default:k", expiredAgo("stale", 2*time.Hour), time.Minute))
+		assert.NoError(t, l1.Set(ctx, "default:k2", expiredAgo("stale_2", 30*time.Minute), time.Minute))
+		cx := newCx(l1, time.Hour)
+
+		val, err := cx.Get(ctx, "k")
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, val)
+
+		// 过期太久的key不兜底并返回错误，其余key仍然兜底
+		got, err := cx.MGet(ctx, []string{"k", "k2"})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, []*string{nil, gptr.Of("stale_2")}, got)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		assert.NoError(t, l1.Set(ctx, "default:k", expiredAgo("stale", 2*time.Hour), time.Minute))
+		cx := newCx(l1, 0)
+
+		val, err := cx.Get(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "stale", *val)
+	})
+}
+
+func TestCachex_GetBypassLocal(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	codec := NewCodecJsonSonic[string]()
+
+	t.Run("l2 hit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l2 := NewMockCacher(ctrl)
+		// L1不读，只回填
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+		l1.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		l2.EXPECT().Get(gomock.Any(), "default:k").
+			Return(mustSerialize(t, codec, newEntry(gptr.Of("from_l2"), time.Minute)), nil).Times(1)
+
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithL2(l2).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				t.Fatal("loader should not be called")
+				return nil, nil
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		got, err := cx.GetBypassLocal(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "from_l2", *got)
+	})
+
+	t.Run("l2 miss", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l2 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+		l2.EXPECT().Get(gomock.Any(), "default:k").Return(nil, nil).Times(1)
+		var l1Val, l2Val []byte
+		l1.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+				l1Val = val
+				return nil
+			}).Times(1)
+		l2.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+				l2Val = val
+				return nil
+			}).Times(1)
+
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithL2(l2).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				return gptr.Of("from_source"), nil
+			}).
+			Build()
+		assert.NoError(t, err)
+
+		got, err := cx.GetBypassLocal(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "from_source", *got)
+		assert.Equal(t, "from_source", *mustGetValue(t, codec, deserializeEntry[string](l1Val)))
+		assert.Equal(t, "from_source", *mustGetValue(t, codec, deserializeEntry[string](l2Val)))
+	})
+
+	t.Run("stale l1 ignored", func(t *testing.T) {
+		l1 := NewLocalCacher(1)
+		l2 := NewLocalCacher(1)
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithL2(l2).
+			WithGenKeyFn(genKeyFn).
+			WithLoader(func(ctx context.Context, key string) (*string, error) {
+				return gptr.Of("from_source"), nil
+			}).
+			Build()
+		assert.NoError(t, err)
+		// L1中是旧数据，L2中是新数据
+		assert.NoError(t, l1.Set(ctx, "default:k", mustSerialize(t, codec, newEntry(gptr.Of("old"), time.Minute)), time.Minute))
+		assert.NoError(t, l2.Set(ctx, "default:k", mustSerialize(t, codec, newEntry(gptr.Of("new"), time.Minute)), time.Minute))
+
+		got, err := cx.Get(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "old", *got)
+		got, err = cx.GetBypassLocal(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "new", *got)
+		// L1已回填为L2的数据
+		got, err = cx.Get(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "new", *got)
+	})
+}
+
+func TestCachex_CompareAndSet(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	type counter struct {
+		Version int
+		Count   int
+	}
+	cases := map[string]func() (Cacher, Cacher){
+		"redis": func() (Cacher, Cacher) { return NewLocalCacher(1), NewRedisCacher(cli) },
+		"local": func() (Cacher, Cacher) { return NewLocalCacher(1), nil },
+		"decorated": func() (Cacher, Cacher) {
+			return NewLocalCacher(1), NewTimingCacher(NewRetryCacher(NewRedisCacher(cli), 2, 0), time.Second, nil)
+		},
+	}
+	for name, cachers := range cases {
+		t.Run(name, func(t *testing.T) {
+			s.FlushAll()
+			l1, l2 := cachers()
+			b := New[string, counter]().WithL1(l1).WithGenKeyFn(genKeyFn)
+			if l2 != nil {
+				b = b.WithL2(l2)
+			}
+			cx, err := b.Build()
+			assert.NoError(t, err)
+
+			// key不存在
+			swapped, err := cx.CompareAndSet(ctx, "k", &counter{Version: 1}, &counter{Version: 2})
+			assert.NoError(t, err)
+			assert.False(t, swapped)
+
+			assert.NoError(t, cx.Set(ctx, "k", &counter{Version: 1, Count: 10}))
+			// 值不一致
+			swapped, err = cx.CompareAndSet(ctx, "k", &counter{Version: 0, Count: 10}, &counter{Version: 2, Count: 11})
+			assert.NoError(t, err)
+			assert.False(t, swapped)
+			got, err := cx.WithSourceStrategy(SourceStrategyCacheOnly).Get(ctx, "k")
+			assert.NoError(t, err)
+			assert.Equal(t, &counter{Version: 1, Count: 10}, got)
+
+			// 替换成功，L1同步更新
+			old, err := cx.WithSourceStrategy(SourceStrategyCacheOnly).Get(ctx, "k")
+			assert.NoError(t, err)
+			swapped, err = cx.CompareAndSet(ctx, "k", old, &counter{Version: 2, Count: 11})
+			assert.NoError(t, err)
+			assert.True(t, swapped)
+			got, err = cx.WithSourceStrategy(SourceStrategyCacheOnly).Get(ctx, "k")
+			assert.NoError(t, err)
+			assert.Equal(t, &counter{Version: 2, Count: 11}, got)
+
+			// 旧值已被替换，再次使用旧值失败
+			swapped, err = cx.CompareAndSet(ctx, "k", old, &counter{Version: 3, Count: 12})
+			assert.NoError(t, err)
+			assert.False(t, swapped)
+		})
+	}
+
+	t.Run("nil value", func(t *testing.T) {
+		cx, err := New[string, string]().
+			WithL1(NewLocalCacher(1)).
+			WithGenKeyFn(genKeyFn).
+			WithCacheNil(true).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.Set(ctx, "k", nil))
+
+		// old为nil匹配缓存的空值
+		swapped, err := cx.CompareAndSet(ctx, "k", gptr.Of("v"), gptr.Of("v1"))
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+		swapped, err = cx.CompareAndSet(ctx, "k", nil, gptr.Of("v1"))
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+		swapped, err = cx.CompareAndSet(ctx, "k", nil, gptr.Of("v2"))
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		codec := NewCodecJsonSonic[string]()
+		l1.EXPECT().Get(gomock.Any(), "default:k").
+			Return(mustSerialize(t, codec, newEntry(gptr.Of("v"), time.Minute)), nil)
+		cx, err := New[string, string]().WithL1(l1).WithGenKeyFn(genKeyFn).Build()
+		assert.NoError(t, err)
+
+		swapped, err := cx.CompareAndSet(ctx, "k", gptr.Of("v"), gptr.Of("v1"))
+		assert.ErrorIs(t, err, ErrCASNotSupported)
+		assert.False(t, swapped)
+	})
+}
+
+func TestCachex_TTLJitter(t *testing.T) {
+	ctx := context.Background()
+	genKeyFn := func(key string) string { return key }
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l2 := NewMockCacher(ctrl)
+		l1.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), 10*time.Second).Return(nil).Times(1)
+		l2.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), 13*time.Second).Return(nil).Times(1)
+		l1.EXPECT().MSet(gomock.Any(), gomock.Any(), 10*time.Second).Return(nil).Times(1)
+		l2.EXPECT().MSet(gomock.Any(), gomock.Any(), 13*time.Second).Return(nil).Times(1)
+
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithL2(l2).
+			WithDelTTL(10 * time.Second).
+			WithGenKeyFn(genKeyFn).
+			WithTTLJitter(0).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+		assert.NoError(t, cx.MSet(ctx, []string{"k1", "k2"}, []*string{gptr.Of("v1"), gptr.Of("v2")}))
+	})
+
+	t.Run("injected rand", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), 10*time.Second+250*time.Millisecond).Return(nil).Times(1)
+
+		var gotMax int64
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(10 * time.Second).
+			WithGenKeyFn(genKeyFn).
+			WithTTLJitter(500 * time.Millisecond).
+			WithJitterRand(func(n int64) int64 {
+				gotMax = n
+				return n / 2
+			}).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+		assert.Equal(t, int64(500*time.Millisecond), gotMax)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Set(gomock.Any(), "default:k", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+				assert.GreaterOrEqual(t, ttl, 10*time.Second)
+				assert.Less(t, ttl, 11*time.Second)
+				return nil
+			}).Times(1)
+
+		cx, err := New[string, string]().
+			WithL1(l1).
+			WithDelTTL(10 * time.Second).
+			WithGenKeyFn(genKeyFn).
+			Build()
+		assert.NoError(t, err)
+		assert.NoError(t, cx.Set(ctx, "k", gptr.Of("v")))
+	})
+}
+
+func TestCachex_BatchKeyFn(t *testing.T) {
+	ctx := context.Background()
+	// 排序后拼接，顺序不同的相同批次也合并回源
+	batchKeyCalled := make(chan struct{}, 2)
+	batchKeyFn := func(keys []string) string {
+		batchKeyCalled <- struct{}{}
+		return strings.Join(slices.Sorted(slices.Values(keys)), "|")
+	}
+	var loaded int64
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	cx, err := New[string, string]().
+		WithL1(NewLocalCacher(1)).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithBatchKeyFn(batchKeyFn).
+		WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+			atomic.AddInt64(&loaded, 1)
+			entered <- struct{}{}
+			<-release
+			return gslice.Map(keys, func(k string) *string { return gptr.Of("v_" + k) }), nil
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([][]*string, 2)
+	batches := [][]string{{"a", "b"}, {"b", "a"}}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = cx.MGet(ctx, batches[0])
+	}()
+	<-batchKeyCalled
+	<-entered
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[1], _ = cx.MGet(ctx, batches[1])
+	}()
+	// 第二个请求生成batch key后加入singleflight
+	<-batchKeyCalled
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&loaded))
+	assert.Equal(t, []*string{gptr.Of("v_a"), gptr.Of("v_b")}, results[0])
+	assert.Equal(t, []*string{gptr.Of("v_b"), gptr.Of("v_a")}, results[1])
+}
+
+func TestCachex_BatchKeyFnCollision(t *testing.T) {
+	ctx := context.Background()
+	// 所有批次生成相同的key
+	batchKeyCalled := make(chan struct{}, 2)
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var loaded int64
+	cx, err := New[string, string]().
+		WithL1(NewSyncMapCacher()).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithBatchKeyFn(func(keys []string) string {
+			batchKeyCalled <- struct{}{}
+			return "same"
+		}).
+		WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+			if atomic.AddInt64(&loaded, 1) == 1 {
+				entered <- struct{}{}
+				<-release
+			}
+			return gslice.Map(keys, func(k string) *string { return gptr.Of("v_" + k) }), nil
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([][]*string, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		results[0], _ = cx.MGet(ctx, []string{"a"})
+	}()
+	<-batchKeyCalled
+	<-entered
+	go func() {
+		defer wg.Done()
+		results[1], _ = cx.MGet(ctx, []string{"b"})
+	}()
+	<-batchKeyCalled
+	close(release)
+	wg.Wait()
+
+	// 共享的结果不包含b，第二个请求单独回源
+	assert.Equal(t, int64(2), atomic.LoadInt64(&loaded))
+	assert.Equal(t, []*string{gptr.Of("v_a")}, results[0])
+	assert.Equal(t, []*string{gptr.Of("v_b")}, results[1])
+}
+
+func TestCachex_ConfigErrors(t *testing.T) {
+	ctx := context.Background()
+	cx, err := New[string, string]().
+		WithL1(NewSyncMapCacher()).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithSourceStrategy(SourceStrategySourceOnly).
+		Build()
+	assert.NoError(t, err)
+
+	t.Run("loader not set", func(t *testing.T) {
+		_, err := cx.Get(ctx, "k")
+		assert.ErrorIs(t, err, ErrLoaderNotSet)
+		_, err = cx.MGet(ctx, []string{"k1", "k2"})
+		assert.ErrorIs(t, err, ErrLoaderNotSet)
+		// 缓存优先未命中时同样需要回源
+		_, err = cx.GetWithStrategy(ctx, "k", SourceStrategyCacheFirst)
+		assert.ErrorIs(t, err, ErrLoaderNotSet)
+	})
+
+	t.Run("invalid source strategy", func(t *testing.T) {
+		_, err := cx.GetWithStrategy(ctx, "k", SourceStrategy(100))
+		assert.ErrorIs(t, err, ErrInvalidSourceStrategy)
+		assert.EqualError(t, err, "invalid source strategy: 100")
+		_, err = cx.MGetWithStrategy(ctx, []string{"k"}, SourceStrategy(100))
+		assert.ErrorIs(t, err, ErrInvalidSourceStrategy)
+	})
+}
+
+func TestMGetMap(t *testing.T) {
+	ctx := context.Background()
+	var loaded [][]string
+	cx, err := New[string, string]().
+		WithL1(NewSyncMapCacher()).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithMultiLoader(func(ctx context.Context, keys []string) ([]*string, error) {
+			loaded = append(loaded, keys)
+			vals := make([]*string, len(keys))
+			for i, key := range keys {
+				if key != "missing" {
+					vals[i] = gptr.Of("v_" + key)
+				}
+			}
+			return vals, nil
+		}).
+		Build()
+	assert.NoError(t, err)
+
+	got, err := MGetMap(ctx, cx, []string{"c", "a", "missing", "c", "b", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*string{
+		"a":       gptr.Of("v_a"),
+		"b":       gptr.Of("v_b"),
+		"c":       gptr.Of("v_c"),
+		"missing": nil,
+	}, got)
+	// 重复的key只回源一次
+	assert.Equal(t, [][]string{{"c", "a", "missing", "b"}}, loaded)
+
+	got, err = MGetMap(ctx, cx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+// memLoaderLocker 进程内的回源锁，多个节点共用同一个实例模拟分布式锁
+type memLoaderLocker struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func newMemLoaderLocker() *memLoaderLocker {
+	return &memLoaderLocker{locks: make(map[string]chan struct{})}
+}
+
+func (l *memLoaderLocker) sem(key string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.locks[key] == nil {
+		l.locks[key] = make(chan struct{}, 1)
+	}
+	return l.locks[key]
+}
+
+func (l *memLoaderLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (func(ctx context.Context) error, error) {
+	sem := l.sem(key)
+	select {
+	case sem <- struct{}{}:
+		return func(ctx context.Context) error {
+			<-sem
+			return nil
+		}, nil
+	case <-time.After(ttl):
+		return nil, errors.New("lock wait timeout")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// held key是否被锁定
+func (l *memLoaderLocker) held(key string) bool {
+	return len(l.sem(key)) > 0
+}
+
+func TestCachex_LoaderLock(t *testing.T) {
+	ctx := context.Background()
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer cli.Close()
+
+	var loads atomic.Int64
+	loader := func(ctx context.Context, key string) (*string, error) {
+		loads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return gptr.Of("v_" + key), nil
+	}
+	// 每个节点有各自的L1、singleflight，共享L2和回源锁
+	locker := newMemLoaderLocker()
+	newNode := func(ss SourceStrategy, ttl time.Duration) CacheX[string, string] {
+		cx, err := New[string, string]().
+			WithL1(NewSyncMapCacher()).
+			WithL2(NewRedisCacher(cli)).
+			WithGenKeyFn(func(key string) string { return key }).
+			WithLoader(loader).
+			WithSourceStrategy(ss).
+			WithLoaderLock(locker, ttl).
+			Build()
+		assert.NoError(t, err)
+		return cx
+	}
+
+	for _, ss := range []SourceStrategy{SourceStrategyCacheFirst, SourceStrategyExpiredBackup} {
+		t.Run(fmt.Sprintf("loader runs once across nodes, ss=%d", ss), func(t *testing.T) {
+			loads.Store(0)
+			key := fmt.Sprintf("hot_%d", ss)
+			nodes := make([]CacheX[string, string], 5)
+			for i := range nodes {
+				nodes[i] = newNode(ss, 5*time.Second)
+			}
+			var wg sync.WaitGroup
+			var fromCache atomic.Int64
+			for _, node := range nodes {
+				for j := 0; j < 4; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						val, hit, err := node.GetE(ctx, key)
+						assert.NoError(t, err)
+						assert.Equal(t, "v_"+key, *val)
+						if hit {
+							fromCache.Add(1)
+						}
+					}()
+				}
+			}
+			wg.Wait()
+			assert.Equal(t, int64(1), loads.Load())
+			assert.Positive(t, fromCache.Load())
+			// 锁已释放
+			assert.False(t, locker.held(loaderLockPrefix+"default:"+key))
+		})
+	}
+
+	t.Run("load without lock after wait timeout", func(t *testing.T) {
+		loads.Store(0)
+		unlock, err := locker.Acquire(ctx, loaderLockPrefix+"default:locked", time.Minute)
+		assert.NoError(t, err)
+		defer unlock(ctx)
+
+		rec := &recordLogger{}
+		cx, err := New[string, string]().
+			WithL1(NewSyncMapCacher()).
+			WithL2(NewRedisCacher(cli)).
+			WithGenKeyFn(func(key string) string { return key }).
+			WithLoader(loader).
+			WithLogger(rec).
+			WithLoaderLock(locker, 30*time.Millisecond).
+			Build()
+		assert.NoError(t, err)
+
+		val, err := cx.Get(ctx, "locked")
+		assert.NoError(t, err)
+		assert.Equal(t, "v_locked", *val)
+		assert.Equal(t, int64(1), loads.Load())
+		assert.True(t, slices.ContainsFunc(rec.Warns(), func(msg string) bool {
+			return strings.Contains(msg, "acquire loader lock fail")
+		}))
+	})
+
+	t.Run("ttl required", func(t *testing.T) {
+		_, err := New[string, string]().
+			WithGenKeyFn(func(key string) string { return key }).
+			WithLoader(loader).
+			WithLoaderLock(locker, 0).
+			Build()
+		assert.Error(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package cachex
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 
@@ -48,9 +49,23 @@ func (rawString[V]) Unmarshal(data []byte) (*V, error) {
 	return &v, nil
 }
 
-// NewCodecJsonSonic encoding/json
+// NewCodecAuto 按V的类型选择编解码：[]byte使用NewCodecBytesDirect，string使用NewCodecRawString，其他类型使用NewCodecJsonSonic
+// 泛型无法直接对类型参数做type switch，这里对*V做类型断言，只匹配[]byte、string本身，基于它们定义的新类型仍使用json
+// 与默认的json编解码不兼容，已有缓存的类型切换时需要通过WithSchemaVersion升级版本，否则旧值会被当作原始数据读出
+func NewCodecAuto[V any]() Codec[V] {
+	switch any((*V)(nil)).(type) {
+	case *[]byte:
+		return any(NewCodecBytesDirect()).(Codec[V])
+	case *string:
+		return any(NewCodecRawString()).(Codec[V])
+	default:
+		return NewCodecJsonSonic[V]()
+	}
+}
+
+// NewCodecJsonSonic bytedance/sonic
 func NewCodecJsonSonic[V any]() Codec[V] {
-	return &jsonStd[V]{}
+	return &jsonSonic[V]{}
 }
 
 type jsonSonic[V any] struct{}
@@ -87,3 +102,26 @@ func (j *jsonStd[V]) Unmarshal(data []byte) (*V, error) {
 	}
 	return &v, nil
 }
+
+// NewCodecJsonNumber encoding/json，解码时使用 UseNumber
+// 数字会被解码为 json.Number 而不是 float64，适用于 interface{} 中包含超过 2^53 的 int64 的场景
+func NewCodecJsonNumber[V any]() Codec[V] {
+	return &jsonNumber[V]{}
+}
+
+type jsonNumber[V any] struct{}
+
+func (j *jsonNumber[V]) Marshal(v *V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (j *jsonNumber[V]) Unmarshal(data []byte) (*V, error) {
+	var v V
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,134 @@
+package cachex
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecJsonNumber(t *testing.T) {
+	bigInt := int64(math.MaxInt64 - 1) // 大于 2^53，float64 无法精确表示
+	value := map[string]interface{}{"id": bigInt}
+
+	t.Run("std loses precision", func(t *testing.T) {
+		codec := NewCodecJsonStd[map[string]interface{}]()
+		data, err := codec.Marshal(&value)
+		assert.NoError(t, err)
+		got, err := codec.Unmarshal(data)
+		assert.NoError(t, err)
+		_, ok := (*got)["id"].(float64)
+		assert.True(t, ok)
+	})
+
+	t.Run("number round trip", func(t *testing.T) {
+		codec := NewCodecJsonNumber[map[string]interface{}]()
+		data, err := codec.Marshal(&value)
+		assert.NoError(t, err)
+		got, err := codec.Unmarshal(data)
+		assert.NoError(t, err)
+		num, ok := (*got)["id"].(json.Number)
+		assert.True(t, ok)
+		n, err := num.Int64()
+		assert.NoError(t, err)
+		assert.Equal(t, bigInt, n)
+	})
+
+	t.Run("number through entry", func(t *testing.T) {
+		codec := NewCodecJsonNumber[map[string]interface{}]()
+		e := newEntry(&value, 0)
+		bytes, err := e.Serialize(codec)
+		assert.NoError(t, err)
+		got, err := deserializeEntry[map[string]interface{}](bytes).Value(codec)
+		assert.NoError(t, err)
+		n, err := (*got)["id"].(json.Number).Int64()
+		assert.NoError(t, err)
+		assert.Equal(t, bigInt, n)
+	})
+}
+
+func TestCodecJsonSonic(t *testing.T) {
+	codec := NewCodecJsonSonic[map[string]string]()
+	_, ok := codec.(*jsonSonic[map[string]string])
+	assert.True(t, ok)
+
+	value := map[string]string{"hello": "world"}
+	data, err := codec.Marshal(&value)
+	assert.NoError(t, err)
+	got, err := codec.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, value, *got)
+}
+
+func TestCodecAuto(t *testing.T) {
+	t.Run("bytes", func(t *testing.T) {
+		codec := NewCodecAuto[[]byte]()
+		_, ok := codec.(*bytesDirect[[]byte])
+		assert.True(t, ok)
+		value := []byte("hello")
+		data, err := codec.Marshal(&value)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), data)
+	})
+	t.Run("string", func(t *testing.T) {
+		codec := NewCodecAuto[string]()
+		_, ok := codec.(*rawString[string])
+		assert.True(t, ok)
+		value := "hello"
+		data, err := codec.Marshal(&value)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), data)
+	})
+	t.Run("struct", func(t *testing.T) {
+		_, ok := NewCodecAuto[benchCodecValue]().(*jsonSonic[benchCodecValue])
+		assert.True(t, ok)
+	})
+	t.Run("named string", func(t *testing.T) {
+		type name string
+		_, ok := NewCodecAuto[name]().(*jsonSonic[name])
+		assert.True(t, ok)
+	})
+	t.Run("builder", func(t *testing.T) {
+		// 默认仍为json，兼容已有缓存
+		_, ok := New[string, string]().(*builder[string, string]).codec.(*jsonSonic[string])
+		assert.True(t, ok)
+		_, ok = New[string, string]().WithAutoCodec().(*builder[string, string]).codec.(*rawString[string])
+		assert.True(t, ok)
+	})
+}
+
+type benchCodecValue struct {
+	ID    int64             `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+func benchmarkCodec(b *testing.B, codec Codec[benchCodecValue]) {
+	value := &benchCodecValue{
+		ID:    1024,
+		Name:  "benchmark",
+		Tags:  []string{"a", "b", "c", "d"},
+		Attrs: map[string]string{"k1": "v1", "k2": "v2"},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := codec.Marshal(value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = codec.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCodecJsonSonic(b *testing.B) {
+	benchmarkCodec(b, NewCodecJsonSonic[benchCodecValue]())
+}
+
+func BenchmarkCodecJsonStd(b *testing.B) {
+	benchmarkCodec(b, NewCodecJsonStd[benchCodecValue]())
+}
@@ -3,46 +3,88 @@ package cachex
 import (
 	"encoding/binary"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // +------------------------+------------------------+--------+----------------+
-// | CreateAt               | TTL                    | IsNil  | Value          |
+// | CreateAt               | TTL                    | Flags  | Value          |
 // +------------------------+------------------------+--------+----------------+
 // ↑                        ↑                        ↑        ↑
 // 第0字节                  第8字节                  第16字节  第17字节
 //
 // 总长度 = 17字节(固定头部) + len(Value)字节
+//
+// Flags最低位表示是否为空值(IsNil)，最高位表示头部后带有1字节的schema版本：
+//
+// +------------------------+------------------------+--------+---------+----------------+
+// | CreateAt               | TTL                    | Flags  | Version | Value          |
+// +------------------------+------------------------+--------+---------+----------------+
+// ↑                        ↑                        ↑        ↑         ↑
+// 第0字节                  第8字节                  第16字节  第17字节   第18字节
+//
+// 版本为0时不写入版本字节，与旧格式保持兼容
 
 const (
 	bytesCreateAtSize = 8
 	bytesTTLSize      = 8
 	bytesIsNilSize    = 1
 	bytesHeaderSize   = bytesCreateAtSize + bytesTTLSize + bytesIsNilSize
+	bytesVersionSize  = 1
+
+	flagIsNil   uint8 = 1 << 0 // 空值
+	flagVersion uint8 = 1 << 7 // 带有schema版本
 )
 
+// entry 会经singleflight共享给多个调用方并发读取，创建后只允许通过sync.Once延迟填充raw和val
 type entry[V any] struct {
 	createAt int64         // 创建时间
 	ttl      time.Duration // 业务过期时间
 	valBytes []byte        // value序列化后的值
 	val      *V            // 缓存值
 	isNil    uint8         // 是否为空值
+	version  uint8         // schema版本，0表示未设置
+	raw      []byte        // 完整序列化结果(头部+value)，用于避免重复序列化
+
+	rawOnce sync.Once // 保护raw的延迟序列化
+	rawErr  error     // 延迟序列化的错误
+	valOnce sync.Once // 保护val的延迟反序列化
+	valErr  error     // 延迟反序列化的错误
 }
 
 func (e *entry[V]) Serialize(codec Codec[V]) ([]byte, error) {
-	if len(e.valBytes) == 0 && e.val != nil {
+	// 已有完整序列化结果(反序列化得到或已序列化过)，直接复用，避免再次分配和拷贝
+	e.rawOnce.Do(func() {
+		if e.raw == nil {
+			e.raw, e.rawErr = e.serialize(codec)
+		}
+	})
+	return e.raw, e.rawErr
+}
+
+func (e *entry[V]) serialize(codec Codec[V]) ([]byte, error) {
+	valBytes := e.valBytes
+	if len(valBytes) == 0 && e.val != nil {
 		bytes, err := codec.Marshal(e.val)
 		if err != nil {
-			return nil, fmt.Errorf("cachex: failed to marshal value: %v", err)
+			return nil, fmt.Errorf("cachex: failed to marshal value: %w", err)
 		}
-		e.valBytes = bytes
+		valBytes = bytes
 	}
-	totalLen := bytesHeaderSize + len(e.valBytes)
-	buffer := make([]byte, totalLen)
+	headerLen := bytesHeaderSize
+	flags := e.isNil & flagIsNil
+	if e.version != 0 {
+		headerLen += bytesVersionSize
+		flags |= flagVersion
+	}
+	buffer := make([]byte, headerLen+len(valBytes))
 	binary.LittleEndian.PutUint64(buffer[0:bytesCreateAtSize], uint64(e.createAt))
 	binary.LittleEndian.PutUint64(buffer[bytesCreateAtSize:bytesCreateAtSize+bytesTTLSize], uint64(e.ttl))
-	buffer[bytesCreateAtSize+bytesTTLSize] = e.isNil
-	copy(buffer[bytesHeaderSize:], e.valBytes)
+	buffer[bytesCreateAtSize+bytesTTLSize] = flags
+	if e.version != 0 {
+		buffer[bytesHeaderSize] = e.version
+	}
+	copy(buffer[headerLen:], valBytes)
 	return buffer, nil
 }
 
@@ -69,20 +111,36 @@ func (e *entry[V]) Value(codec Codec[V]) (*V, error) {
 	if e.IsNil() {
 		return nil, nil
 	}
-	if e.val != nil {
-		return e.val, nil
-	}
-	val, err := codec.Unmarshal(e.valBytes)
-	if err != nil {
-		panic(fmt.Errorf("cachex: failed to unmarshal value: %v", err))
+	e.valOnce.Do(func() {
+		if e.val != nil {
+			return
+		}
+		// bytesDirect 直接引用 valBytes，不发生拷贝
+		val, err := codec.Unmarshal(e.valBytes)
+		if err != nil {
+			e.valErr = fmt.Errorf("cachex: failed to unmarshal value: %w", err)
+			return
+		}
+		e.val = val
+	})
+	if e.valErr != nil {
+		return nil, e.valErr
 	}
-	return val, nil
+	return e.val, nil
 }
 
 func (e *entry[V]) CreateAt() int64 {
 	return e.createAt
 }
 
+// RemainingTTL 距离业务过期的剩余时间，已过期时小于等于0，不过期时返回TTLNoExpiration
+func (e *entry[V]) RemainingTTL() time.Duration {
+	if e.ttl <= 0 {
+		return TTLNoExpiration
+	}
+	return time.Until(time.UnixMilli(e.createAt).Add(e.ttl))
+}
+
 func newEntry[V any](val *V, ttl time.Duration) *entry[V] {
 	if val == nil {
 		return &entry[V]{
@@ -103,13 +161,25 @@ func newEntry[V any](val *V, ttl time.Duration) *entry[V] {
 }
 
 func deserializeEntry[V any](bytes []byte) *entry[V] {
-	if len(bytes) < 17 {
+	if len(bytes) < bytesHeaderSize {
 		return nil
 	}
+	flags := bytes[bytesCreateAtSize+bytesTTLSize]
+	headerLen := bytesHeaderSize
+	var version uint8
+	if flags&flagVersion != 0 {
+		if len(bytes) < bytesHeaderSize+bytesVersionSize {
+			return nil
+		}
+		version = bytes[bytesHeaderSize]
+		headerLen += bytesVersionSize
+	}
 	return &entry[V]{
 		createAt: int64(binary.LittleEndian.Uint64(bytes[0:bytesCreateAtSize])),
 		ttl:      time.Duration(int64(binary.LittleEndian.Uint64(bytes[bytesCreateAtSize : bytesCreateAtSize+bytesTTLSize]))),
-		isNil:    bytes[bytesCreateAtSize+bytesTTLSize],
-		valBytes: bytes[bytesHeaderSize:],
+		isNil:    flags & flagIsNil,
+		version:  version,
+		valBytes: bytes[headerLen:],
+		raw:      bytes,
 	}
 }
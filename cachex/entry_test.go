@@ -52,3 +52,96 @@ func TestEntry(t *testing.T) {
 		assert.Equal(t, e.isNil, e2.isNil)
 	})
 }
+
+func TestEntrySerializeReuse(t *testing.T) {
+	codec := NewCodecBytesDirect()
+	e := newEntry(gptr.Of([]byte("hello")), time.Minute)
+	b1, err := e.Serialize(codec)
+	assert.NoError(t, err)
+	b2, err := e.Serialize(codec)
+	assert.NoError(t, err)
+	assert.Same(t, &b1[0], &b2[0])
+
+	// 反序列化得到的entry再次序列化，直接复用原始bytes
+	e2 := deserializeEntry[[]byte](b1)
+	b3, err := e2.Serialize(codec)
+	assert.NoError(t, err)
+	assert.Same(t, &b1[0], &b3[0])
+
+	// bytesDirect 取值不拷贝
+	val, err := e2.Value(codec)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), *val)
+	assert.Same(t, &b1[bytesHeaderSize], &(*val)[0])
+}
+
+func BenchmarkEntryBytesDirect1MB(b *testing.B) {
+	codec := NewCodecBytesDirect()
+	value := make([]byte, 1<<20)
+	bytes, err := newEntry(&value, time.Minute).Serialize(codec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("serialize", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := newEntry(&value, time.Minute)
+			// 写L2、L1各序列化一次
+			_, _ = e.Serialize(codec)
+			_, _ = e.Serialize(codec)
+		}
+	})
+	b.Run("reserialize", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// L2命中回填L1
+			_, _ = deserializeEntry[[]byte](bytes).Serialize(codec)
+		}
+	})
+	b.Run("value", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = deserializeEntry[[]byte](bytes).Value(codec)
+		}
+	})
+}
+
+func TestEntrySchemaVersion(t *testing.T) {
+	codec := NewCodecJsonSonic[string]()
+
+	t.Run("version round trip", func(t *testing.T) {
+		e := newEntry(gptr.Of("hello"), time.Minute)
+		e.version = 3
+		bytes, err := e.Serialize(codec)
+		assert.NoError(t, err)
+		assert.Len(t, bytes, bytesHeaderSize+bytesVersionSize+len(`"hello"`))
+
+		e2 := deserializeEntry[string](bytes)
+		assert.Equal(t, uint8(3), e2.version)
+		assert.False(t, e2.IsNil())
+		val, err := e2.Value(codec)
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("hello"), val)
+	})
+
+	t.Run("nil with version", func(t *testing.T) {
+		e := newEntry[string](nil, time.Minute)
+		e.version = 1
+		e2 := deserializeEntry[string](mustSerialize(t, codec, e))
+		assert.True(t, e2.IsNil())
+		assert.Equal(t, uint8(1), e2.version)
+	})
+
+	t.Run("version 0 keeps legacy format", func(t *testing.T) {
+		bytes := mustSerialize(t, codec, newEntry(gptr.Of("hello"), time.Minute))
+		assert.Len(t, bytes, bytesHeaderSize+len(`"hello"`))
+		assert.Equal(t, uint8(0), deserializeEntry[string](bytes).version)
+	})
+
+	t.Run("truncated version", func(t *testing.T) {
+		e := newEntry[string](nil, time.Minute)
+		e.version = 1
+		bytes := mustSerialize(t, codec, e)
+		assert.Nil(t, deserializeEntry[string](bytes[:bytesHeaderSize]))
+	})
+}
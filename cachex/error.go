@@ -0,0 +1,66 @@
+package cachex
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误定义
+var (
+	ErrKeyValueLengthMismatch = errors.New("keys values length not equal")
+	ErrLoaderResultMismatch   = errors.New("len(keys) != len(values)")
+	ErrInvalidEntry           = errors.New("invalid cache entry")
+	ErrNotFound               = errors.New("not found") // loader返回该错误表示数据不存在，配置了fallback loader时会继续尝试
+	ErrCacheMiss              = errors.New("cache miss")
+	ErrCASNotSupported        = errors.New("cacher does not support compare and swap") // Cacher未实现CompareAndSwapper
+	ErrLoaderNotSet           = errors.New("loader not set")                           // 需要回源但未设置loader，属于配置错误
+	ErrInvalidSourceStrategy  = errors.New("invalid source strategy")                  // 回源策略不合法，属于配置错误
+	ErrValueTooLarge          = errors.New("cache value too large")                    // value超过NewMaxSizeCacher的限制，未写入缓存
+	ErrPanicRecovered         = errors.New("panic recovered")                          // loader panic，已恢复并作为错误返回
+	ErrTTLExceedsDelTTL       = errors.New("ttl exceeds del ttl")                      // MSetWithTTL的失效时间大于删除时间，缓存会在失效前被删除
+)
+
+// MultiLoadError 批量回源部分key失败，或关闭WithReloadOnCorrupt时部分key的缓存值无法解析，Keys与Errs一一对应
+// 使用errors.As获取，如: var mErr *MultiLoadError[int64]; errors.As(err, &mErr)
+type MultiLoadError[K any] struct {
+	Keys []K
+	Errs []error
+}
+
+func (e *MultiLoadError[K]) Error() string {
+	return fmt.Sprintf("mloader fn err: %d keys failed, first: %v", len(e.Errs), e.Errs[0])
+}
+
+func (e *MultiLoadError[K]) Unwrap() []error {
+	return e.Errs
+}
+
+// corruptKeysError 关闭WithReloadOnCorrupt时批量读缓存无法解析的key，key为缓存key
+// 与其余key的结果一起返回，由MGet转换为*MultiLoadError
+type corruptKeysError map[string]error
+
+func (e corruptKeysError) Error() string {
+	return fmt.Sprintf("cachex: %d keys corrupt", len(e))
+}
+
+// merge 合并other，返回合并后的结果
+func (e corruptKeysError) merge(other corruptKeysError) corruptKeysError {
+	if len(other) == 0 {
+		return e
+	}
+	if e == nil {
+		e = make(corruptKeysError, len(other))
+	}
+	for k, err := range other {
+		e[k] = err
+	}
+	return e
+}
+
+// orNil 没有无法解析的key时返回nil，避免返回非nil的空error
+func (e corruptKeysError) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
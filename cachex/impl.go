@@ -1,7 +1,9 @@
 package cachex
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -13,19 +15,35 @@ import (
 	"golang.org/x/sync/singleflight"
 )
 
+const (
+	warmBatchSize       = 100  // Warm预热每批回源的key数量
+	defaultDelBatchSize = 1000 // MDel默认每批删除的key数量
+
+	loaderLockPrefix = "cachex_load_lock:" // 回源锁的key前缀，拼接缓存key
+)
+
 type cachex[K any, V any] struct {
-	namespace string              // 命名空间，用于区分key
-	codec     Codec[V]            // 编解码
-	expireTTL time.Duration       // 缓存过期时间
-	logger    Logger              // logger
-	cache     *wrapper[V]         // 缓存
-	genKeyFn  GenKeyFn[K]         // 生成缓存key函数
-	loaderFn  LoaderFn[K, V]      // 单个回源函数
-	mLoaderFn MultiLoaderFn[K, V] // 批量回源函数
-	cacheNil  bool                // 是否缓存空值
-	group     singleflight.Group  // 单个回源singleflight
-	mGroup    singleflight.Group  // 批量回源singleflight
-	ss        SourceStrategy      // 缓存策略
+	namespace  string               // 命名空间，用于区分key，已转义分隔符
+	codec      Codec[V]             // 编解码
+	expireTTL  time.Duration        // 缓存过期时间
+	logger     Logger               // logger
+	cache      *wrapper[V]          // 缓存
+	genKeyFn   GenKeyFn[K]          // 生成缓存key函数
+	batchKeyFn BatchKeyFn[K]        // 生成批量回源singleflight key函数，为nil时拼接所有缓存key
+	loaderFn   LoaderFn[K, V]       // 单个回源函数
+	mLoaderFn  MultiLoaderFn[K, V]  // 批量回源函数
+	mLoaderFnE MultiLoaderFnE[K, V] // 可部分失败的批量回源函数
+	fbLoaderFn LoaderFn[K, V]       // 备用回源函数
+	cacheNil   bool                 // 是否缓存空值
+	group      singleflight.Group   // 单个回源singleflight
+	mGroup     singleflight.Group   // 批量回源singleflight
+	ss         SourceStrategy       // 缓存策略
+
+	warmConcurrency int           // 预热并发数
+	delBatchSize    int           // MDel每批删除的key数量
+	maxStaleness    time.Duration // ExpiredBackup兜底时允许的最大过期时长，0表示不限制
+	loaderLock      LoaderLocker  // 缓存未命中时回源前获取的分布式锁，为nil时不加锁
+	loaderLockTTL   time.Duration // 回源锁的过期时间，也是等待锁的最长时间
 }
 
 func (c *cachex[K, V]) WithSourceStrategy(ss SourceStrategy) CacheX[K, V] {
@@ -35,7 +53,49 @@ func (c *cachex[K, V]) WithSourceStrategy(ss SourceStrategy) CacheX[K, V] {
 }
 
 func (c *cachex[K, V]) Get(ctx context.Context, key K) (*V, error) {
-	switch c.ss {
+	val, _, err := c.GetE(ctx, key)
+	return val, err
+}
+
+func (c *cachex[K, V]) GetE(ctx context.Context, key K) (*V, bool, error) {
+	val, src, err := c.getValue(ctx, key, c.ss)
+	return val, src.fromCache(), err
+}
+
+func (c *cachex[K, V]) GetWithSource(ctx context.Context, key K) (*V, Source, error) {
+	return c.getValue(ctx, key, c.ss)
+}
+
+func (c *cachex[K, V]) GetWithStrategy(ctx context.Context, key K, ss SourceStrategy) (*V, error) {
+	val, _, err := c.getValue(ctx, key, ss)
+	return val, err
+}
+
+func (c *cachex[K, V]) getValue(ctx context.Context, key K, ss SourceStrategy) (*V, Source, error) {
+	e, src, err := c.get(ctx, key, ss)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
+	if e == nil {
+		return nil, src, nil
+	}
+	// 缓存的空值不区分来自哪一级缓存
+	if src.fromCache() && e.IsNil() {
+		src = SourceNil
+	}
+	val, err := e.Value(c.codec)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
+	return val, src, nil
+}
+
+// get 按回源策略获取entry，Source表示结果来自哪一级缓存或回源
+func (c *cachex[K, V]) get(ctx context.Context, key K, ss SourceStrategy) (*entry[V], Source, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, SourceMiss, err
+	}
+	switch ss {
 	case SourceStrategyCacheFirst:
 		return c.ssCacheFirstGet(ctx, key)
 	case SourceStrategySourceFirst:
@@ -47,94 +107,200 @@ func (c *cachex[K, V]) Get(ctx context.Context, key K) (*V, error) {
 	case SourceStrategyExpiredBackup:
 		return c.ssExpiredBackupGet(ctx, key)
 	default:
-		return nil, fmt.Errorf("invalid source strategy: %v", c.ss)
+		return nil, SourceMiss, fmt.Errorf("%w: %v", ErrInvalidSourceStrategy, ss)
 	}
 }
 
-func (c *cachex[K, V]) ssCacheFirstGet(ctx context.Context, key K) (*V, error) {
+func (c *cachex[K, V]) ssCacheFirstGet(ctx context.Context, key K) (*entry[V], Source, error) {
 	// 先读缓存
 	cacheKey := c.key(key)
-	fromCache := c.cache.Get(ctx, cacheKey)
+	fromCache, src, err := c.cache.GetWithSource(ctx, cacheKey)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
 	// 存在且没过期
 	if fromCache != nil && !fromCache.IsExpired() {
-		return fromCache.Value(c.codec)
+		return fromCache, src, nil
 	}
-	// 回源
-	fromSource, err := c.load(ctx, key)
-	if err != nil {
-		return nil, err
-	}
-	// 设置缓存
-	_ = c.set(ctx, cacheKey, fromSource)
-	return fromSource.Value(c.codec)
+	// 回源并设置缓存
+	return c.loadOnMiss(ctx, key, cacheKey)
 }
 
-func (c *cachex[K, V]) ssSourceFirstGet(ctx context.Context, key K) (*V, error) {
+func (c *cachex[K, V]) ssSourceFirstGet(ctx context.Context, key K) (*entry[V], Source, error) {
 	// 回源
 	cacheKey := c.key(key)
 	fromSource, err := c.load(ctx, key)
 	if err != nil {
-		// 回源失败，缓存兜底
-		fromCache := c.cache.Get(ctx, cacheKey)
-		if fromCache != nil && !fromCache.IsExpired() {
+		// 回源失败，缓存兜底，读缓存失败视为无兜底
+		fromCache, src, cacheErr := c.cache.GetWithSource(ctx, cacheKey)
+		if cacheErr == nil && fromCache != nil && !fromCache.IsExpired() {
 			// 有缓存兜底
-			return fromCache.Value(c.codec)
+			return fromCache, src, nil
 		}
 		// 没有缓存兜底，返回error
-		return nil, err
+		return nil, SourceMiss, err
 	}
 	// 刷新缓存
 	_ = c.set(ctx, cacheKey, fromSource)
-	return fromSource.Value(c.codec)
+	return fromSource, SourceLoader, nil
 }
 
-func (c *cachex[K, V]) ssCacheOnlyGet(ctx context.Context, key K) (*V, error) {
+func (c *cachex[K, V]) ssCacheOnlyGet(ctx context.Context, key K) (*entry[V], Source, error) {
 	cacheKey := c.key(key)
-	fromCache := c.cache.Get(ctx, cacheKey)
+	fromCache, src, err := c.cache.GetWithSource(ctx, cacheKey)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
 	// 存在且没过期
 	if fromCache != nil && !fromCache.IsExpired() {
-		return fromCache.Value(c.codec)
+		return fromCache, src, nil
 	}
-	return nil, nil
+	return nil, SourceMiss, nil
 }
 
-func (c *cachex[K, V]) ssSourceOnlyGet(ctx context.Context, key K) (*V, error) {
+func (c *cachex[K, V]) ssSourceOnlyGet(ctx context.Context, key K) (*entry[V], Source, error) {
 	fromSource, err := c.load(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, SourceMiss, err
 	}
-	return fromSource.Value(c.codec)
+	return fromSource, SourceLoader, nil
 }
 
-func (c *cachex[K, V]) ssExpiredBackupGet(ctx context.Context, key K) (*V, error) {
+func (c *cachex[K, V]) ssExpiredBackupGet(ctx context.Context, key K) (*entry[V], Source, error) {
 	// 先读缓存
 	cacheKey := c.key(key)
-	fromCache := c.cache.Get(ctx, cacheKey)
+	fromCache, cacheSrc, err := c.cache.GetWithSource(ctx, cacheKey)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
 	// 存在且没过期
 	if fromCache != nil && !fromCache.IsExpired() {
-		return fromCache.Value(c.codec)
+		return fromCache, cacheSrc, nil
 	}
-	// 回源
-	fromSource, err := c.load(ctx, key)
+	// 回源并更新缓存
+	fromSource, src, err := c.loadOnMiss(ctx, key, cacheKey)
 	if err != nil {
 		// 回源失败，过期缓存兜底
-		if fromCache != nil {
-			return fromCache.Value(c.codec)
+		if c.usableBackup(fromCache) {
+			return fromCache, cacheSrc, nil
 		}
 		// 没有缓存兜底，返回error
+		return nil, SourceMiss, err
+	}
+	return fromSource, src, nil
+}
+
+func (c *cachex[K, V]) GetBypassLocal(ctx context.Context, key K) (*V, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// 只读L2，命中时回填L1
+	cacheKey := c.key(key)
+	fromL2, err := c.cache.GetL2(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	if fromL2 != nil && !fromL2.IsExpired() {
+		return fromL2.Value(c.codec)
+	}
+	// 回源并写入L1、L2
+	fromSource, err := c.load(ctx, key)
+	if err != nil {
 		return nil, err
 	}
-	// 更新缓存
 	_ = c.set(ctx, cacheKey, fromSource)
 	return fromSource.Value(c.codec)
 }
 
+func (c *cachex[K, V]) GetOrSet(ctx context.Context, key K, valueFn func(ctx context.Context) (*V, error)) (*V, error) {
+	if valueFn == nil {
+		return nil, fmt.Errorf("value fn not set")
+	}
+	// 先读缓存
+	cacheKey := c.key(key)
+	fromCache, err := c.cache.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	// 存在且没过期
+	if fromCache != nil && !fromCache.IsExpired() {
+		return fromCache.Value(c.codec)
+	}
+	// 使用valueFn获取并写入缓存，与loader回源区分singleflight key
+	v, err, _ := c.group.Do("s"+cacheKey, func() (interface{}, error) {
+		val, err := valueFn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("value fn err: %w", err)
+		}
+		e := c.cache.newEntry(val, c.expireTTL)
+		_ = c.set(ctx, cacheKey, e)
+		return e, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*entry[V]).Value(c.codec)
+}
+
+// loadOnMiss 缓存未命中时回源并写入缓存，Source表示结果来自缓存还是回源
+// 配置了回源锁时，获取锁后先重新读缓存，其他进程已经回源写入时直接使用缓存，避免多个进程同时回源
+func (c *cachex[K, V]) loadOnMiss(ctx context.Context, key K, cacheKey string) (*entry[V], Source, error) {
+	if c.loaderLock == nil {
+		fromSource, err := c.load(ctx, key)
+		if err != nil {
+			return nil, SourceMiss, err
+		}
+		_ = c.set(ctx, cacheKey, fromSource)
+		return fromSource, SourceLoader, nil
+	}
+
+	type result struct {
+		e   *entry[V]
+		src Source
+	}
+	// 同一进程内只有一个调用方等待锁
+	v, err, _ := c.group.Do(loaderLockPrefix+cacheKey, func() (interface{}, error) {
+		unlock, err := c.loaderLock.Acquire(ctx, loaderLockPrefix+cacheKey, c.loaderLockTTL)
+		switch {
+		case err == nil:
+			defer func() { _ = unlock(context.WithoutCancel(ctx)) }()
+			// 等待期间其他进程可能已经回源并写入缓存
+			fromCache, src, cacheErr := c.cache.GetWithSource(ctx, cacheKey)
+			if cacheErr == nil && fromCache != nil && !fromCache.IsExpired() {
+				return result{e: fromCache, src: src}, nil
+			}
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		default:
+			// 等待超时或锁不可用时直接回源，不影响可用性
+			fields := map[string]interface{}{"op": "loader_lock", "key": cacheKey, "error": err}
+			logWarn(ctx, c.logger, fields, "acquire loader lock fail, key:[%v], err:[%v]", cacheKey, err)
+		}
+		fromSource, err := c.load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		// 释放锁之前写入缓存，等待的进程获取锁后可以读到
+		_ = c.set(ctx, cacheKey, fromSource)
+		return result{e: fromSource, src: SourceLoader}, nil
+	})
+	if err != nil {
+		return nil, SourceMiss, err
+	}
+	r := v.(result)
+	return r.e, r.src, nil
+}
+
 func (c *cachex[K, V]) load(ctx context.Context, key K) (*entry[V], error) {
-	if c.loaderFn == nil && c.mLoaderFn == nil {
-		return nil, fmt.Errorf("loader not set")
+	if c.loaderFn == nil && !c.hasMultiLoader() {
+		return nil, ErrLoaderNotSet
+	}
+	// ctx已取消，不再回源
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	// 没有配置单个回源函数，从批量回源拿
-	if c.loaderFn == nil && c.mLoaderFn != nil {
+	if c.loaderFn == nil {
 		vals, err := c.mLoad(ctx, []K{key})
 		if err != nil {
 			return nil, err
@@ -144,11 +310,13 @@ func (c *cachex[K, V]) load(ctx context.Context, key K) (*entry[V], error) {
 	// 从单个回源拿
 	k := c.key(key)
 	v, err, _ := c.group.Do(k, func() (interface{}, error) {
-		val, err := c.loaderFn(ctx, key)
-		if err != nil {
-			return nil, fmt.Errorf("loader fn err: %w", err)
-		}
-		return newEntry(val, c.expireTTL), nil
+		// loader panic时恢复并作为错误返回，可使用过期数据兜底
+		var e *entry[V]
+		err := recoverFn(ctx, c.logger, func() (err error) {
+			e, err = c.loadOne(ctx, key)
+			return err
+		})()
+		return e, err
 	})
 	if err != nil {
 		return nil, err
@@ -156,12 +324,39 @@ func (c *cachex[K, V]) load(ctx context.Context, key K) (*entry[V], error) {
 	return v.(*entry[V]), nil
 }
 
+// loadOne 从单个回源函数加载，主回源未找到数据时使用备用回源
+func (c *cachex[K, V]) loadOne(ctx context.Context, key K) (*entry[V], error) {
+	val, err := c.loaderFn(ctx, key)
+	// 主回源未找到数据，使用备用回源
+	if c.fbLoaderFn != nil && (errors.Is(err, ErrNotFound) || (err == nil && val == nil)) {
+		val, err = c.fbLoaderFn(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("fallback loader fn err: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loader fn err: %w", err)
+	}
+	return c.cache.newEntry(val, c.expireTTL), nil
+}
+
 func (c *cachex[K, V]) MGet(ctx context.Context, keys []K) ([]*V, error) {
-	switch c.ss {
+	return c.mGet(ctx, keys, c.ss)
+}
+
+func (c *cachex[K, V]) MGetWithStrategy(ctx context.Context, keys []K, ss SourceStrategy) ([]*V, error) {
+	return c.mGet(ctx, keys, ss)
+}
+
+func (c *cachex[K, V]) mGet(ctx context.Context, keys []K, ss SourceStrategy) ([]*V, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch ss {
 	case SourceStrategyCacheFirst:
 		return c.ssCacheFirstMGet(ctx, keys)
 	case SourceStrategySourceFirst:
-		return c.ssSourceOnlyMGet(ctx, keys)
+		return c.ssSourceFirstMGet(ctx, keys)
 	case SourceStrategyCacheOnly:
 		return c.ssCacheOnlyMGet(ctx, keys)
 	case SourceStrategySourceOnly:
@@ -169,70 +364,167 @@ func (c *cachex[K, V]) MGet(ctx context.Context, keys []K) ([]*V, error) {
 	case SourceStrategyExpiredBackup:
 		return c.ssExpiredBackupMGet(ctx, keys)
 	default:
-		return nil, fmt.Errorf("invalid source strategy: %v", c.ss)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSourceStrategy, ss)
 	}
 }
 
 func (c *cachex[K, V]) ssCacheOnlyMGet(ctx context.Context, keys []K) ([]*V, error) {
-	cacheKeys := c.keys(keys)
-	fromCache := c.cache.MGet(ctx, cacheKeys)
+	fromCache, _, corrupt, err := c.cacheMGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
 	hit, _, _ := c.groupBatchRes(keys, fromCache)
-	return c.packBatchRes(keys, hit), nil
+	return c.packBatchRes(keys, hit), c.joinPartialErr(corrupt, nil)
 }
 
 func (c *cachex[K, V]) ssSourceOnlyMGet(ctx context.Context, keys []K) ([]*V, error) {
 	fromSource, err := c.mLoad(ctx, keys)
-	if err != nil {
+	if err != nil && !c.isPartialLoad(err) {
 		return nil, err
 	}
-	return c.packBatchRes(keys, fromSource), nil
+	return c.packBatchRes(keys, fromSource), err
 }
 
 func (c *cachex[K, V]) ssCacheFirstMGet(ctx context.Context, keys []K) ([]*V, error) {
 	// 读缓存
-	fromCache := c.cache.MGet(ctx, c.keys(keys))
-	hit, expire, miss := c.groupBatchRes(keys, fromCache)
+	fromCache, valid, corrupt, err := c.cacheMGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	hit, expire, miss := c.groupBatchRes(valid, fromCache)
 	if len(miss) == 0 && len(expire) == 0 {
 		// 全部命中，直接返回
-		return c.packBatchRes(keys, hit), nil
+		return c.packBatchRes(keys, hit), c.joinPartialErr(corrupt, nil)
 	}
-	// 回源
+	// 回源，部分失败时写入成功的key，失败的key返回nil
 	fromSource, err := c.mLoad(ctx, gslice.Merge(expire, miss))
-	if err != nil {
+	if err != nil && !c.isPartialLoad(err) {
 		return nil, err
 	}
 	_ = c.mSet(ctx, fromSource)
-	return c.packBatchRes(keys, gmap.Merge(hit, fromSource)), nil
+	return c.packBatchRes(keys, gmap.Merge(hit, fromSource)), c.joinPartialErr(corrupt, err)
 }
 
 func (c *cachex[K, V]) ssSourceFirstMGet(ctx context.Context, keys []K) ([]*V, error) {
 	// 回源
 	fromSource, err := c.mLoad(ctx, keys)
-	if err != nil {
-		// 回源失败，缓存兜底
-		fromCache := c.cache.MGet(ctx, c.keys(keys))
+	if err != nil && !c.isPartialLoad(err) {
+		// 回源失败，缓存兜底，读缓存失败视为无兜底，有key没有兜底时返回回源错误
+		fromCache, _, _, cacheErr := c.cacheMGet(ctx, keys)
+		if cacheErr != nil {
+			return nil, err
+		}
 		hit, _, _ := c.groupBatchRes(keys, fromCache)
+		if len(hit) < len(keys) {
+			return c.packBatchRes(keys, hit), err
+		}
 		return c.packBatchRes(keys, hit), nil
 	}
 	_ = c.mSet(ctx, fromSource)
-	return c.packBatchRes(keys, fromSource), nil
+	if err != nil {
+		// 部分失败，失败的key使用缓存兜底，并返回失败的key
+		fromCache, _, _, cacheErr := c.cacheMGet(ctx, keys)
+		if cacheErr == nil {
+			hit, _, _ := c.groupBatchRes(keys, fromCache)
+			fromSource = gmap.Merge(hit, fromSource)
+		}
+	}
+	return c.packBatchRes(keys, fromSource), err
 }
 
 func (c *cachex[K, V]) ssExpiredBackupMGet(ctx context.Context, keys []K) ([]*V, error) {
 	// 读缓存
-	fromCache := c.cache.MGet(ctx, c.keys(keys))
-	hit, expire, miss := c.groupBatchRes(keys, fromCache)
+	fromCache, valid, corrupt, err := c.cacheMGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	hit, expire, miss := c.groupBatchRes(valid, fromCache)
 	if len(miss) == 0 && len(expire) == 0 {
-		return c.packBatchRes(keys, hit), nil
+		return c.packBatchRes(keys, hit), c.joinPartialErr(corrupt, nil)
 	}
 	// 回源
 	fromSource, err := c.mLoad(ctx, gslice.Merge(expire, miss))
-	if err != nil {
-		// 回源失败，用缓存数据兜底
-		return c.packBatchRes(keys, fromCache), nil
+	backup, tooStale := c.backupBatchRes(fromCache)
+	if err != nil && !c.isPartialLoad(err) {
+		// 回源失败，用缓存数据兜底，有缓存过期太久不能兜底时返回回源错误
+		if tooStale {
+			return c.packBatchRes(keys, backup), err
+		}
+		return c.packBatchRes(keys, backup), c.joinPartialErr(corrupt, nil)
 	}
 	_ = c.mSet(ctx, fromSource)
-	return c.packBatchRes(keys, gmap.Merge(hit, fromSource)), nil
+	// 部分失败时，失败的key用缓存数据兜底，并返回失败的key
+	return c.packBatchRes(keys, gmap.Merge(backup, fromSource)), c.joinPartialErr(corrupt, err)
+}
+
+// cacheMGet 批量读缓存，返回需要继续处理的key
+// 关闭reloadOnCorrupt时无法解析的key不回源，从返回的key中去掉，汇总为*MultiLoadError，其余key不受影响
+func (c *cachex[K, V]) cacheMGet(ctx context.Context, keys []K) (map[string]*entry[V], []K, *MultiLoadError[K], error) {
+	fromCache, err := c.cache.MGet(ctx, c.keys(keys))
+	var corrupt corruptKeysError
+	if errors.As(err, &corrupt) {
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(corrupt) == 0 {
+		return fromCache, keys, nil, nil
+	}
+	valid := make([]K, 0, len(keys))
+	mErr := &MultiLoadError[K]{}
+	for _, key := range keys {
+		if keyErr, ok := corrupt[c.key(key)]; ok {
+			mErr.Keys = append(mErr.Keys, key)
+			mErr.Errs = append(mErr.Errs, keyErr)
+			continue
+		}
+		valid = append(valid, key)
+	}
+	return fromCache, valid, mErr, nil
+}
+
+// joinPartialErr 合并无法解析的key与回源部分失败的key，err只能为nil或*MultiLoadError
+func (c *cachex[K, V]) joinPartialErr(corrupt *MultiLoadError[K], err error) error {
+	if corrupt == nil {
+		return err
+	}
+	var mErr *MultiLoadError[K]
+	if !errors.As(err, &mErr) {
+		return corrupt
+	}
+	return &MultiLoadError[K]{
+		Keys: append(corrupt.Keys, mErr.Keys...),
+		Errs: append(corrupt.Errs, mErr.Errs...),
+	}
+}
+
+// usableBackup 缓存是否可以在回源失败时兜底，过期超过maxStaleness的缓存不可用
+func (c *cachex[K, V]) usableBackup(e *entry[V]) bool {
+	if e == nil {
+		return false
+	}
+	if c.maxStaleness <= 0 || !e.IsExpired() {
+		return true
+	}
+	return e.RemainingTTL() >= -c.maxStaleness
+}
+
+// backupBatchRes 过滤出可以兜底的缓存，返回是否有缓存因过期太久被过滤
+func (c *cachex[K, V]) backupBatchRes(vals map[string]*entry[V]) (map[string]*entry[V], bool) {
+	if c.maxStaleness <= 0 {
+		return vals, false
+	}
+	res := make(map[string]*entry[V], len(vals))
+	tooStale := false
+	for k, v := range vals {
+		if !c.usableBackup(v) {
+			tooStale = tooStale || v != nil
+			continue
+		}
+		res[k] = v
+	}
+	return res, tooStale
 }
 
 func (c *cachex[K, V]) groupBatchRes(keys []K, vals map[string]*entry[V]) (map[string]*entry[V], []K, []K) {
@@ -273,24 +565,30 @@ func (c *cachex[K, V]) packBatchRes(keys []K, kvs map[string]*entry[V]) []*V {
 	return res
 }
 
+// hasMultiLoader 是否配置了批量回源函数
+func (c *cachex[K, V]) hasMultiLoader() bool {
+	return c.mLoaderFn != nil || c.mLoaderFnE != nil
+}
+
+// mLoad 批量回源，使用MultiLoaderFnE部分key失败时，返回成功的结果和*MultiLoadError
 func (c *cachex[K, V]) mLoad(ctx context.Context, keys []K) (map[string]*entry[V], error) {
-	if c.loaderFn == nil && c.mLoaderFn == nil {
-		return nil, fmt.Errorf("loader not set")
+	if c.loaderFn == nil && !c.hasMultiLoader() {
+		return nil, ErrLoaderNotSet
+	}
+	// ctx已取消，不再回源
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	// 没有配置批量回源函数，并发从单个回源函数拿
-	if c.mLoaderFn == nil && c.loaderFn != nil {
+	if !c.hasMultiLoader() {
 		res := make(map[string]*entry[V])
 		mu := sync.Mutex{}
 		eg := errgroup.Group{}
 		eg.SetLimit(50)
 		for _, key := range keys {
 			k := key
-			eg.Go(func() (err error) {
-				defer func() {
-					if r := recover(); r != nil {
-						err = fmt.Errorf("loader fn err: panic:%v", r)
-					}
-				}()
+			// loader panic时恢复并作为错误返回，避免进程崩溃
+			eg.Go(recoverFn(ctx, c.logger, func() error {
 				v, err := c.load(ctx, k)
 				if err != nil {
 					return err
@@ -299,7 +597,7 @@ func (c *cachex[K, V]) mLoad(ctx context.Context, keys []K) (map[string]*entry[V
 				defer mu.Unlock()
 				res[c.key(k)] = v
 				return nil
-			})
+			}))
 		}
 		err := eg.Wait()
 		if err != nil {
@@ -308,29 +606,173 @@ func (c *cachex[K, V]) mLoad(ctx context.Context, keys []K) (map[string]*entry[V
 		return res, nil
 	}
 	// 从批量回源函数拿
-	groupKey := "m" + strings.Join(c.keys(keys), ",")
-	got, err, _ := c.mGroup.Do(groupKey, func() (interface{}, error) {
-		res := make(map[string]*entry[V], len(keys))
-		values, err := c.mLoaderFn(ctx, keys)
-		if err != nil {
-			return nil, fmt.Errorf("mloader fn err: %w", err)
+	got, err, shared := c.mGroup.Do(c.batchKey(keys), func() (interface{}, error) {
+		return c.mLoadBatch(ctx, keys)
+	})
+	if err != nil && !c.isPartialLoad(err) {
+		return nil, err
+	}
+	res := got.(map[string]*entry[V])
+	// 自定义的batchKeyFn可能对不同的批次生成相同的key，共享的结果不包含本次的key时直接回源
+	if shared && c.batchKeyFn != nil && !c.coversKeys(res, err, keys) {
+		var mErr error
+		res, mErr = c.mLoadBatch(ctx, keys)
+		if mErr != nil && !c.isPartialLoad(mErr) {
+			return nil, mErr
 		}
-		if len(keys) != len(values) {
-			return nil, fmt.Errorf("mloader fn err: len(keys) != len(values)")
+		return res, mErr
+	}
+	return res, err
+}
+
+// mLoadBatch 调用批量回源函数
+func (c *cachex[K, V]) mLoadBatch(ctx context.Context, keys []K) (map[string]*entry[V], error) {
+	if c.mLoaderFnE != nil {
+		return c.mLoadE(ctx, keys)
+	}
+	res := make(map[string]*entry[V], len(keys))
+	values, err := c.mLoaderFn(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("mloader fn err: %w", err)
+	}
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("mloader fn err: %w", ErrLoaderResultMismatch)
+	}
+	for i, key := range keys {
+		val := values[i]
+		// 批量回源未找到数据，使用备用回源
+		if val == nil && c.fbLoaderFn != nil {
+			if val, err = c.fbLoaderFn(ctx, key); err != nil {
+				return nil, fmt.Errorf("fallback loader fn err: %w", err)
+			}
 		}
-		for i, key := range keys {
-			res[c.key(key)] = newEntry(values[i], c.expireTTL)
+		res[c.key(key)] = c.cache.newEntry(val, c.expireTTL)
+	}
+	return res, nil
+}
+
+// coversKeys 批量回源的结果是否包含所有key，部分失败的key包含在*MultiLoadError中
+func (c *cachex[K, V]) coversKeys(res map[string]*entry[V], err error, keys []K) bool {
+	var failed map[string]struct{}
+	var mErr *MultiLoadError[K]
+	if errors.As(err, &mErr) {
+		failed = make(map[string]struct{}, len(mErr.Keys))
+		for _, key := range mErr.Keys {
+			failed[c.key(key)] = struct{}{}
 		}
-		return res, nil
-	})
+	}
+	for _, key := range keys {
+		k := c.key(key)
+		if _, ok := res[k]; ok {
+			continue
+		}
+		if _, ok := failed[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// batchKey 批量回源的singleflight key
+func (c *cachex[K, V]) batchKey(keys []K) string {
+	if c.batchKeyFn != nil {
+		return "m" + c.batchKeyFn(keys)
+	}
+	return "m" + strings.Join(c.keys(keys), ",")
+}
+
+// mLoadE 使用MultiLoaderFnE回源，只返回成功的key，失败的key汇总为*MultiLoadError
+func (c *cachex[K, V]) mLoadE(ctx context.Context, keys []K) (map[string]*entry[V], error) {
+	values, errs := c.mLoaderFnE(ctx, keys)
+	if len(keys) != len(values) || len(keys) != len(errs) {
+		return nil, fmt.Errorf("mloader fn err: %w", ErrLoaderResultMismatch)
+	}
+	res := make(map[string]*entry[V], len(keys))
+	var mErr *MultiLoadError[K]
+	for i, key := range keys {
+		val, err := values[i], errs[i]
+		// 批量回源未找到数据，使用备用回源
+		if c.fbLoaderFn != nil && (errors.Is(err, ErrNotFound) || (err == nil && val == nil)) {
+			if val, err = c.fbLoaderFn(ctx, key); err != nil {
+				err = fmt.Errorf("fallback loader fn err: %w", err)
+			}
+		}
+		if err != nil {
+			if mErr == nil {
+				mErr = &MultiLoadError[K]{}
+			}
+			mErr.Keys = append(mErr.Keys, key)
+			mErr.Errs = append(mErr.Errs, err)
+			continue
+		}
+		res[c.key(key)] = c.cache.newEntry(val, c.expireTTL)
+	}
+	if mErr != nil {
+		return res, mErr
+	}
+	return res, nil
+}
+
+// isPartialLoad 是否为批量回源部分失败，此时成功的结果仍然可用
+func (c *cachex[K, V]) isPartialLoad(err error) bool {
+	var mErr *MultiLoadError[K]
+	return errors.As(err, &mErr)
+}
+
+func (c *cachex[K, V]) TTL(ctx context.Context, key K) (time.Duration, error) {
+	val, err := c.cache.Get(ctx, c.key(key))
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	return got.(map[string]*entry[V]), nil
+	if val == nil {
+		return 0, ErrCacheMiss
+	}
+	return val.RemainingTTL(), nil
+}
+
+// Warm 预热，按批回源keys并写入所有级别缓存，批次间并发数由WithWarmConcurrency控制
+// 部分key回源失败不影响其他批次，失败的key合并后在全部批次完成后返回
+func (c *cachex[K, V]) Warm(ctx context.Context, keys []K) error {
+	// 不使用errgroup.WithContext，避免一个批次失败时取消其他批次的回源和写入
+	var eg errgroup.Group
+	eg.SetLimit(max(c.warmConcurrency, 1))
+	var mu sync.Mutex
+	partial := &MultiLoadError[K]{}
+	for _, batch := range gslice.Chunk(keys, warmBatchSize) {
+		eg.Go(recoverFn(ctx, c.logger, func() error {
+			vals, err := c.mLoad(ctx, batch)
+			var mErr *MultiLoadError[K]
+			if err != nil && !errors.As(err, &mErr) {
+				return err
+			}
+			// 部分失败时仍写入成功的key
+			if setErr := c.mSet(ctx, vals); setErr != nil {
+				return setErr
+			}
+			if mErr != nil {
+				mu.Lock()
+				partial.Keys = append(partial.Keys, mErr.Keys...)
+				partial.Errs = append(partial.Errs, mErr.Errs...)
+				mu.Unlock()
+			}
+			return nil
+		}))
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	if len(partial.Keys) > 0 {
+		return partial
+	}
+	return nil
+}
+
+func (c *cachex[K, V]) HealthCheck(ctx context.Context) error {
+	return c.cache.Ping(ctx)
 }
 
 func (c *cachex[K, V]) Set(ctx context.Context, key K, value *V) error {
-	return c.set(ctx, c.key(key), newEntry(value, c.expireTTL))
+	return c.set(ctx, c.key(key), c.cache.newEntry(value, c.expireTTL))
 }
 
 func (c *cachex[K, V]) set(ctx context.Context, key string, val *entry[V]) error {
@@ -343,13 +785,52 @@ func (c *cachex[K, V]) set(ctx context.Context, key string, val *entry[V]) error
 	return c.cache.Set(ctx, key, val)
 }
 
+func (c *cachex[K, V]) CompareAndSet(ctx context.Context, key K, old, new *V) (bool, error) {
+	if new == nil && !c.cacheNil {
+		return false, fmt.Errorf("cachex: compare and set nil value without cache nil")
+	}
+	var oldBytes []byte
+	if old != nil {
+		b, err := c.codec.Marshal(old)
+		if err != nil {
+			return false, fmt.Errorf("cachex: failed to marshal value: %w", err)
+		}
+		oldBytes = b
+	}
+	match := func(cur *entry[V]) bool {
+		if cur.IsExpired() {
+			return false
+		}
+		if old == nil || cur.IsNil() {
+			return old == nil && cur.IsNil()
+		}
+		return bytes.Equal(cur.valBytes, oldBytes)
+	}
+	return c.cache.CompareAndSwap(ctx, c.key(key), match, c.cache.newEntry(new, c.expireTTL))
+}
+
 func (c *cachex[K, V]) MSet(ctx context.Context, keys []K, values []*V) error {
 	if len(keys) != len(values) {
-		return fmt.Errorf("keys values length not equal")
+		return ErrKeyValueLengthMismatch
+	}
+	kvs := make(map[string]*entry[V])
+	for i := 0; i < len(keys); i++ {
+		kvs[c.key(keys[i])] = c.cache.newEntry(values[i], c.expireTTL)
+	}
+	return c.mSet(ctx, kvs)
+}
+
+func (c *cachex[K, V]) MSetWithTTL(ctx context.Context, keys []K, values []*V, ttls []time.Duration) error {
+	if len(keys) != len(values) || len(keys) != len(ttls) {
+		return ErrKeyValueLengthMismatch
 	}
+	// 失效时间记录在entry中，可以一次批量写入；缓存按delTTL删除，更长的失效时间不会生效
 	kvs := make(map[string]*entry[V])
 	for i := 0; i < len(keys); i++ {
-		kvs[c.key(keys[i])] = newEntry(values[i], c.expireTTL)
+		if c.cache.delTTL > 0 && ttls[i] > c.cache.delTTL {
+			return fmt.Errorf("%w: key %v ttl %v > del ttl %v", ErrTTLExceedsDelTTL, keys[i], ttls[i], c.cache.delTTL)
+		}
+		kvs[c.key(keys[i])] = c.cache.newEntry(values[i], ttls[i])
 	}
 	return c.mSet(ctx, kvs)
 }
@@ -371,12 +852,20 @@ func (c *cachex[K, V]) Del(ctx context.Context, key K) error {
 	return c.cache.Delete(ctx, c.key(key))
 }
 
+// MDel 按delBatchSize分批删除，避免一次删除大量key阻塞缓存后端
+// 某一批失败时继续删除其余批次，返回合并后的错误
 func (c *cachex[K, V]) MDel(ctx context.Context, keys []K) error {
-	return c.cache.MDelete(ctx, c.keys(keys))
+	var errs []error
+	for _, chunk := range gslice.Chunk(c.keys(keys), max(c.delBatchSize, 1)) {
+		if err := c.cache.MDelete(ctx, chunk); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (c *cachex[K, V]) key(key K) string {
-	return c.namespace + ":" + c.genKeyFn(key)
+	return c.namespace + keySeparator + c.genKeyFn(key)
 }
 
 func (c *cachex[K, V]) keys(keys []K) []string {
@@ -389,13 +878,24 @@ func (c *cachex[K, V]) keys(keys []K) []string {
 
 func (c *cachex[K, V]) clone() *cachex[K, V] {
 	return &cachex[K, V]{
-		namespace: c.namespace,
-		codec:     c.codec,
-		expireTTL: c.expireTTL,
-		genKeyFn:  c.genKeyFn,
-		loaderFn:  c.loaderFn,
-		mLoaderFn: c.mLoaderFn,
-		cacheNil:  c.cacheNil,
-		ss:        c.ss,
+		namespace:  c.namespace,
+		codec:      c.codec,
+		expireTTL:  c.expireTTL,
+		logger:     c.logger,
+		cache:      c.cache,
+		genKeyFn:   c.genKeyFn,
+		batchKeyFn: c.batchKeyFn,
+		loaderFn:   c.loaderFn,
+		mLoaderFn:  c.mLoaderFn,
+		mLoaderFnE: c.mLoaderFnE,
+		fbLoaderFn: c.fbLoaderFn,
+		cacheNil:   c.cacheNil,
+		ss:         c.ss,
+
+		warmConcurrency: c.warmConcurrency,
+		delBatchSize:    c.delBatchSize,
+		maxStaleness:    c.maxStaleness,
+		loaderLock:      c.loaderLock,
+		loaderLockTTL:   c.loaderLockTTL,
 	}
 }
@@ -0,0 +1,50 @@
+package cachex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keySeparator 命名空间与业务key之间的分隔符
+const keySeparator = ":"
+
+var namespaceEscaper = strings.NewReplacer(`\`, `\\`, keySeparator, `\`+keySeparator)
+
+// escapeNamespace 转义命名空间中的分隔符，保证第一个未转义的分隔符之前一定是命名空间，WithEscapeNamespace(true)时使用
+// 否则命名空间"a"+key"b:c"与命名空间"a:b"+key"c"会生成相同的缓存key
+func escapeNamespace(namespace string) string {
+	if !strings.ContainsAny(namespace, `\`+keySeparator) {
+		return namespace
+	}
+	return namespaceEscaper.Replace(namespace)
+}
+
+// MustUniqueKeys 检查genKeyFn对给定的不同key是否生成了相同的缓存key，存在冲突时panic
+// 适合在初始化或测试中使用样例key校验genKeyFn，如 MustUniqueKeys(genKeyFn, sampleKeys...)
+func MustUniqueKeys[K any](fn GenKeyFn[K], keys ...K) {
+	seen := make(map[string]int, len(keys))
+	for i, key := range keys {
+		k := fn(key)
+		if j, ok := seen[k]; ok {
+			panic(fmt.Errorf("cachex: gen key collision, keys[%d]=%v and keys[%d]=%v both generate %q", j, keys[j], i, key, k))
+		}
+		seen[k] = i
+	}
+}
+
+// CompositeKey 将多个字段拼接为一个无歧义的key，可在GenKeyFn中使用
+// 每个字段以"长度:内容"的形式拼接，字段中包含任何字符都不会与其他组合冲突
+// 如 CompositeKey("a", "bc") = "1:a|2:bc"，CompositeKey("ab", "c") = "2:ab|1:c"
+func CompositeKey(parts ...string) string {
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(strconv.Itoa(len(part)))
+		sb.WriteByte(':')
+		sb.WriteString(part)
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package cachex
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/bytedance/gg/gptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeNamespace(t *testing.T) {
+	assert.Equal(t, "user", escapeNamespace("user"))
+	assert.Equal(t, `a\:b`, escapeNamespace("a:b"))
+	assert.Equal(t, `a\\\:b`, escapeNamespace(`a\:b`))
+}
+
+func TestCachex_NamespaceIsolation(t *testing.T) {
+	ctx := context.Background()
+	l1 := NewLocalCacher(1)
+	genKeyFn := func(key string) string { return key }
+
+	// 未转义时 "a"+"b:c" 与 "a:b"+"c" 都会生成 "a:b:c"
+	cx1, err := New[string, string]().WithNamespace("a").WithEscapeNamespace(true).WithL1(l1).WithGenKeyFn(genKeyFn).
+		WithSourceStrategy(SourceStrategyCacheOnly).Build()
+	assert.NoError(t, err)
+	cx2, err := New[string, string]().WithNamespace("a:b").WithEscapeNamespace(true).WithL1(l1).WithGenKeyFn(genKeyFn).
+		WithSourceStrategy(SourceStrategyCacheOnly).Build()
+	assert.NoError(t, err)
+
+	assert.NoError(t, cx1.Set(ctx, "b:c", gptr.Of("from_cx1")))
+	assert.NoError(t, cx2.Set(ctx, "c", gptr.Of("from_cx2")))
+
+	got, err := cx1.Get(ctx, "b:c")
+	assert.NoError(t, err)
+	assert.Equal(t, gptr.Of("from_cx1"), got)
+	got, err = cx2.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, gptr.Of("from_cx2"), got)
+
+	// 默认不转义，key格式不变
+	syncMap := NewSyncMapCacher()
+	cx3, err := New[string, string]().WithNamespace("a:b").WithL1(syncMap).WithGenKeyFn(genKeyFn).
+		WithSourceStrategy(SourceStrategyCacheOnly).Build()
+	assert.NoError(t, err)
+	assert.NoError(t, cx3.Set(ctx, "c", gptr.Of("v")))
+	raw, err := syncMap.Get(ctx, "a:b:c")
+	assert.NoError(t, err)
+	assert.NotNil(t, raw)
+}
+
+func TestMustUniqueKeys(t *testing.T) {
+	assert.NotPanics(t, func() {
+		MustUniqueKeys(strconv.Itoa, 1, 2, 3)
+	})
+	assert.PanicsWithError(t, `cachex: gen key collision, keys[0]=11 and keys[1]=21 both generate "1"`, func() {
+		MustUniqueKeys(func(key int) string { return strconv.Itoa(key % 10) }, 11, 21)
+	})
+}
+
+func TestCompositeKey(t *testing.T) {
+	assert.Equal(t, "1:a|2:bc", CompositeKey("a", "bc"))
+	assert.Equal(t, "2:ab|1:c", CompositeKey("ab", "c"))
+	assert.Equal(t, "", CompositeKey())
+	assert.Equal(t, "0:", CompositeKey(""))
+
+	// 简单拼接会冲突的组合
+	pairs := [][2][]string{
+		{{"a", "bc"}, {"ab", "c"}},
+		{{"a:b", "c"}, {"a", "b:c"}},
+		{{"a|1:b"}, {"a", "b"}},
+		{{"", "a"}, {"a", ""}},
+		{{"", ""}, {""}},
+		{{"1:a"}, {"a"}},
+	}
+	for _, p := range pairs {
+		assert.NotEqual(t, CompositeKey(p[0]...), CompositeKey(p[1]...), "%q vs %q", p[0], p[1])
+	}
+
+	// 由少量字符组成的所有组合都不冲突
+	alphabet := []string{"", "a", ":", "|", "1", "a:", "1:", "|1:a"}
+	seen := make(map[string][]string)
+	for _, x := range alphabet {
+		for _, y := range alphabet {
+			for _, parts := range [][]string{{x}, {x, y}, {x, y, x}} {
+				key := CompositeKey(parts...)
+				if prev, ok := seen[key]; ok {
+					assert.Equal(t, prev, parts, "collision on %q", key)
+				}
+				seen[key] = parts
+			}
+		}
+	}
+}
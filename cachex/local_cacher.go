@@ -1,9 +1,11 @@
 package cachex
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/coocood/freecache"
@@ -12,6 +14,7 @@ import (
 // localCache 本地缓存实现
 type localCache struct {
 	fc *freecache.Cache
+	mu sync.Mutex // 保证CompareAndSwap之间互斥
 }
 
 func NewLocalCacher(sizeMB int) Cacher {
@@ -20,7 +23,10 @@ func NewLocalCacher(sizeMB int) Cacher {
 	}
 }
 
-func (l *localCache) Get(_ context.Context, key string) ([]byte, error) {
+func (l *localCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	val, err := l.fc.Get(stringToBytes(key))
 	if err != nil {
 		if errors.Is(err, freecache.ErrNotFound) {
@@ -34,16 +40,20 @@ func (l *localCache) Get(_ context.Context, key string) ([]byte, error) {
 func (l *localCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
 	result := make(map[string][]byte)
 	for _, key := range keys {
+		// Get已检查ctx，取消后提前退出
 		val, err := l.Get(ctx, key)
 		if err != nil {
-			return nil, fmt.Errorf("freecache error: %w", err)
+			return nil, err
 		}
 		result[key] = val
 	}
 	return result, nil
 }
 
-func (l *localCache) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+func (l *localCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	err := l.fc.Set(stringToBytes(key), val, int(ttl.Seconds()))
 	if err != nil {
 		return fmt.Errorf("freecache error: %w", err)
@@ -56,7 +66,8 @@ func (l *localCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.D
 	for k, v := range kvs {
 		err := l.Set(ctx, k, v, ttl)
 		if err != nil {
-			_ = l.MDelete(ctx, success)
+			// 回滚已写入的key，ctx可能已取消，不能再用它
+			_ = l.MDelete(context.WithoutCancel(ctx), success)
 			return err
 		}
 		success = append(success, k)
@@ -64,13 +75,42 @@ func (l *localCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.D
 	return nil
 }
 
-func (l *localCache) Delete(_ context.Context, key string) error {
+// CompareAndSwap 加锁后比较并写入，只与其他CompareAndSwap互斥，并发的Set仍可能被覆盖
+func (l *localCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, err := l.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if cur == nil || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+	if err = l.Set(ctx, key, new, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Ping 本地缓存始终可用
+func (l *localCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (l *localCache) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	l.fc.Del([]byte(key))
 	return nil
 }
 
-func (l *localCache) MDelete(_ context.Context, keys []string) error {
+func (l *localCache) MDelete(ctx context.Context, keys []string) error {
 	for _, key := range keys {
+		// ctx取消后提前退出
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		l.fc.Del([]byte(key))
 	}
 	return nil
@@ -97,3 +97,34 @@ func TestLocalCacher_NewLocalCacher(t *testing.T) {
 	assert.NotNil(t, cacher)
 	// You can add more specific tests for capacity if freecache exposes it
 }
+
+func TestLocalCacher_ContextCanceled(t *testing.T) {
+	cacher := NewLocalCacher(1) // 1MB cache
+	ttl := time.Second * 5
+	_ = cacher.Set(context.Background(), "ctxKey", []byte("ctxValue"), ttl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := cacher.Get(ctx, "ctxKey")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, got)
+
+	results, err := cacher.MGet(ctx, []string{"ctxKey", "otherKey"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, results)
+
+	err = cacher.Set(ctx, "newKey", []byte("newValue"), ttl)
+	assert.ErrorIs(t, err, context.Canceled)
+	err = cacher.MSet(ctx, map[string][]byte{"newKey": []byte("newValue")}, ttl)
+	assert.ErrorIs(t, err, context.Canceled)
+	got, _ = cacher.Get(context.Background(), "newKey")
+	assert.Nil(t, got) // 未写入
+
+	err = cacher.Delete(ctx, "ctxKey")
+	assert.ErrorIs(t, err, context.Canceled)
+	err = cacher.MDelete(ctx, []string{"ctxKey"})
+	assert.ErrorIs(t, err, context.Canceled)
+	got, _ = cacher.Get(context.Background(), "ctxKey")
+	assert.Equal(t, []byte("ctxValue"), got) // 未删除
+}
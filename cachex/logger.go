@@ -4,23 +4,86 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 )
 
+// defaultLogInterval 默认logger相同模板的日志最小输出间隔
+const defaultLogInterval = time.Second
+
 // defaultLogger 默认logger，使用标准库 log/slog
-type defaultLogger struct{}
+// 按级别和format限流，同一模板每个interval最多输出一条，被丢弃的条数在下一条日志中输出，避免redis故障时大量错误日志拖慢请求
+type defaultLogger struct {
+	interval time.Duration
+	now      func() time.Time
+	limits   sync.Map // key: logLimitKey, value: *logLimit
+}
+
+type logLimitKey struct {
+	level  slog.Level
+	format string
+}
+
+type logLimit struct {
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int64
+}
 
 func newDefaultLogger() Logger {
-	return defaultLogger{}
+	return &defaultLogger{
+		interval: defaultLogInterval,
+		now:      time.Now,
+	}
+}
+
+func (d *defaultLogger) Infof(ctx context.Context, format string, a ...any) {
+	d.log(ctx, slog.LevelInfo, format, a...)
+}
+
+func (d *defaultLogger) Warnf(ctx context.Context, format string, a ...any) {
+	d.log(ctx, slog.LevelWarn, format, a...)
+}
+
+func (d *defaultLogger) Errorf(ctx context.Context, format string, a ...any) {
+	d.log(ctx, slog.LevelError, format, a...)
 }
 
-func (d defaultLogger) Infof(ctx context.Context, format string, a ...any) {
-	slog.Info(fmt.Sprintf(format, a...))
+func (d *defaultLogger) log(ctx context.Context, level slog.Level, format string, a ...any) {
+	suppressed, ok := d.allow(level, format)
+	if !ok {
+		return
+	}
+	msg := fmt.Sprintf(format, a...)
+	if suppressed > 0 {
+		slog.Log(ctx, level, msg, slog.Int64("suppressed", suppressed))
+		return
+	}
+	slog.Log(ctx, level, msg)
 }
 
-func (d defaultLogger) Warnf(ctx context.Context, format string, a ...any) {
-	slog.Warn(fmt.Sprintf(format, a...))
+// allow 返回是否输出本条日志，以及上次输出后被丢弃的条数
+func (d *defaultLogger) allow(level slog.Level, format string) (int64, bool) {
+	v, _ := d.limits.LoadOrStore(logLimitKey{level: level, format: format}, &logLimit{})
+	l := v.(*logLimit)
+	now := d.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() && now.Sub(l.last) < d.interval {
+		l.suppressed++
+		return 0, false
+	}
+	suppressed := l.suppressed
+	l.last = now
+	l.suppressed = 0
+	return suppressed, true
 }
 
-func (d defaultLogger) Errorf(ctx context.Context, format string, a ...any) {
-	slog.Error(fmt.Sprintf(format, a...))
+// logWarn 输出warn日志，logger实现了FieldLogger时输出结构化字段，否则使用format输出
+func logWarn(ctx context.Context, logger Logger, fields map[string]interface{}, format string, a ...any) {
+	if fl, ok := logger.(FieldLogger); ok {
+		fl.WarnWithFields(ctx, fmt.Sprintf(format, a...), fields)
+		return
+	}
+	logger.Warnf(ctx, format, a...)
 }
@@ -0,0 +1,126 @@
+package cachex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytedance/gg/gptr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func captureSlog(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return buf
+}
+
+func TestDefaultLogger_RateLimit(t *testing.T) {
+	buf := captureSlog(t)
+	now := time.Unix(1700000000, 0)
+	logger := newDefaultLogger().(*defaultLogger)
+	logger.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Warnf(ctx, "get cache fail, key:[%v], err:[%v]", i, "timeout")
+			}
+		}(i)
+	}
+	wg.Wait()
+	// 不同模板、不同级别互不影响
+	logger.Warnf(ctx, "mget cache fail, err:[%v]", "timeout")
+	logger.Errorf(ctx, "get cache fail, key:[%v], err:[%v]", 0, "timeout")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+
+	// 超过间隔后输出，并携带被丢弃的条数
+	buf.Reset()
+	now = now.Add(time.Second)
+	logger.Warnf(ctx, "get cache fail, key:[%v], err:[%v]", 1, "timeout")
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "get cache fail, key:[1], err:[timeout]", record["msg"])
+	assert.EqualValues(t, 999, record["suppressed"])
+
+	// 丢弃计数已清零
+	buf.Reset()
+	now = now.Add(time.Second)
+	logger.Warnf(ctx, "get cache fail, key:[%v], err:[%v]", 2, "timeout")
+	record = nil
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.NotContains(t, record, "suppressed")
+}
+
+type fieldLogEntry struct {
+	msg    string
+	fields map[string]interface{}
+}
+
+// fieldLogger 实现FieldLogger的logger
+type fieldLogger struct {
+	recordLogger
+	mu      sync.Mutex
+	entries []fieldLogEntry
+}
+
+func (f *fieldLogger) WarnWithFields(ctx context.Context, msg string, fields map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, fieldLogEntry{msg: msg, fields: fields})
+}
+
+func TestFieldLogger(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	l1 := NewMockCacher(ctrl)
+	l2 := NewMockCacher(ctrl)
+	cacheErr := errors.New("connection refused")
+	l1.EXPECT().Get(gomock.Any(), "ns:k").Return(nil, nil)
+	l2.EXPECT().Get(gomock.Any(), "ns:k").Return(nil, cacheErr)
+	l1.EXPECT().Set(gomock.Any(), "ns:k", gomock.Any(), gomock.Any()).Return(nil)
+	l2.EXPECT().Set(gomock.Any(), "ns:k", gomock.Any(), gomock.Any()).Return(nil)
+
+	logger := &fieldLogger{}
+	cx, err := New[string, string]().
+		WithNamespace("ns").
+		WithL1(l1).
+		WithL2(l2).
+		WithLogger(logger).
+		WithGenKeyFn(func(key string) string { return key }).
+		WithLoader(func(ctx context.Context, key string) (*string, error) { return gptr.Of("v"), nil }).
+		Build()
+	assert.NoError(t, err)
+
+	val, err := cx.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", *val)
+
+	// 实现FieldLogger时不再调用Warnf
+	assert.Empty(t, logger.Warns())
+	assert.Len(t, logger.entries, 1)
+	entry := logger.entries[0]
+	assert.Equal(t, "cachex: cacher get error: connection refused", entry.msg)
+	assert.Equal(t, map[string]interface{}{
+		"op":    "get",
+		"layer": "l2",
+		"key":   "ns:k",
+		"error": cacheErr,
+	}, entry.fields)
+}
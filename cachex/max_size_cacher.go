@@ -0,0 +1,90 @@
+package cachex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxSizeCache 限制写入value大小的Cacher装饰器
+type maxSizeCache struct {
+	inner    Cacher
+	maxBytes int
+}
+
+// NewMaxSizeCacher 包装一个Cacher，Set、MSet的value超过maxBytes时不写入并返回ErrValueTooLarge，读操作直接透传
+// 拒绝写入时删除key已有的值，避免继续读到旧数据；maxBytes小于等于0时不限制
+func NewMaxSizeCacher(inner Cacher, maxBytes int) Cacher {
+	return &maxSizeCache{
+		inner:    inner,
+		maxBytes: maxBytes,
+	}
+}
+
+func (m *maxSizeCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return m.inner.Get(ctx, key)
+}
+
+func (m *maxSizeCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return m.inner.MGet(ctx, keys)
+}
+
+func (m *maxSizeCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if m.tooLarge(val) {
+		return errors.Join(m.rejectErr([]string{key}), m.inner.Delete(ctx, key))
+	}
+	return m.inner.Set(ctx, key, val, ttl)
+}
+
+// MSet 只写入未超过限制的value，超过限制的key返回在错误中
+func (m *maxSizeCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	var rejected []string
+	for k, v := range kvs {
+		if m.tooLarge(v) {
+			rejected = append(rejected, k)
+		}
+	}
+	if len(rejected) == 0 {
+		return m.inner.MSet(ctx, kvs, ttl)
+	}
+
+	allowed := make(map[string][]byte, len(kvs)-len(rejected))
+	for k, v := range kvs {
+		if !m.tooLarge(v) {
+			allowed[k] = v
+		}
+	}
+	var setErr error
+	if len(allowed) > 0 {
+		setErr = m.inner.MSet(ctx, allowed, ttl)
+	}
+	return errors.Join(m.rejectErr(rejected), m.inner.MDelete(ctx, rejected), setErr)
+}
+
+func (m *maxSizeCache) Delete(ctx context.Context, key string) error {
+	return m.inner.Delete(ctx, key)
+}
+
+func (m *maxSizeCache) MDelete(ctx context.Context, keys []string) error {
+	return m.inner.MDelete(ctx, keys)
+}
+
+func (m *maxSizeCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	if m.tooLarge(new) {
+		return false, m.rejectErr([]string{key})
+	}
+	return casCacher(ctx, m.inner, key, old, new, ttl)
+}
+
+func (m *maxSizeCache) Ping(ctx context.Context) error {
+	return pingCacher(ctx, m.inner)
+}
+
+func (m *maxSizeCache) tooLarge(val []byte) bool {
+	return m.maxBytes > 0 && len(val) > m.maxBytes
+}
+
+func (m *maxSizeCache) rejectErr(keys []string) error {
+	return fmt.Errorf("%w: max %d bytes, keys: %v", ErrValueTooLarge, m.maxBytes, keys)
+}
@@ -0,0 +1,82 @@
+package cachex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMaxSizeCacher(t *testing.T) {
+	ctx := context.Background()
+	small := []byte("12345")
+	large := []byte("123456")
+
+	t.Run("set", func(t *testing.T) {
+		cacher := NewMaxSizeCacher(NewSyncMapCacher(), 5)
+		assert.NoError(t, cacher.Set(ctx, "small", small, time.Minute))
+		got, err := cacher.Get(ctx, "small")
+		assert.NoError(t, err)
+		assert.Equal(t, small, got)
+
+		err = cacher.Set(ctx, "large", large, time.Minute)
+		assert.ErrorIs(t, err, ErrValueTooLarge)
+		got, err = cacher.Get(ctx, "large")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("rejected set deletes old value", func(t *testing.T) {
+		cacher := NewMaxSizeCacher(NewSyncMapCacher(), 5)
+		assert.NoError(t, cacher.Set(ctx, "key", small, time.Minute))
+		assert.ErrorIs(t, cacher.Set(ctx, "key", large, time.Minute), ErrValueTooLarge)
+		got, err := cacher.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("mset writes under-limit values", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().MSet(gomock.Any(), map[string][]byte{"k1": small}, time.Minute).Return(nil).Times(1)
+		inner.EXPECT().MDelete(gomock.Any(), []string{"k2"}).Return(nil).Times(1)
+
+		cacher := NewMaxSizeCacher(inner, 5)
+		err := cacher.MSet(ctx, map[string][]byte{"k1": small, "k2": large}, time.Minute)
+		assert.ErrorIs(t, err, ErrValueTooLarge)
+		assert.Contains(t, err.Error(), "k2")
+	})
+
+	t.Run("reads pass through", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().Get(gomock.Any(), "key").Return(large, nil).Times(1)
+		inner.EXPECT().MGet(gomock.Any(), []string{"key"}).Return(map[string][]byte{"key": large}, nil).Times(1)
+
+		cacher := NewMaxSizeCacher(inner, 5)
+		got, err := cacher.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, large, got)
+		vals, err := cacher.MGet(ctx, []string{"key"})
+		assert.NoError(t, err)
+		assert.Equal(t, large, vals["key"])
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		cacher := NewMaxSizeCacher(NewSyncMapCacher(), 5).(CompareAndSwapper)
+		assert.NoError(t, cacher.(Cacher).Set(ctx, "key", []byte("a"), time.Minute))
+		swapped, err := cacher.CompareAndSwap(ctx, "key", []byte("a"), large, time.Minute)
+		assert.ErrorIs(t, err, ErrValueTooLarge)
+		assert.False(t, swapped)
+		swapped, err = cacher.CompareAndSwap(ctx, "key", []byte("a"), small, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		cacher := NewMaxSizeCacher(NewSyncMapCacher(), 0)
+		assert.NoError(t, cacher.Set(ctx, "key", make([]byte, 1<<20), time.Minute))
+	})
+}
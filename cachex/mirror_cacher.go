@@ -0,0 +1,99 @@
+package cachex
+
+import (
+	"context"
+	"time"
+)
+
+// mirrorCache 双写的Cacher装饰器，用于缓存迁移
+type mirrorCache struct {
+	primary   Cacher
+	secondary Cacher
+	logger    Logger
+}
+
+// MirrorCacherOption mirror cacher 配置选项
+type MirrorCacherOption func(*mirrorCache)
+
+// WithMirrorLogger 设置secondary失败时打印Warn日志的logger，默认使用标准库log/slog
+func WithMirrorLogger(logger Logger) MirrorCacherOption {
+	return func(m *mirrorCache) {
+		if logger != nil {
+			m.logger = logger
+		}
+	}
+}
+
+// NewMirrorCacher 读操作只访问primary，写操作同时写入primary和secondary
+// primary写入失败时不写入secondary，避免secondary中出现primary没有的值；删除总是同时删除两者
+// secondary失败只打印Warn日志，不影响返回结果，用于迁移期间的双写
+func NewMirrorCacher(primary, secondary Cacher, opts ...MirrorCacherOption) Cacher {
+	m := &mirrorCache{
+		primary:   primary,
+		secondary: secondary,
+		logger:    newDefaultLogger(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *mirrorCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return m.primary.Get(ctx, key)
+}
+
+func (m *mirrorCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return m.primary.MGet(ctx, keys)
+}
+
+func (m *mirrorCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := m.primary.Set(ctx, key, val, ttl); err != nil {
+		return err
+	}
+	m.mirror(ctx, "set", m.secondary.Set(ctx, key, val, ttl))
+	return nil
+}
+
+func (m *mirrorCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	if err := m.primary.MSet(ctx, kvs, ttl); err != nil {
+		return err
+	}
+	m.mirror(ctx, "mset", m.secondary.MSet(ctx, kvs, ttl))
+	return nil
+}
+
+func (m *mirrorCache) Delete(ctx context.Context, key string) error {
+	err := m.primary.Delete(ctx, key)
+	m.mirror(ctx, "delete", m.secondary.Delete(ctx, key))
+	return err
+}
+
+func (m *mirrorCache) MDelete(ctx context.Context, keys []string) error {
+	err := m.primary.MDelete(ctx, keys)
+	m.mirror(ctx, "mdelete", m.secondary.MDelete(ctx, keys))
+	return err
+}
+
+// CompareAndSwap 只在primary上比较，写入成功后同步写入secondary
+func (m *mirrorCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	swapped, err := casCacher(ctx, m.primary, key, old, new, ttl)
+	if swapped {
+		m.mirror(ctx, "set", m.secondary.Set(ctx, key, new, ttl))
+	}
+	return swapped, err
+}
+
+// Ping 只检查primary，secondary不可用时打印Warn日志
+func (m *mirrorCache) Ping(ctx context.Context) error {
+	err := pingCacher(ctx, m.primary)
+	m.mirror(ctx, "ping", pingCacher(ctx, m.secondary))
+	return err
+}
+
+func (m *mirrorCache) mirror(ctx context.Context, op string, err error) {
+	if err != nil {
+		fields := map[string]interface{}{"op": op, "layer": "secondary", "error": err}
+		logWarn(ctx, m.logger, fields, "cachex: mirror cacher secondary %s error: %v", op, err)
+	}
+}
@@ -0,0 +1,89 @@
+package cachex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMirrorCacher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("read from primary", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCacher(ctrl)
+		secondary := NewMockCacher(ctrl)
+		primary.EXPECT().Get(gomock.Any(), "k1").Return([]byte("v1"), nil).Times(1)
+		primary.EXPECT().MGet(gomock.Any(), []string{"k1"}).Return(map[string][]byte{"k1": []byte("v1")}, nil).Times(1)
+		cacher := NewMirrorCacher(primary, secondary)
+
+		got, err := cacher.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), got)
+		gots, err := cacher.MGet(ctx, []string{"k1"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"k1": []byte("v1")}, gots)
+	})
+
+	t.Run("write to both", func(t *testing.T) {
+		primary := NewLocalCacher(1)
+		secondary := NewLocalCacher(1)
+		cacher := NewMirrorCacher(primary, secondary)
+
+		assert.NoError(t, cacher.Set(ctx, "k1", []byte("v1"), time.Minute))
+		assert.NoError(t, cacher.MSet(ctx, map[string][]byte{"k2": []byte("v2"), "k3": []byte("v3")}, time.Minute))
+		for _, c := range []Cacher{primary, secondary} {
+			got, err := c.MGet(ctx, []string{"k1", "k2", "k3"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2"), "k3": []byte("v3")}, got)
+		}
+
+		assert.NoError(t, cacher.Delete(ctx, "k1"))
+		assert.NoError(t, cacher.MDelete(ctx, []string{"k2", "k3"}))
+		for _, c := range []Cacher{primary, secondary} {
+			got, err := c.MGet(ctx, []string{"k1", "k2", "k3"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string][]byte{"k1": nil, "k2": nil, "k3": nil}, got)
+		}
+	})
+
+	t.Run("secondary failure tolerated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		secondary := NewMockCacher(ctrl)
+		secondary.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		secondary.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		secondary.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		secondary.EXPECT().MDelete(gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		logger := &recordLogger{}
+		primary := NewLocalCacher(1)
+		cacher := NewMirrorCacher(primary, secondary, WithMirrorLogger(logger))
+
+		assert.NoError(t, cacher.Set(ctx, "k1", []byte("v1"), time.Minute))
+		assert.NoError(t, cacher.MSet(ctx, map[string][]byte{"k2": []byte("v2")}, time.Minute))
+		got, err := primary.Get(ctx, "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), got)
+		assert.NoError(t, cacher.Delete(ctx, "k1"))
+		assert.NoError(t, cacher.MDelete(ctx, []string{"k2"}))
+		assert.Len(t, logger.Warns(), 4)
+	})
+
+	t.Run("primary failure returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		primary := NewMockCacher(ctrl)
+		primary.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		primary.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		secondary := NewLocalCacher(1)
+		cacher := NewMirrorCacher(primary, secondary)
+		assert.ErrorIs(t, cacher.Set(ctx, "k1", []byte("v1"), time.Minute), assert.AnError)
+		assert.ErrorIs(t, cacher.MSet(ctx, map[string][]byte{"k2": []byte("v2")}, time.Minute), assert.AnError)
+
+		// primary写入失败时不写入secondary
+		got, err := secondary.MGet(ctx, []string{"k1", "k2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"k1": nil, "k2": nil}, got)
+	})
+}
@@ -9,18 +9,78 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const defaultRedisBatchSize = 1000
+
+// compareAndSwapScript 值与ARGV[1]相同时写入ARGV[2]，ARGV[3]为过期时间(ms)，0表示不过期
+var compareAndSwapScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
 type redisCache struct {
-	cli *redis.Client
+	cli        *redis.Client
+	batchSize  int           // 批量操作单条命令的最大key数量
+	slidingTTL time.Duration // 读取时刷新的过期时间，0表示不刷新
+	unlink     bool          // 删除时使用UNLINK代替DEL
+}
+
+// RedisCacherOption redis cacher 配置选项
+type RedisCacherOption func(*redisCache)
+
+// WithRedisBatchSize 设置批量操作单条命令的最大key数量，超过时拆分为多条命令，默认1000
+func WithRedisBatchSize(size int) RedisCacherOption {
+	return func(r *redisCache) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
 }
 
-func NewRedisCacher(cli *redis.Client) Cacher {
-	return &redisCache{
-		cli: cli,
+// WithSlidingTTL 读取命中时使用GETEX将key的过期时间重置为ttl，使频繁访问的key一直保留
+// 只影响redis中key的删除时间，不影响entry自身的业务过期时间(WithExpireTTL)，
+// 业务过期后依然会按回源策略回源，ttl应不小于写入时的删除时间
+func WithSlidingTTL(ttl time.Duration) RedisCacherOption {
+	return func(r *redisCache) {
+		if ttl > 0 {
+			r.slidingTTL = ttl
+		}
+	}
+}
+
+// WithUnlink 删除时使用UNLINK代替DEL，由redis在后台线程回收内存，避免删除大value时阻塞redis
+// 需要redis 4.0及以上版本，默认使用DEL
+func WithUnlink(unlink bool) RedisCacherOption {
+	return func(r *redisCache) {
+		r.unlink = unlink
+	}
+}
+
+func NewRedisCacher(cli *redis.Client, opts ...RedisCacherOption) Cacher {
+	r := &redisCache{
+		cli:       cli,
+		batchSize: defaultRedisBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
-	val, err := r.cli.Get(ctx, key).Bytes()
+	var cmd *redis.StringCmd
+	if r.slidingTTL > 0 {
+		cmd = r.cli.GetEx(ctx, key, r.slidingTTL)
+	} else {
+		cmd = r.cli.Get(ctx, key)
+	}
+	val, err := cmd.Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, nil
@@ -31,18 +91,78 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 }
 
 func (r *redisCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
-	values, err := r.cli.MGet(ctx, keys...).Result()
+	if r.slidingTTL > 0 {
+		return r.mGetEx(ctx, keys)
+	}
+	// 按batchSize拆分为多条MGET，通过pipeline一次发送
+	chunks := r.chunk(keys)
+	pipe := r.cli.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(chunks))
+	for i, chunk := range chunks {
+		cmds[i] = pipe.MGet(ctx, chunk...)
+	}
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("redis error: %w", err)
 	}
 	result := make(map[string][]byte, len(keys))
-	for i, key := range keys {
-		val, ok := values[i].(string)
-		if !ok || val == "" {
-			result[key] = nil
-			continue
+	for i, chunk := range chunks {
+		values, err := cmds[i].Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis error: %w", err)
+		}
+		if len(values) != len(chunk) {
+			return nil, fmt.Errorf("redis error: mget returned %d values for %d keys", len(values), len(chunk))
+		}
+		for j, key := range chunk {
+			val, err := mGetValue(values[j])
+			if err != nil {
+				return nil, fmt.Errorf("redis error: key %s: %w", key, err)
+			}
+			result[key] = val
+		}
+	}
+	return result, nil
+}
+
+// mGetValue 转换MGET返回的单个值，nil表示key不存在
+func mGetValue(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return stringToBytes(val), nil
+	case []byte:
+		return val, nil
+	default:
+		return nil, fmt.Errorf("unexpected mget value type %T", v)
+	}
+}
+
+// mGetEx 通过pipeline对每个key执行GETEX，每batchSize个key执行一次
+func (r *redisCache) mGetEx(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for _, chunk := range r.chunk(keys) {
+		pipe := r.cli.Pipeline()
+		cmds := make([]*redis.StringCmd, len(chunk))
+		for i, key := range chunk {
+			cmds[i] = pipe.GetEx(ctx, key, r.slidingTTL)
+		}
+		// key不存在时Exec返回redis.Nil，逐个检查命令结果
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("redis error: %w", err)
+		}
+		for i, key := range chunk {
+			val, err := cmds[i].Bytes()
+			if err != nil {
+				if errors.Is(err, redis.Nil) {
+					result[key] = nil
+					continue
+				}
+				return nil, fmt.Errorf("redis error: %w", err)
+			}
+			result[key] = val
 		}
-		result[key] = stringToBytes(val)
 	}
 	return result, nil
 }
@@ -55,10 +175,29 @@ func (r *redisCache) Set(ctx context.Context, key string, val []byte, ttl time.D
 	return nil
 }
 
+// CompareAndSwap 通过Lua脚本原子地比较并写入
+func (r *redisCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	swapped, err := compareAndSwapScript.Run(ctx, r.cli, []string{key}, old, new, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis error: %w", err)
+	}
+	return swapped == 1, nil
+}
+
 func (r *redisCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	// 每batchSize个key执行一次pipeline
 	pipe := r.cli.Pipeline()
 	for k, v := range kvs {
 		pipe.Set(ctx, k, v, ttl)
+		if pipe.Len() < r.batchSize {
+			continue
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("redis error: %w", err)
+		}
+	}
+	if pipe.Len() == 0 {
+		return nil
 	}
 	_, err := pipe.Exec(ctx)
 	if err != nil {
@@ -68,7 +207,7 @@ func (r *redisCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.D
 }
 
 func (r *redisCache) Delete(ctx context.Context, key string) error {
-	err := r.cli.Del(ctx, key).Err()
+	err := r.del(ctx, r.cli, key).Err()
 	if err != nil {
 		return fmt.Errorf("redis error: %w", err)
 	}
@@ -76,9 +215,41 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 }
 
 func (r *redisCache) MDelete(ctx context.Context, keys []string) error {
-	err := r.cli.Del(ctx, keys...).Err()
+	// 按batchSize拆分为多条DEL，通过pipeline一次发送
+	pipe := r.cli.Pipeline()
+	for _, chunk := range r.chunk(keys) {
+		r.del(ctx, pipe, chunk...)
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
+
+// del 根据配置使用DEL或UNLINK删除keys
+func (r *redisCache) del(ctx context.Context, cmd redis.Cmdable, keys ...string) *redis.IntCmd {
+	if r.unlink {
+		return cmd.Unlink(ctx, keys...)
+	}
+	return cmd.Del(ctx, keys...)
+}
+
+// Ping 发送PING检查redis连接
+func (r *redisCache) Ping(ctx context.Context) error {
+	err := r.cli.Ping(ctx).Err()
 	if err != nil {
 		return fmt.Errorf("redis error: %w", err)
 	}
 	return nil
 }
+
+// chunk 按batchSize拆分keys
+func (r *redisCache) chunk(keys []string) [][]string {
+	chunks := make([][]string, 0, (len(keys)+r.batchSize-1)/r.batchSize)
+	for start := 0; start < len(keys); start += r.batchSize {
+		end := min(start+r.batchSize, len(keys))
+		chunks = append(chunks, keys[start:end])
+	}
+	return chunks
+}
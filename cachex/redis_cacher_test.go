@@ -2,6 +2,9 @@ package cachex
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -80,6 +83,64 @@ func TestRedisCacher_MSetMGet(t *testing.T) {
 	assert.Nil(t, results["key3Redis"])
 }
 
+func TestRedisCacher_MGetMissAndError(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	counter := &cmdCounter{counts: make(map[string]int)}
+	cli.AddHook(counter)
+	cacher := NewRedisCacher(cli, WithRedisBatchSize(2))
+	ctx := context.Background()
+
+	kvs := map[string][]byte{
+		"mget1": []byte("v1"),
+		"mget2": []byte("v2"),
+		"mget3": []byte("v3"),
+	}
+	assert.NoError(t, cacher.MSet(ctx, kvs, time.Minute))
+
+	t.Run("all hit", func(t *testing.T) {
+		pipelines := counter.Pipelines()
+		got, err := cacher.MGet(ctx, []string{"mget1", "mget2", "mget3"})
+		assert.NoError(t, err)
+		assert.Equal(t, kvs, got)
+		// 3个key按batchSize=2拆分为2条MGET，在同一个pipeline中发送
+		assert.Equal(t, 2, counter.Count("mget"))
+		assert.Equal(t, pipelines+1, counter.Pipelines())
+	})
+
+	t.Run("partial miss", func(t *testing.T) {
+		// 非string类型的key，MGET返回nil，当作未命中
+		_, err := s.Lpush("mget-list", "x")
+		assert.NoError(t, err)
+
+		got, err := cacher.MGet(ctx, []string{"mget1", "missing", "mget3", "mget-list"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 4)
+		assert.Equal(t, []byte("v1"), got["mget1"])
+		assert.Equal(t, []byte("v3"), got["mget3"])
+		assert.Nil(t, got["missing"])
+		assert.Nil(t, got["mget-list"])
+	})
+
+	t.Run("redis error", func(t *testing.T) {
+		s.SetError("simulated failure")
+		defer s.SetError("")
+
+		got, err := cacher.MGet(ctx, []string{"mget1", "mget2", "mget3"})
+		assert.ErrorContains(t, err, "simulated failure")
+		assert.Nil(t, got)
+	})
+
+	t.Run("unexpected value type", func(t *testing.T) {
+		_, err := mGetValue(int64(1))
+		assert.ErrorContains(t, err, "unexpected mget value type int64")
+
+		val, err := mGetValue([]byte("v"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v"), val)
+	})
+}
+
 func TestRedisCacher_DeleteMDelete(t *testing.T) {
 	s := miniredis.RunT(t)
 	defer s.Close()
@@ -122,3 +183,215 @@ func TestRedisCacher_NewRedisCacher(t *testing.T) {
 	cacher := NewRedisCacher(cli)
 	assert.NotNil(t, cacher)
 }
+
+// cmdCounter 统计发送到redis的命令数量和pipeline数量
+type cmdCounter struct {
+	mu        sync.Mutex
+	counts    map[string]int
+	pipelines int
+}
+
+func (c *cmdCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (c *cmdCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.add(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (c *cmdCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.mu.Lock()
+		c.pipelines++
+		c.mu.Unlock()
+		for _, cmd := range cmds {
+			c.add(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (c *cmdCounter) add(cmd redis.Cmder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[cmd.Name()]++
+}
+
+func (c *cmdCounter) Count(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[name]
+}
+
+func (c *cmdCounter) Pipelines() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pipelines
+}
+
+func TestRedisCacher_BatchSize(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	counter := &cmdCounter{counts: make(map[string]int)}
+	cli.AddHook(counter)
+	cacher := NewRedisCacher(cli, WithRedisBatchSize(1000))
+	ctx := context.Background()
+
+	keys := make([]string, 2500)
+	kvs := make(map[string][]byte, len(keys))
+	for i := range keys {
+		keys[i] = fmt.Sprintf("batchKey%d", i)
+		kvs[keys[i]] = []byte(fmt.Sprintf("batchValue%d", i))
+	}
+
+	// Test MSet
+	err := cacher.MSet(ctx, kvs, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, len(keys), counter.Count("set"))
+
+	// Test MGet - 拆分为3条MGET，通过一个pipeline发送
+	pipelines := counter.Pipelines()
+	results, err := cacher.MGet(ctx, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, counter.Count("mget"))
+	assert.Equal(t, pipelines+1, counter.Pipelines())
+	assert.Len(t, results, len(keys))
+	for _, key := range keys {
+		assert.Equal(t, kvs[key], results[key])
+	}
+
+	// Test MDelete - 拆分为3条DEL
+	err = cacher.MDelete(ctx, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, counter.Count("del"))
+	assert.Empty(t, s.Keys())
+}
+
+func TestRedisCacher_SlidingTTL(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	ctx := context.Background()
+
+	t.Run("enabled", func(t *testing.T) {
+		cacher := NewRedisCacher(cli, WithSlidingTTL(time.Hour))
+		assert.NoError(t, cacher.Set(ctx, "slidingKey", []byte("v"), time.Minute))
+		assert.NoError(t, cacher.MSet(ctx, map[string][]byte{"slidingKey1": []byte("v1"), "slidingKey2": []byte("v2")}, time.Minute))
+		assert.Equal(t, time.Minute, s.TTL("slidingKey"))
+
+		got, err := cacher.Get(ctx, "slidingKey")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v"), got)
+		assert.Equal(t, time.Hour, s.TTL("slidingKey"))
+
+		results, err := cacher.MGet(ctx, []string{"slidingKey1", "slidingKey2", "slidingMissing"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"slidingKey1": []byte("v1"), "slidingKey2": []byte("v2"), "slidingMissing": nil}, results)
+		assert.Equal(t, time.Hour, s.TTL("slidingKey1"))
+		assert.Equal(t, time.Hour, s.TTL("slidingKey2"))
+		assert.False(t, s.Exists("slidingMissing"))
+
+		got, err = cacher.Get(ctx, "slidingMissing")
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cacher := NewRedisCacher(cli)
+		assert.NoError(t, cacher.Set(ctx, "fixedKey", []byte("v"), time.Minute))
+		_, err := cacher.Get(ctx, "fixedKey")
+		assert.NoError(t, err)
+		_, err = cacher.MGet(ctx, []string{"fixedKey"})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, s.TTL("fixedKey"))
+	})
+}
+
+func TestRedisCacher_Ping(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	cacher := NewRedisCacher(cli)
+	assert.NoError(t, cacher.(HealthChecker).Ping(ctx))
+
+	assert.NoError(t, cli.Close())
+	assert.ErrorIs(t, cacher.(HealthChecker).Ping(ctx), redis.ErrClosed)
+}
+
+func TestRedisCacher_Unlink(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+
+	for _, unlink := range []bool{false, true} {
+		t.Run(fmt.Sprintf("unlink=%v", unlink), func(t *testing.T) {
+			cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+			counter := &cmdCounter{counts: make(map[string]int)}
+			cli.AddHook(counter)
+			cacher := NewRedisCacher(cli, WithUnlink(unlink), WithRedisBatchSize(2))
+
+			kvs := map[string][]byte{"u1": []byte("v1"), "u2": []byte("v2"), "u3": []byte("v3"), "u4": []byte("v4")}
+			assert.NoError(t, cacher.MSet(ctx, kvs, time.Minute))
+
+			assert.NoError(t, cacher.Delete(ctx, "u1"))
+			assert.False(t, s.Exists("u1"))
+			assert.NoError(t, cacher.MDelete(ctx, []string{"u2", "u3", "u4"}))
+			assert.Empty(t, s.Keys())
+
+			if unlink {
+				assert.Equal(t, 3, counter.Count("unlink"))
+				assert.Equal(t, 0, counter.Count("del"))
+			} else {
+				assert.Equal(t, 3, counter.Count("del"))
+				assert.Equal(t, 0, counter.Count("unlink"))
+			}
+		})
+	}
+}
+
+func TestRedisCacher_CompareAndSwap(t *testing.T) {
+	s := miniredis.RunT(t)
+	ctx := context.Background()
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	cacher := NewRedisCacher(cli).(CompareAndSwapper)
+
+	// key不存在
+	swapped, err := cacher.CompareAndSwap(ctx, "k", []byte("v1"), []byte("v2"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	assert.False(t, s.Exists("k"))
+
+	assert.NoError(t, s.Set("k", "v1"))
+	// 值不一致
+	swapped, err = cacher.CompareAndSwap(ctx, "k", []byte("v0"), []byte("v2"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	got, _ := s.Get("k")
+	assert.Equal(t, "v1", got)
+
+	// 替换成功并设置过期时间
+	swapped, err = cacher.CompareAndSwap(ctx, "k", []byte("v1"), []byte("v2"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	got, _ = s.Get("k")
+	assert.Equal(t, "v2", got)
+	assert.Equal(t, time.Minute, s.TTL("k"))
+
+	// ttl为0时不过期
+	swapped, err = cacher.CompareAndSwap(ctx, "k", []byte("v2"), []byte("v3"), 0)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, time.Duration(0), s.TTL("k"))
+}
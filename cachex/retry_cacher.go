@@ -0,0 +1,106 @@
+package cachex
+
+import (
+	"context"
+	"time"
+)
+
+// retryCache 失败自动重试的Cacher装饰器
+type retryCache struct {
+	inner    Cacher
+	attempts int
+	backoff  time.Duration
+}
+
+// NewRetryCacher 包装一个Cacher，操作失败时按backoff间隔重试，最多执行attempts次
+// attempts小于1时按1处理，即不重试
+func NewRetryCacher(inner Cacher, attempts int, backoff time.Duration) Cacher {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &retryCache{
+		inner:    inner,
+		attempts: attempts,
+		backoff:  backoff,
+	}
+}
+
+func (r *retryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var val []byte
+	err := r.do(ctx, func() (err error) {
+		val, err = r.inner.Get(ctx, key)
+		return err
+	})
+	return val, err
+}
+
+func (r *retryCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	var vals map[string][]byte
+	err := r.do(ctx, func() (err error) {
+		vals, err = r.inner.MGet(ctx, keys)
+		return err
+	})
+	return vals, err
+}
+
+func (r *retryCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	return r.do(ctx, func() error {
+		return r.inner.Set(ctx, key, val, ttl)
+	})
+}
+
+func (r *retryCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	return r.do(ctx, func() error {
+		return r.inner.MSet(ctx, kvs, ttl)
+	})
+}
+
+func (r *retryCache) Delete(ctx context.Context, key string) error {
+	return r.do(ctx, func() error {
+		return r.inner.Delete(ctx, key)
+	})
+}
+
+func (r *retryCache) MDelete(ctx context.Context, keys []string) error {
+	return r.do(ctx, func() error {
+		return r.inner.MDelete(ctx, keys)
+	})
+}
+
+func (r *retryCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	var swapped bool
+	err := r.do(ctx, func() (err error) {
+		swapped, err = casCacher(ctx, r.inner, key, old, new, ttl)
+		return err
+	})
+	return swapped, err
+}
+
+func (r *retryCache) Ping(ctx context.Context) error {
+	return r.do(ctx, func() error {
+		return pingCacher(ctx, r.inner)
+	})
+}
+
+func (r *retryCache) do(ctx context.Context, fn func() error) error {
+	var err error
+	for i := 0; i < r.attempts; i++ {
+		if i > 0 {
+			// 等待后重试
+			select {
+			case <-time.After(r.backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		// ctx已取消，不再重试
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package cachex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRetryCacher(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	ctx := context.Background()
+
+	t.Run("get fails once then succeeds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		gomock.InOrder(
+			inner.EXPECT().Get(gomock.Any(), "key").Return(nil, errFlaky).Times(1),
+			inner.EXPECT().Get(gomock.Any(), "key").Return([]byte("value"), nil).Times(1),
+		)
+		cacher := NewRetryCacher(inner, 2, time.Millisecond)
+		got, err := cacher.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), got)
+	})
+
+	t.Run("set fails once then succeeds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		gomock.InOrder(
+			inner.EXPECT().Set(gomock.Any(), "key", []byte("value"), time.Minute).Return(errFlaky).Times(1),
+			inner.EXPECT().Set(gomock.Any(), "key", []byte("value"), time.Minute).Return(nil).Times(1),
+		)
+		cacher := NewRetryCacher(inner, 3, time.Millisecond)
+		assert.NoError(t, cacher.Set(ctx, "key", []byte("value"), time.Minute))
+	})
+
+	t.Run("mget/mset/delete/mdelete retry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		gomock.InOrder(
+			inner.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(nil, errFlaky).Times(1),
+			inner.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(map[string][]byte{"key": []byte("value")}, nil).Times(1),
+		)
+		gomock.InOrder(
+			inner.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(errFlaky).Times(1),
+			inner.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1),
+		)
+		gomock.InOrder(
+			inner.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(errFlaky).Times(1),
+			inner.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+		)
+		gomock.InOrder(
+			inner.EXPECT().MDelete(gomock.Any(), gomock.Any()).Return(errFlaky).Times(1),
+			inner.EXPECT().MDelete(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+		)
+		cacher := NewRetryCacher(inner, 2, time.Millisecond)
+		got, err := cacher.MGet(ctx, []string{"key"})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), got["key"])
+		assert.NoError(t, cacher.MSet(ctx, map[string][]byte{"key": []byte("value")}, time.Minute))
+		assert.NoError(t, cacher.Delete(ctx, "key"))
+		assert.NoError(t, cacher.MDelete(ctx, []string{"key"}))
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().Get(gomock.Any(), "key").Return(nil, errFlaky).Times(3)
+		cacher := NewRetryCacher(inner, 3, time.Millisecond)
+		_, err := cacher.Get(ctx, "key")
+		assert.ErrorIs(t, err, errFlaky)
+	})
+
+	t.Run("ctx canceled during backoff", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().Get(gomock.Any(), "key").Return(nil, errFlaky).Times(1)
+		cacher := NewRetryCacher(inner, 3, time.Second)
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := cacher.Get(cctx, "key")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
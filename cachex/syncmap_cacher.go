@@ -0,0 +1,167 @@
+package cachex
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// syncMapCache 基于sync.Map的本地缓存，适合配置、开关等数量很少的缓存
+// 读写都拷贝value，调用方修改传入或读到的[]byte不影响缓存
+type syncMapCache struct {
+	m sync.Map // key: string, value: *syncMapItem
+}
+
+type syncMapItem struct {
+	val      []byte
+	expireAt time.Time // 为零值时不过期
+}
+
+func (i *syncMapItem) expired(now time.Time) bool {
+	return !i.expireAt.IsZero() && !now.Before(i.expireAt)
+}
+
+// SyncMapCacherOption sync.Map cacher 配置选项
+type SyncMapCacherOption func(*syncMapCache)
+
+// WithSweeper 后台每隔interval清理过期的key，ctx取消时停止
+// 不开启时过期的key只在读取时删除，写入后不再读取的key会一直占用内存
+func WithSweeper(ctx context.Context, interval time.Duration) SyncMapCacherOption {
+	return func(c *syncMapCache) {
+		if interval <= 0 {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					c.sweep()
+				}
+			}
+		}()
+	}
+}
+
+// NewSyncMapCacher 基于sync.Map的Cacher，没有容量限制，过期时间精确到纳秒
+// 相比NewLocalCacher没有序列化到预分配内存的开销，只适合数量很少的key
+func NewSyncMapCacher(opts ...SyncMapCacherOption) Cacher {
+	c := &syncMapCache{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *syncMapCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.get(key, time.Now()), nil
+}
+
+func (c *syncMapCache) get(key string, now time.Time) []byte {
+	v, ok := c.m.Load(key)
+	if !ok {
+		return nil
+	}
+	item := v.(*syncMapItem)
+	if item.expired(now) {
+		// 只删除读到的过期值，避免删除并发写入的新值
+		c.m.CompareAndDelete(key, item)
+		return nil
+	}
+	return bytes.Clone(item.val)
+}
+
+func (c *syncMapCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		result[key] = c.get(key, now)
+	}
+	return result, nil
+}
+
+func (c *syncMapCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.m.Store(key, newSyncMapItem(val, ttl))
+	return nil
+}
+
+func (c *syncMapCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for k, v := range kvs {
+		c.m.Store(k, newSyncMapItem(v, ttl))
+	}
+	return nil
+}
+
+func (c *syncMapCache) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.m.Delete(key)
+	return nil
+}
+
+func (c *syncMapCache) MDelete(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		c.m.Delete(key)
+	}
+	return nil
+}
+
+// CompareAndSwap 通过sync.Map.CompareAndSwap原子地替换读到的值
+func (c *syncMapCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	v, ok := c.m.Load(key)
+	if !ok {
+		return false, nil
+	}
+	item := v.(*syncMapItem)
+	if item.expired(time.Now()) || !bytes.Equal(item.val, old) {
+		return false, nil
+	}
+	return c.m.CompareAndSwap(key, item, newSyncMapItem(new, ttl)), nil
+}
+
+// Ping 本地缓存始终可用
+func (c *syncMapCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// sweep 删除所有过期的key
+func (c *syncMapCache) sweep() {
+	now := time.Now()
+	c.m.Range(func(key, value any) bool {
+		if value.(*syncMapItem).expired(now) {
+			c.m.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
+
+func newSyncMapItem(val []byte, ttl time.Duration) *syncMapItem {
+	item := &syncMapItem{val: bytes.Clone(val)}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	return item
+}
@@ -0,0 +1,159 @@
+package cachex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMapCacher_SetGet(t *testing.T) {
+	cacher := NewSyncMapCacher()
+	ctx := context.Background()
+
+	assert.NoError(t, cacher.Set(ctx, "k", []byte("v"), 50*time.Millisecond))
+	got, err := cacher.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), got)
+
+	// 过期后读取返回nil
+	time.Sleep(60 * time.Millisecond)
+	got, err = cacher.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	// ttl<=0不过期
+	assert.NoError(t, cacher.Set(ctx, "forever", []byte("v"), 0))
+	got, err = cacher.Get(ctx, "forever")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), got)
+
+	got, err = cacher.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSyncMapCacher_Copy(t *testing.T) {
+	cacher := NewSyncMapCacher()
+	ctx := context.Background()
+
+	// 写入后修改传入的[]byte不影响缓存
+	val := []byte("v1")
+	assert.NoError(t, cacher.Set(ctx, "k", val, 0))
+	val[1] = '2'
+	got, err := cacher.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), got)
+
+	// 修改读到的[]byte不影响缓存
+	got[1] = '3'
+	mGot, err := cacher.MGet(ctx, []string{"k"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), mGot["k"])
+
+	kvs := map[string][]byte{"m": []byte("v1")}
+	assert.NoError(t, cacher.MSet(ctx, kvs, 0))
+	kvs["m"][1] = '2'
+	got, err = cacher.Get(ctx, "m")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), got)
+}
+
+func TestSyncMapCacher_MSetMGetMDelete(t *testing.T) {
+	cacher := NewSyncMapCacher()
+	ctx := context.Background()
+
+	kvs := map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")}
+	assert.NoError(t, cacher.MSet(ctx, kvs, time.Minute))
+
+	got, err := cacher.MGet(ctx, []string{"k1", "k2", "k3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2"), "k3": nil}, got)
+
+	assert.NoError(t, cacher.Delete(ctx, "k1"))
+	v, err := cacher.Get(ctx, "k1")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.NoError(t, cacher.MDelete(ctx, []string{"k2"}))
+	v, err = cacher.Get(ctx, "k2")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestSyncMapCacher_ContextCanceled(t *testing.T) {
+	cacher := NewSyncMapCacher()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cacher.Get(ctx, "k")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, cacher.Set(ctx, "k", []byte("v"), time.Minute), context.Canceled)
+}
+
+func TestSyncMapCacher_CompareAndSwap(t *testing.T) {
+	cacher := NewSyncMapCacher().(CompareAndSwapper)
+	ctx := context.Background()
+
+	ok, err := cacher.CompareAndSwap(ctx, "k", []byte("a"), []byte("b"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, cacher.(Cacher).Set(ctx, "k", []byte("a"), time.Minute))
+	ok, err = cacher.CompareAndSwap(ctx, "k", []byte("x"), []byte("b"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = cacher.CompareAndSwap(ctx, "k", []byte("a"), []byte("b"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	got, _ := cacher.(Cacher).Get(ctx, "k")
+	assert.Equal(t, []byte("b"), got)
+}
+
+func TestSyncMapCacher_Sweeper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cacher := NewSyncMapCacher(WithSweeper(ctx, 10*time.Millisecond))
+	c := cacher.(*syncMapCache)
+
+	assert.NoError(t, cacher.Set(ctx, "expire", []byte("v"), 20*time.Millisecond))
+	assert.NoError(t, cacher.Set(ctx, "keep", []byte("v"), time.Minute))
+
+	// 不读取过期的key，由后台清理
+	assert.Eventually(t, func() bool {
+		_, ok := c.m.Load("expire")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	_, ok := c.m.Load("keep")
+	assert.True(t, ok)
+}
+
+func TestSyncMapCacher_Concurrent(t *testing.T) {
+	cacher := NewSyncMapCacher()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("k%d", j%10)
+				val := []byte(fmt.Sprintf("v%d", i))
+				assert.NoError(t, cacher.Set(ctx, key, val, time.Millisecond))
+				_, err := cacher.Get(ctx, key)
+				assert.NoError(t, err)
+				_, err = cacher.MGet(ctx, []string{key, "other"})
+				assert.NoError(t, err)
+				if j%7 == 0 {
+					assert.NoError(t, cacher.Delete(ctx, key))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
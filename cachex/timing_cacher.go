@@ -0,0 +1,74 @@
+package cachex
+
+import (
+	"context"
+	"time"
+)
+
+// timingCache 记录慢操作的Cacher装饰器
+type timingCache struct {
+	inner         Cacher
+	slowThreshold time.Duration
+	logger        Logger
+}
+
+// NewTimingCacher 包装一个Cacher，操作耗时超过slowThreshold时打印Warn日志
+// logger为nil时使用默认logger
+func NewTimingCacher(inner Cacher, slowThreshold time.Duration, logger Logger) Cacher {
+	if logger == nil {
+		logger = newDefaultLogger()
+	}
+	return &timingCache{
+		inner:         inner,
+		slowThreshold: slowThreshold,
+		logger:        logger,
+	}
+}
+
+func (t *timingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	defer t.observe(ctx, "get", 1, time.Now())
+	return t.inner.Get(ctx, key)
+}
+
+func (t *timingCache) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	defer t.observe(ctx, "mget", len(keys), time.Now())
+	return t.inner.MGet(ctx, keys)
+}
+
+func (t *timingCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	defer t.observe(ctx, "set", 1, time.Now())
+	return t.inner.Set(ctx, key, val, ttl)
+}
+
+func (t *timingCache) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	defer t.observe(ctx, "mset", len(kvs), time.Now())
+	return t.inner.MSet(ctx, kvs, ttl)
+}
+
+func (t *timingCache) Delete(ctx context.Context, key string) error {
+	defer t.observe(ctx, "delete", 1, time.Now())
+	return t.inner.Delete(ctx, key)
+}
+
+func (t *timingCache) MDelete(ctx context.Context, keys []string) error {
+	defer t.observe(ctx, "mdelete", len(keys), time.Now())
+	return t.inner.MDelete(ctx, keys)
+}
+
+func (t *timingCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	defer t.observe(ctx, "cas", 1, time.Now())
+	return casCacher(ctx, t.inner, key, old, new, ttl)
+}
+
+func (t *timingCache) Ping(ctx context.Context) error {
+	defer t.observe(ctx, "ping", 0, time.Now())
+	return pingCacher(ctx, t.inner)
+}
+
+func (t *timingCache) observe(ctx context.Context, op string, keyCount int, begin time.Time) {
+	elapsed := time.Since(begin)
+	if elapsed > t.slowThreshold {
+		fields := map[string]interface{}{"op": op, "key_count": keyCount, "latency": elapsed}
+		logWarn(ctx, t.logger, fields, "cachex: slow cacher %s, keys:%d, latency:%v", op, keyCount, elapsed)
+	}
+}
@@ -0,0 +1,94 @@
+package cachex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type recordLogger struct {
+	mu    sync.Mutex
+	warns []string
+	errs  []string
+}
+
+func (r *recordLogger) Infof(ctx context.Context, format string, v ...interface{}) {}
+
+func (r *recordLogger) Warnf(ctx context.Context, format string, v ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warns = append(r.warns, fmt.Sprintf(format, v...))
+}
+
+func (r *recordLogger) Errorf(ctx context.Context, format string, v ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, fmt.Sprintf(format, v...))
+}
+
+func (r *recordLogger) Warns() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.warns...)
+}
+
+func (r *recordLogger) Errors() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.errs...)
+}
+
+func TestTimingCacher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("slow operation logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().MGet(gomock.Any(), []string{"k1", "k2"}).DoAndReturn(
+			func(ctx context.Context, keys []string) (map[string][]byte, error) {
+				time.Sleep(20 * time.Millisecond)
+				return map[string][]byte{"k1": []byte("v1")}, nil
+			}).Times(1)
+		logger := &recordLogger{}
+		cacher := NewTimingCacher(inner, 10*time.Millisecond, logger)
+		got, err := cacher.MGet(ctx, []string{"k1", "k2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"k1": []byte("v1")}, got)
+		warns := logger.Warns()
+		assert.Len(t, warns, 1)
+		assert.Contains(t, warns[0], "slow cacher mget")
+		assert.Contains(t, warns[0], "keys:2")
+	})
+
+	t.Run("fast operation not logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().Get(gomock.Any(), "key").Return([]byte("value"), nil).Times(1)
+		inner.EXPECT().Set(gomock.Any(), "key", []byte("value"), time.Minute).Return(nil).Times(1)
+		logger := &recordLogger{}
+		cacher := NewTimingCacher(inner, time.Second, logger)
+		got, err := cacher.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), got)
+		assert.NoError(t, cacher.Set(ctx, "key", []byte("value"), time.Minute))
+		assert.Empty(t, logger.Warns())
+	})
+
+	t.Run("error passes through", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		inner := NewMockCacher(ctrl)
+		inner.EXPECT().Delete(gomock.Any(), "key").DoAndReturn(func(ctx context.Context, key string) error {
+			time.Sleep(20 * time.Millisecond)
+			return assert.AnError
+		}).Times(1)
+		logger := &recordLogger{}
+		cacher := NewTimingCacher(inner, 10*time.Millisecond, logger)
+		assert.ErrorIs(t, cacher.Delete(ctx, "key"), assert.AnError)
+		assert.Len(t, logger.Warns(), 1)
+	})
+}
@@ -0,0 +1,241 @@
+package cachex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/redis/go-redis/v9/push"
+)
+
+const (
+	defaultTrackingLocalTTL = time.Minute
+	invalidatePushName      = "invalidate" // RESP3 失效通知的名称
+	trackingVersionSlots    = 256          // 失效版本号的分片数，同一分片的key共享版本号
+)
+
+// TrackingRedisCacher 基于RESP3 CLIENT TRACKING的客户端缓存
+// 读取的key在进程内缓存一份，key在redis中被修改或删除时服务端推送失效通知，删除本地副本
+type TrackingRedisCacher struct {
+	cli      *redis.Client
+	remote   *redisCache
+	local    *localCache
+	localTTL time.Duration
+	enabled  atomic.Bool // 所有连接都成功开启了tracking
+
+	seed     maphash.Seed
+	versions [trackingVersionSlots]atomic.Uint64 // 按key分片的失效版本号，从redis读取期间版本变化时不写入本地缓存
+}
+
+var _ Cacher = (*TrackingRedisCacher)(nil)
+
+// NewRedisCacherWithTracking 使用opt创建redis客户端，并在每个连接上开启CLIENT TRACKING
+//
+// 本地缓存语义:
+//   - 本地缓存使用freecache，占用内存上限为localSizeMB
+//   - 本地副本最多保留localTTL，<=0时默认1分钟，用于限制漏掉失效通知时的脏读时间
+//   - 失效通知在收到通知的连接下一次被使用时才处理，空闲连接上的通知会延迟处理，不适合要求强一致的场景
+//   - 本进程的写入完成后立即删除本地副本
+//   - 从redis读取期间收到失效通知或本进程写入时，读到的值不写入本地缓存，避免旧值覆盖失效
+//   - 不要与WithSlidingTTL同时使用，GETEX修改过期时间也会触发失效通知
+//
+// 降级:
+//   - 服务端不支持RESP3或CLIENT TRACKING时，任一连接开启失败即关闭本地缓存，所有读写直接访问redis
+//
+// 使用完需要调用Close关闭客户端
+func NewRedisCacherWithTracking(opt *redis.Options, localSizeMB int, localTTL time.Duration, opts ...RedisCacherOption) (*TrackingRedisCacher, error) {
+	if localTTL <= 0 {
+		localTTL = defaultTrackingLocalTTL
+	}
+	t := &TrackingRedisCacher{
+		local:    NewLocalCacher(localSizeMB).(*localCache),
+		localTTL: localTTL,
+		seed:     maphash.MakeSeed(),
+	}
+	t.enabled.Store(true)
+
+	o := *opt
+	o.Protocol = 3
+	onConnect := o.OnConnect
+	o.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		// 开启失败不影响连接使用，只关闭本地缓存
+		if err := cn.Do(ctx, "CLIENT", "TRACKING", "ON").Err(); err != nil {
+			t.disable()
+		}
+		return nil
+	}
+	t.cli = redis.NewClient(&o)
+	t.remote = NewRedisCacher(t.cli, opts...).(*redisCache)
+
+	err := t.cli.RegisterPushNotificationHandler(invalidatePushName, &invalidateHandler{t: t}, false)
+	if err != nil {
+		_ = t.cli.Close()
+		return nil, fmt.Errorf("redis register invalidate handler error: %w", err)
+	}
+	return t, nil
+}
+
+// Tracking 本地缓存是否生效
+func (t *TrackingRedisCacher) Tracking() bool {
+	return t.enabled.Load()
+}
+
+// Close 关闭redis客户端
+func (t *TrackingRedisCacher) Close() error {
+	return t.cli.Close()
+}
+
+// Ping 发送PING检查redis连接
+func (t *TrackingRedisCacher) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
+
+func (t *TrackingRedisCacher) disable() {
+	if t.enabled.CompareAndSwap(true, false) {
+		t.invalidateAll()
+	}
+}
+
+// version key所在分片的失效版本号
+func (t *TrackingRedisCacher) version(key string) *atomic.Uint64 {
+	return &t.versions[maphash.String(t.seed, key)%trackingVersionSlots]
+}
+
+// invalidate 删除本地副本，先增加版本号，正在读取的旧值不会再写入
+// 写入redis时ctx可能已取消，删除本地副本不受ctx影响
+func (t *TrackingRedisCacher) invalidate(ctx context.Context, key string) {
+	t.version(key).Add(1)
+	_ = t.local.Delete(context.WithoutCancel(ctx), key)
+}
+
+// invalidateAll 清空本地缓存
+func (t *TrackingRedisCacher) invalidateAll() {
+	for i := range t.versions {
+		t.versions[i].Add(1)
+	}
+	t.local.fc.Clear()
+}
+
+// fill 将从redis读取的值写入本地缓存，ver为读取前的版本号
+// 版本号已变化时不写入；写入后再检查一次，与并发的invalidate交错时删除刚写入的值
+func (t *TrackingRedisCacher) fill(ctx context.Context, key string, val []byte, ver uint64) {
+	// 读取后再检查，连接在执行命令前已完成tracking开启
+	v := t.version(key)
+	if !t.enabled.Load() || v.Load() != ver {
+		return
+	}
+	_ = t.local.Set(ctx, key, val, t.localTTL)
+	if v.Load() != ver {
+		_ = t.local.Delete(ctx, key)
+	}
+}
+
+func (t *TrackingRedisCacher) Get(ctx context.Context, key string) ([]byte, error) {
+	if t.enabled.Load() {
+		if val, err := t.local.Get(ctx, key); err == nil && val != nil {
+			return val, nil
+		}
+	}
+	ver := t.version(key).Load()
+	val, err := t.remote.Get(ctx, key)
+	if err != nil || val == nil {
+		return val, err
+	}
+	t.fill(ctx, key, val, ver)
+	return val, nil
+}
+
+func (t *TrackingRedisCacher) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if !t.enabled.Load() {
+		return t.remote.MGet(ctx, keys)
+	}
+	result := make(map[string][]byte, len(keys))
+	miss := make([]string, 0, len(keys))
+	vers := make(map[string]uint64, len(keys))
+	for _, key := range keys {
+		if val, err := t.local.Get(ctx, key); err == nil && val != nil {
+			result[key] = val
+			continue
+		}
+		miss = append(miss, key)
+		vers[key] = t.version(key).Load()
+	}
+	if len(miss) == 0 {
+		return result, nil
+	}
+	fromRemote, err := t.remote.MGet(ctx, miss)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range fromRemote {
+		result[key] = val
+		if val != nil {
+			t.fill(ctx, key, val, vers[key])
+		}
+	}
+	return result, nil
+}
+
+func (t *TrackingRedisCacher) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	defer t.invalidate(ctx, key)
+	return t.remote.Set(ctx, key, val, ttl)
+}
+
+func (t *TrackingRedisCacher) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	defer t.invalidate(ctx, key)
+	return t.remote.CompareAndSwap(ctx, key, old, new, ttl)
+}
+
+func (t *TrackingRedisCacher) MSet(ctx context.Context, kvs map[string][]byte, ttl time.Duration) error {
+	defer func() {
+		for key := range kvs {
+			t.invalidate(ctx, key)
+		}
+	}()
+	return t.remote.MSet(ctx, kvs, ttl)
+}
+
+func (t *TrackingRedisCacher) Delete(ctx context.Context, key string) error {
+	defer t.invalidate(ctx, key)
+	return t.remote.Delete(ctx, key)
+}
+
+func (t *TrackingRedisCacher) MDelete(ctx context.Context, keys []string) error {
+	defer func() {
+		for _, key := range keys {
+			t.invalidate(ctx, key)
+		}
+	}()
+	return t.remote.MDelete(ctx, keys)
+}
+
+// invalidateHandler 处理服务端推送的失效通知: ["invalidate", [key...]]，key列表为nil表示FLUSHALL
+type invalidateHandler struct {
+	t *TrackingRedisCacher
+}
+
+func (h *invalidateHandler) HandlePushNotification(ctx context.Context, _ push.NotificationHandlerContext, notification []interface{}) error {
+	if len(notification) < 2 || notification[1] == nil {
+		h.t.invalidateAll()
+		return nil
+	}
+	keys, ok := notification[1].([]interface{})
+	if !ok {
+		return errors.New("invalid invalidate notification")
+	}
+	for _, key := range keys {
+		if k, ok := key.(string); ok {
+			h.t.invalidate(ctx, k)
+		}
+	}
+	return nil
+}
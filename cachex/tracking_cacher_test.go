@@ -0,0 +1,145 @@
+package cachex
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+	"github.com/redis/go-redis/v9/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTrackingCacher tracking为true时模拟服务端支持CLIENT TRACKING，否则使用miniredis默认的不支持
+func newTestTrackingCacher(t *testing.T, tracking bool) (*TrackingRedisCacher, *cmdCounter) {
+	s := miniredis.RunT(t)
+	if tracking {
+		s.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+			if cmd == "CLIENT" && len(args) > 0 && strings.EqualFold(args[0], "TRACKING") {
+				c.WriteOK()
+				return true
+			}
+			return false
+		})
+	}
+	cacher, err := NewRedisCacherWithTracking(&redis.Options{Addr: s.Addr()}, 1, time.Minute)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cacher.Close() })
+	counter := &cmdCounter{counts: make(map[string]int)}
+	cacher.cli.AddHook(counter)
+	return cacher, counter
+}
+
+func TestTrackingRedisCacher_Fallback(t *testing.T) {
+	ctx := context.Background()
+	cacher, counter := newTestTrackingCacher(t, false)
+
+	// miniredis不支持CLIENT TRACKING，降级为直接访问redis
+	require.NoError(t, cacher.Set(ctx, "k", []byte("v"), time.Minute))
+	assert.False(t, cacher.Tracking())
+	for i := 0; i < 3; i++ {
+		got, err := cacher.Get(ctx, "k")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v"), got)
+	}
+	assert.Equal(t, 3, counter.Count("get"))
+}
+
+func TestTrackingRedisCacher_LocalCache(t *testing.T) {
+	ctx := context.Background()
+	cacher, counter := newTestTrackingCacher(t, true)
+	require.NoError(t, cacher.Set(ctx, "k1", []byte("v1"), time.Minute))
+	require.NoError(t, cacher.Set(ctx, "k2", []byte("v2"), time.Minute))
+	require.True(t, cacher.Tracking())
+
+	t.Run("repeated get served locally", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			got, err := cacher.Get(ctx, "k1")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("v1"), got)
+		}
+		assert.Equal(t, 1, counter.Count("get"))
+
+		got, err := cacher.MGet(ctx, []string{"k1", "k2", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2"), "missing": nil}, got)
+		got, err = cacher.MGet(ctx, []string{"k1", "k2"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")}, got)
+		// 只有第一次MGet的未命中key访问了redis
+		assert.Equal(t, 1, counter.Count("mget"))
+	})
+
+	t.Run("invalidate notification", func(t *testing.T) {
+		handler := cacher.cli.GetPushNotificationHandler(invalidatePushName)
+		require.NotNil(t, handler)
+
+		require.NoError(t, handler.HandlePushNotification(ctx, push.NotificationHandlerContext{}, []interface{}{"invalidate", []interface{}{"k1"}}))
+		_, err := cacher.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, 2, counter.Count("get"))
+
+		// nil表示FLUSHALL，清空本地缓存
+		require.NoError(t, handler.HandlePushNotification(ctx, push.NotificationHandlerContext{}, []interface{}{"invalidate", nil}))
+		_, err = cacher.Get(ctx, "k2")
+		require.NoError(t, err)
+		assert.Equal(t, 3, counter.Count("get"))
+	})
+
+	t.Run("own write invalidates", func(t *testing.T) {
+		require.NoError(t, cacher.Set(ctx, "k1", []byte("v1-new"), time.Minute))
+		got, err := cacher.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1-new"), got)
+
+		require.NoError(t, cacher.Delete(ctx, "k1"))
+		got, err = cacher.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+// invalidateOnRead 在指定key从redis读取返回后、写入本地缓存前模拟收到失效通知
+type invalidateOnRead struct {
+	cmdCounter
+	handler push.NotificationHandler
+	key     string
+}
+
+func (h *invalidateOnRead) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if args := cmd.Args(); len(args) > 1 && args[1] == h.key {
+			notification := []interface{}{"invalidate", []interface{}{h.key}}
+			_ = h.handler.HandlePushNotification(ctx, push.NotificationHandlerContext{}, notification)
+		}
+		return err
+	}
+}
+
+func TestTrackingRedisCacher_InvalidateDuringRead(t *testing.T) {
+	ctx := context.Background()
+	cacher, counter := newTestTrackingCacher(t, true)
+	require.NoError(t, cacher.Set(ctx, "k", []byte("v"), time.Minute))
+	require.NoError(t, cacher.Set(ctx, "other", []byte("o"), time.Minute))
+	cacher.cli.AddHook(&invalidateOnRead{handler: cacher.cli.GetPushNotificationHandler(invalidatePushName), key: "k"})
+
+	// 读取期间key已失效，读到的值不写入本地缓存，下次读取仍访问redis
+	for i := 0; i < 2; i++ {
+		got, err := cacher.Get(ctx, "k")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v"), got)
+	}
+	assert.Equal(t, 2, counter.Count("get"))
+
+	// 未失效的key正常写入本地缓存
+	for i := 0; i < 2; i++ {
+		_, err := cacher.Get(ctx, "other")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, counter.Count("get"))
+}
@@ -1,6 +1,41 @@
 package cachex
 
-import "unsafe"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+	"unsafe"
+)
+
+// pingCacher 检查cacher是否可用，未实现HealthChecker时视为可用
+func pingCacher(ctx context.Context, c Cacher) error {
+	if hc, ok := c.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+// casCacher Cacher实现了CompareAndSwapper时执行CompareAndSwap，否则返回ErrCASNotSupported
+func casCacher(ctx context.Context, c Cacher, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	if cs, ok := c.(CompareAndSwapper); ok {
+		return cs.CompareAndSwap(ctx, key, old, new, ttl)
+	}
+	return false, ErrCASNotSupported
+}
+
+// recoverFn fn panic时恢复并记录日志，panic转换为ErrPanicRecovered返回，避免loader或codec的panic导致进程崩溃
+func recoverFn(ctx context.Context, logger Logger, fn func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf(ctx, "[cachex] panic recovered: %v, stack:\n%s", r, debug.Stack())
+				err = fmt.Errorf("%w: %v", ErrPanicRecovered, r)
+			}
+		}()
+		return fn()
+	}
+}
 
 // stringToBytes converts string to byte slice.
 func stringToBytes(s string) []byte {
@@ -5,71 +5,186 @@ import (
 	"fmt"
 	"math/rand"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
+const defaultTTLJitter = time.Second // 删除时间默认增加的最大随机值
+
 type wrapper[V any] struct {
-	l1       Cacher
-	l2       Cacher
-	cacheNil bool
-	delTTL   time.Duration
-	codec    Codec[V]
-	logger   Logger
+	l1              Cacher
+	l2              Cacher
+	cacheNil        bool
+	delTTL          time.Duration
+	codec           Codec[V]
+	logger          Logger
+	errHandler      CacheErrorHandlerFn // 读缓存错误处理
+	onSerErr        CodecErrorFn        // 序列化失败回调
+	onDeserErr      CodecErrorFn        // 反序列化失败回调
+	reloadOnCorrupt bool                // 反序列化失败时是否当作未命中
+	corruptMetrics  CodecErrorFn        // 反序列化失败当作未命中时的指标上报
+	dedupeBackfill  bool                // 是否合并同一个key并发的L1回填写入
+	schemaVersion   uint8               // 缓存值的schema版本，版本不一致的值当作未命中
+	ttlJitter       time.Duration       // 删除时间增加的最大随机值，0表示不增加
+	randInt63n      func(int64) int64   // 随机数来源，可通过WithJitterRand替换为固定序列
+	backfill        singleflight.Group  // L1回填singleflight
 }
 
 func newWrapper[V any](l1 Cacher, l2 Cacher, delTTL time.Duration, codec Codec[V], logger Logger) *wrapper[V] {
-	return &wrapper[V]{
-		l1:     l1,
-		l2:     l2,
-		delTTL: delTTL,
-		codec:  codec,
-		logger: logger,
-	}
+	w := &wrapper[V]{
+		l1:              l1,
+		l2:              l2,
+		delTTL:          delTTL,
+		codec:           codec,
+		logger:          logger,
+		reloadOnCorrupt: true,
+		dedupeBackfill:  true,
+		ttlJitter:       defaultTTLJitter,
+		randInt63n:      rand.Int63n,
+	}
+	return w
 }
 
-func (w *wrapper[V]) Get(ctx context.Context, key string) *entry[V] {
-	fromL1 := w.get(ctx, w.l1, key)
+// cacheErr 读缓存出错，未设置errHandler时默认打印日志并当作未命中处理
+func (w *wrapper[V]) cacheErr(ctx context.Context, op string, level int, fields map[string]interface{}, err error) bool {
+	if w.errHandler != nil {
+		return w.errHandler(ctx, op, err)
+	}
+	fields["op"] = op
+	fields["layer"] = layerName(level)
+	fields["error"] = err
+	logWarn(ctx, w.logger, fields, "cachex: cacher %s error: %v", op, err)
+	return true
+}
+
+func (w *wrapper[V]) Get(ctx context.Context, key string) (*entry[V], error) {
+	e, _, err := w.GetWithSource(ctx, key)
+	return e, err
+}
+
+// GetWithSource 返回的Source表示结果来自L1还是L2，都不存在时为SourceMiss
+func (w *wrapper[V]) GetWithSource(ctx context.Context, key string) (*entry[V], Source, error) {
+	fromL1, err := w.get(ctx, 1, key)
+	if err != nil {
+		return nil, SourceMiss, err
+	}
 	if fromL1 != nil && !fromL1.IsExpired() {
-		return fromL1
+		return fromL1, SourceL1, nil
+	}
+	// 读L1期间ctx已取消，不再读L2
+	if err := ctx.Err(); err != nil {
+		return nil, SourceMiss, err
+	}
+	fromL2, err := w.get(ctx, 2, key)
+	if err != nil {
+		return nil, SourceMiss, err
 	}
-	fromL2 := w.get(ctx, w.l2, key)
 	if fromL2 != nil && !fromL2.IsExpired() {
-		_ = w.set(ctx, w.l1, key, fromL2, w.getDelTTL(1))
-		return fromL2
+		w.backfillL1(ctx, key, fromL2)
+		return fromL2, SourceL2, nil
+	}
+	// 都已过期，返回较新的一个用于兜底
+	switch latest := w.latest(fromL1, fromL2); {
+	case latest == nil:
+		return nil, SourceMiss, nil
+	case latest == fromL1:
+		return latest, SourceL1, nil
+	default:
+		return latest, SourceL2, nil
+	}
+}
+
+// GetL2 跳过L1只读L2，L2命中时回填L1
+func (w *wrapper[V]) GetL2(ctx context.Context, key string) (*entry[V], error) {
+	fromL2, err := w.get(ctx, 2, key)
+	if err != nil {
+		return nil, err
+	}
+	if fromL2 != nil && !fromL2.IsExpired() {
+		w.backfillL1(ctx, key, fromL2)
+	}
+	return fromL2, nil
+}
+
+// backfillL1 L2命中后回填L1，开启合并时同一个key并发的回填只写入一次
+func (w *wrapper[V]) backfillL1(ctx context.Context, key string, val *entry[V]) {
+	if w.l1 == nil {
+		return
+	}
+	if !w.dedupeBackfill {
+		w.backfillFailed(ctx, "set", key, w.set(ctx, w.l1, key, val, w.getDelTTL(1)))
+		return
+	}
+	_, _, _ = w.backfill.Do(key, func() (interface{}, error) {
+		err := w.set(ctx, w.l1, key, val, w.getDelTTL(1))
+		w.backfillFailed(ctx, "set", key, err)
+		return nil, err
+	})
+}
+
+// backfillFailed 回填L1失败只打印日志，不影响本次读取的结果，批量回填时key为空
+func (w *wrapper[V]) backfillFailed(ctx context.Context, op string, key string, err error) {
+	if err != nil {
+		fields := map[string]interface{}{"op": op, "layer": layerName(1), "error": err}
+		if key != "" {
+			fields["key"] = key
+		}
+		logWarn(ctx, w.logger, fields, "cachex: backfill l1 %s error: %v", op, err)
 	}
-	return w.latest(fromL1, fromL2)
 }
 
-func (w *wrapper[V]) get(ctx context.Context, cacher Cacher, key string) *entry[V] {
+func (w *wrapper[V]) get(ctx context.Context, level int, key string) (*entry[V], error) {
+	cacher := w.cacher(level)
 	if cacher == nil {
-		return nil
+		return nil, nil
 	}
 	val, err := cacher.Get(ctx, key)
 	if err != nil {
-		w.logger.Warnf(ctx, "cachex: cacher get error: %v", err)
-		return nil
+		// ctx已取消，直接返回，不当作未命中继续读下一级缓存和回源
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if w.cacheErr(ctx, "get", level, map[string]interface{}{"key": key}, err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cachex: cacher get error: %w", err)
 	}
 	if val == nil {
-		return nil
+		return nil, nil
 	}
-	return deserializeEntry[V](val)
+	return w.decode(ctx, key, val)
 }
 
-func (w *wrapper[V]) MGet(ctx context.Context, keys []string) map[string]*entry[V] {
-	fromL1 := w.mGet(ctx, w.l1, keys)
+// MGet 关闭reloadOnCorrupt时，无法解析的key不再读下一级缓存，汇总为corruptKeysError与其余key的结果一起返回
+func (w *wrapper[V]) MGet(ctx context.Context, keys []string) (map[string]*entry[V], error) {
+	fromL1, corrupt, err := w.mGet(ctx, 1, keys)
+	if err != nil {
+		return nil, err
+	}
 	miss := make([]string, 0)
 	hit := make(map[string]*entry[V])
 	for _, key := range keys {
 		val := fromL1[key]
 		hit[key] = val
+		if _, ok := corrupt[key]; ok {
+			continue
+		}
 		if val == nil || val.IsExpired() {
 			miss = append(miss, key)
 		}
 	}
 	if len(miss) == 0 {
-		return hit
+		return hit, corrupt.orNil()
+	}
+	// 读L1期间ctx已取消，不再读L2
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	fromL2 := w.mGet(ctx, w.l2, miss)
+	fromL2, corruptL2, err := w.mGet(ctx, 2, miss)
+	if err != nil {
+		return nil, err
+	}
 	hitL2 := make(map[string]*entry[V])
 	for _, key := range keys {
 		val := fromL2[key]
@@ -78,27 +193,43 @@ func (w *wrapper[V]) MGet(ctx context.Context, keys []string) map[string]*entry[
 			hitL2[key] = val
 		}
 	}
-	_ = w.mSet(ctx, w.l1, hitL2, w.getDelTTL(1))
-	return hit
+	w.backfillFailed(ctx, "mset", "", w.mSet(ctx, w.l1, hitL2, w.getDelTTL(1)))
+	return hit, corrupt.merge(corruptL2).orNil()
 }
 
-func (w *wrapper[V]) mGet(ctx context.Context, cacher Cacher, keys []string) map[string]*entry[V] {
+// mGet 读取一级缓存，关闭reloadOnCorrupt时无法解析的key不影响其他key，单独返回
+func (w *wrapper[V]) mGet(ctx context.Context, level int, keys []string) (map[string]*entry[V], corruptKeysError, error) {
+	cacher := w.cacher(level)
 	data := make(map[string]*entry[V])
 	if cacher == nil {
-		return data
+		return data, nil, nil
 	}
 	kvs, err := cacher.MGet(ctx, keys)
 	if err != nil {
-		w.logger.Warnf(ctx, "cachex: cacher mget error: %v", err)
-		return data
+		// ctx已取消，直接返回，不当作未命中继续读下一级缓存和回源
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, ctxErr
+		}
+		if w.cacheErr(ctx, "mget", level, map[string]interface{}{"key_count": len(keys)}, err) {
+			return data, nil, nil
+		}
+		return nil, nil, fmt.Errorf("cachex: cacher mget error: %w", err)
 	}
+	var corrupt corruptKeysError
 	for k, v := range kvs {
 		if v == nil {
 			continue
 		}
-		data[k] = deserializeEntry[V](v)
+		e, err := w.decode(ctx, k, v)
+		if err != nil {
+			corrupt = corrupt.merge(corruptKeysError{k: err})
+			continue
+		}
+		if e != nil {
+			data[k] = e
+		}
 	}
-	return data
+	return data, corrupt, nil
 }
 
 func (w *wrapper[V]) Set(ctx context.Context, key string, val *entry[V]) error {
@@ -110,12 +241,50 @@ func (w *wrapper[V]) Set(ctx context.Context, key string, val *entry[V]) error {
 	return nil
 }
 
+// CompareAndSwap 以L2为准(未配置L2时为L1)比较并写入，写入成功后覆盖L1
+// match判断当前缓存的entry是否满足替换条件，缓存不存在、无法解析时不替换
+func (w *wrapper[V]) CompareAndSwap(ctx context.Context, key string, match func(cur *entry[V]) bool, val *entry[V]) (bool, error) {
+	cacher, level := w.l2, 2
+	if cacher == nil {
+		cacher, level = w.l1, 1
+	}
+	if cacher == nil {
+		return false, nil
+	}
+	old, err := cacher.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if old == nil {
+		return false, nil
+	}
+	cur, err := w.decode(ctx, key, old)
+	if err != nil || cur == nil {
+		return false, err
+	}
+	if !match(cur) {
+		return false, nil
+	}
+	bytes, err := w.serialize(val)
+	if err != nil {
+		w.serializeFailed(ctx, key, err)
+		return false, err
+	}
+	// 比较完整的序列化结果，读取后被其他写入修改时不替换
+	swapped, err := casCacher(ctx, cacher, key, old, bytes, w.getDelTTL(level))
+	if swapped && level == 2 {
+		w.backfillFailed(ctx, "set", key, w.set(ctx, w.l1, key, val, w.getDelTTL(1)))
+	}
+	return swapped, err
+}
+
 func (w *wrapper[V]) set(ctx context.Context, cacher Cacher, key string, val *entry[V], ttl time.Duration) error {
 	if cacher == nil || val == nil {
 		return nil
 	}
-	bytes, err := val.Serialize(w.codec)
+	bytes, err := w.serialize(val)
 	if err != nil {
+		w.serializeFailed(ctx, key, err)
 		return err
 	}
 	err = cacher.Set(ctx, key, bytes, ttl)
@@ -125,6 +294,58 @@ func (w *wrapper[V]) set(ctx context.Context, cacher Cacher, key string, val *en
 	return nil
 }
 
+// decode 反序列化缓存数据并校验value
+// 失败时默认当作未命中，关闭reloadOnCorrupt时返回错误
+func (w *wrapper[V]) decode(ctx context.Context, key string, bytes []byte) (*entry[V], error) {
+	e := deserializeEntry[V](bytes)
+	if e == nil {
+		return nil, w.deserializeFailed(ctx, key, ErrInvalidEntry)
+	}
+	// schema版本不一致，旧结构的值可能被错误解析，当作未命中重新回源
+	if e.version != w.schemaVersion {
+		return nil, nil
+	}
+	if _, err := e.Value(w.codec); err != nil {
+		return nil, w.deserializeFailed(ctx, key, err)
+	}
+	return e, nil
+}
+
+// newEntry 创建entry，使用当前的schema版本
+func (w *wrapper[V]) newEntry(val *V, ttl time.Duration) *entry[V] {
+	e := newEntry(val, ttl)
+	e.version = w.schemaVersion
+	return e
+}
+
+// serialize 序列化entry
+func (w *wrapper[V]) serialize(e *entry[V]) ([]byte, error) {
+	return e.Serialize(w.codec)
+}
+
+func (w *wrapper[V]) serializeFailed(ctx context.Context, key string, err error) {
+	fields := map[string]interface{}{"op": "serialize", "key": key, "error": err}
+	logWarn(ctx, w.logger, fields, "cachex: serialize error, key:%s, err:%v", key, err)
+	if w.onSerErr != nil {
+		w.onSerErr(ctx, key, err)
+	}
+}
+
+func (w *wrapper[V]) deserializeFailed(ctx context.Context, key string, err error) error {
+	fields := map[string]interface{}{"op": "deserialize", "key": key, "error": err}
+	logWarn(ctx, w.logger, fields, "cachex: deserialize error, key:%s, err:%v", key, err)
+	if w.onDeserErr != nil {
+		w.onDeserErr(ctx, key, err)
+	}
+	if w.reloadOnCorrupt {
+		if w.corruptMetrics != nil {
+			w.corruptMetrics(ctx, key, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("cachex: deserialize key %s error: %w", key, err)
+}
+
 func (w *wrapper[V]) MSet(ctx context.Context, kvs map[string]*entry[V]) error {
 	l2Err := w.mSet(ctx, w.l2, kvs, w.getDelTTL(2))
 	l1Err := w.mSet(ctx, w.l1, kvs, w.getDelTTL(1))
@@ -149,11 +370,13 @@ func (w *wrapper[V]) mSet(ctx context.Context, cacher Cacher, kvs map[string]*en
 		if v.IsNil() && !w.cacheNil {
 			continue
 		}
-		var err error
-		data[k], err = v.Serialize(w.codec)
+		bytes, err := w.serialize(v)
 		if err != nil {
-			return err
+			// 序列化失败的key跳过，不影响其他key写入
+			w.serializeFailed(ctx, k, err)
+			continue
 		}
+		data[k] = bytes
 	}
 	err := cacher.MSet(ctx, data, ttl)
 	if err != nil {
@@ -205,13 +428,40 @@ func (w *wrapper[V]) mDelete(ctx context.Context, cacher Cacher, keys []string)
 	return nil
 }
 
+// Ping 检查L1、L2是否可用，未配置或未实现HealthChecker的级别视为可用
+func (w *wrapper[V]) Ping(ctx context.Context) error {
+	if err := pingCacher(ctx, w.l1); err != nil {
+		return fmt.Errorf("cachex: l1 ping error: %w", err)
+	}
+	if err := pingCacher(ctx, w.l2); err != nil {
+		return fmt.Errorf("cachex: l2 ping error: %w", err)
+	}
+	return nil
+}
+
+// cacher 获取对应级别的缓存，未配置时为nil
+func (w *wrapper[V]) cacher(level int) Cacher {
+	if level == 1 {
+		return w.l1
+	}
+	return w.l2
+}
+
+// layerName 缓存级别的名称，用于日志字段
+func layerName(level int) string {
+	return fmt.Sprintf("l%d", level)
+}
+
 func (w *wrapper[V]) getDelTTL(level int) time.Duration {
 	if level != 1 && level != 2 {
 		// never reach here
 		panic("cachex: invalid level")
 	}
 	// 增加随机值防止集中过期
-	r := time.Duration(rand.Int63n(1000)) * time.Millisecond
+	var r time.Duration
+	if w.ttlJitter > 0 {
+		r = time.Duration(w.randInt63n(int64(w.ttlJitter)))
+	}
 	if level == 1 {
 		return w.delTTL + r
 	}
@@ -2,6 +2,7 @@ package cachex
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,7 +22,8 @@ func TestWrapper_Get(t *testing.T) {
 		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(mustSerialize(t, codec, fromL1), nil).Times(1)
 		l2 := NewMockCacher(ctrl)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Equal(t, gptr.Of("from_l1"), mustGetValue(t, codec, got))
 	})
 	t.Run("l1 expired, l2 miss", func(t *testing.T) {
@@ -35,7 +37,8 @@ func TestWrapper_Get(t *testing.T) {
 		l2 := NewMockCacher(ctrl)
 		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Equal(t, gptr.Of("from_l1"), mustGetValue(t, codec, got))
 		assert.True(t, got.IsExpired())
 	})
@@ -50,7 +53,8 @@ func TestWrapper_Get(t *testing.T) {
 		l2 := NewMockCacher(ctrl)
 		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(mustSerialize(t, codec, fromL2), nil).Times(1)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Equal(t, gptr.Of("from_l2"), mustGetValue(t, codec, got))
 	})
 	t.Run("l1 miss, l2 expired", func(t *testing.T) {
@@ -64,7 +68,8 @@ func TestWrapper_Get(t *testing.T) {
 		l2 := NewMockCacher(ctrl)
 		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(mustSerialize(t, codec, fromL2), nil).Times(1)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Equal(t, gptr.Of("from_l2"), mustGetValue(t, codec, got))
 		assert.True(t, got.IsExpired())
 	})
@@ -80,7 +85,8 @@ func TestWrapper_Get(t *testing.T) {
 		l2 := NewMockCacher(ctrl)
 		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(mustSerialize(t, codec, fromL2), nil).Times(1)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Equal(t, gptr.Of("from_l2"), mustGetValue(t, codec, got))
 		assert.True(t, got.IsExpired())
 	})
@@ -92,11 +98,64 @@ func TestWrapper_Get(t *testing.T) {
 		l2 := NewMockCacher(ctrl)
 		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		w := newWrapper[string](l1, l2, time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
-		got := w.Get(context.Background(), "test")
+		got, err := w.Get(context.Background(), "test")
+		assert.NoError(t, err)
 		assert.Nil(t, got)
 	})
 }
 
+func TestWrapper_GetDedupeBackfill(t *testing.T) {
+	const concurrency = 20
+	codec := NewCodecJsonSonic[string]()
+	fromL2 := mustSerialize(t, codec, newEntry(gptr.Of("from_l2"), time.Minute))
+
+	burst := func(w *wrapper[string]) {
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				got, err := w.Get(context.Background(), "test")
+				assert.NoError(t, err)
+				assert.Equal(t, gptr.Of("from_l2"), mustGetValue(t, codec, got))
+			}()
+		}
+		close(start)
+		wg.Wait()
+	}
+
+	t.Run("dedupe", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).Times(concurrency)
+		// 写入较慢，保证并发的回填都在第一次写入期间到达
+		l1.EXPECT().Set(gomock.Any(), "test", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+				time.Sleep(200 * time.Millisecond)
+				return nil
+			}).Times(1)
+		l2 := NewMockCacher(ctrl)
+		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(fromL2, nil).Times(concurrency)
+
+		burst(newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger()))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil).Times(concurrency)
+		l1.EXPECT().Set(gomock.Any(), "test", gomock.Any(), gomock.Any()).Return(nil).Times(concurrency)
+		l2 := NewMockCacher(ctrl)
+		l2.EXPECT().Get(gomock.Any(), gomock.Any()).Return(fromL2, nil).Times(concurrency)
+
+		w := newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger())
+		w.dedupeBackfill = false
+		burst(w)
+	})
+}
+
 func TestWrapper_MGet(t *testing.T) {
 	t.Run("l1 hit and expired, l2 hit all, hit all", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -120,7 +179,8 @@ func TestWrapper_MGet(t *testing.T) {
 		}, nil).Times(1)
 		w := newWrapper[string](l1, l2, 2*time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
 		keys := []string{"l1_hit", "l1_expired", "l2_hit_1", "l2_hit_2"}
-		fromCache := w.MGet(context.Background(), keys)
+		fromCache, err := w.MGet(context.Background(), keys)
+		assert.NoError(t, err)
 		got := gmap.MapValues(fromCache, func(v *entry[string]) *string {
 			return mustGetValue(t, codec, v)
 		})
@@ -150,7 +210,8 @@ func TestWrapper_MGet(t *testing.T) {
 		}, nil).Times(1)
 		w := newWrapper[string](l1, l2, 2*time.Minute, NewCodecJsonSonic[string](), newDefaultLogger())
 		keys := []string{"hit", "expired", "miss"}
-		fromCache := w.MGet(context.Background(), keys)
+		fromCache, err := w.MGet(context.Background(), keys)
+		assert.NoError(t, err)
 		got := gmap.MapValues(fromCache, func(v *entry[string]) *string {
 			return mustGetValue(t, codec, v)
 		})
@@ -163,6 +224,99 @@ func TestWrapper_MGet(t *testing.T) {
 	})
 }
 
+func TestWrapper_MGetCancel(t *testing.T) {
+	codec := NewCodecJsonSonic[string]()
+	l2Hit := mustSerialize(t, codec, newEntry(gptr.Of("from_l2"), time.Minute))
+
+	t.Run("cancelled during l1 mget", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+				cancel()
+				return nil, ctx.Err()
+			}).Times(1)
+		// 默认的errHandler会把错误当作未命中，ctx取消时不能继续读L2
+		l2 := NewMockCacher(ctrl)
+		w := newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger())
+
+		got, err := w.MGet(ctx, []string{"a", "b"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("cancelled after l1 mget", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+				cancel()
+				return map[string][]byte{}, nil
+			}).Times(1)
+		l2 := NewMockCacher(ctrl)
+		w := newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger())
+
+		got, err := w.MGet(ctx, []string{"a", "b"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("cancelled during l2 mget", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(map[string][]byte{}, nil).Times(1)
+		l2 := NewMockCacher(ctrl)
+		l2.EXPECT().MGet(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, keys []string) (map[string][]byte, error) {
+				cancel()
+				return nil, ctx.Err()
+			}).Times(1)
+		w := newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger())
+
+		got, err := w.MGet(ctx, []string{"a", "b"})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("cancelled during l1 get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().Get(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, key string) ([]byte, error) {
+				cancel()
+				return nil, ctx.Err()
+			}).Times(1)
+		l2 := NewMockCacher(ctrl)
+		w := newWrapper[string](l1, l2, time.Minute, codec, newDefaultLogger())
+
+		got, err := w.Get(ctx, "a")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("backfill error logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		logger := &recordLogger{}
+		l1 := NewMockCacher(ctrl)
+		l1.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(map[string][]byte{}, nil).Times(1)
+		l1.EXPECT().MSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(assert.AnError).Times(1)
+		l2 := NewMockCacher(ctrl)
+		l2.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(map[string][]byte{"a": l2Hit}, nil).Times(1)
+		w := newWrapper[string](l1, l2, time.Minute, codec, logger)
+
+		got, err := w.MGet(context.Background(), []string{"a", "b"})
+		assert.NoError(t, err)
+		assert.Equal(t, gptr.Of("from_l2"), mustGetValue(t, codec, got["a"]))
+		assert.Nil(t, got["b"])
+		assert.Len(t, logger.Warns(), 1)
+		assert.Contains(t, logger.Warns()[0], "backfill l1 mset error")
+	})
+}
+
 func mustSerialize[V any](t *testing.T, codec Codec[V], entry *entry[V]) []byte {
 	bytes, err := entry.Serialize(codec)
 	assert.NoError(t, err)
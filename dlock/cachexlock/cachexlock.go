@@ -0,0 +1,29 @@
+// Package cachexlock 将dlock.Locker适配为cachex的回源锁(cachex.LoaderLocker)
+// 通过方法签名匹配接口，dlock与cachex互不依赖
+package cachexlock
+
+import (
+	"context"
+	"time"
+
+	"github.com/kakkk/gopkg/dlock"
+)
+
+// LoaderLocker 实现cachex.LoaderLocker
+type LoaderLocker struct {
+	locker dlock.Locker
+}
+
+// New 使用dlock.Locker创建回源锁，如cachex.New[K, V]().WithLoaderLock(cachexlock.New(dlock.NewRedisLocker(cli)), ttl)
+func New(locker dlock.Locker) *LoaderLocker {
+	return &LoaderLocker{locker: locker}
+}
+
+// Acquire 最多等待ttl获取锁，ttl同时是锁的过期时间，返回释放锁的函数
+func (l *LoaderLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (func(ctx context.Context) error, error) {
+	lock, err := l.locker.AcquireWait(ctx, key, ttl, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return lock.Unlock, nil
+}
@@ -0,0 +1,51 @@
+package cachexlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/dlock"
+)
+
+// 与cachex.LoaderLocker的方法签名一致
+var _ interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (func(ctx context.Context) error, error)
+} = (*LoaderLocker)(nil)
+
+func TestLoaderLocker(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer cli.Close()
+
+	ctx := context.Background()
+	locker := New(dlock.NewRedisLocker(cli))
+
+	unlock, err := locker.Acquire(ctx, "loader-key", time.Second)
+	require.NoError(t, err)
+	assert.True(t, s.Exists("loader-key"))
+
+	// 锁被持有时最多等待ttl
+	start := time.Now()
+	_, err = locker.Acquire(ctx, "loader-key", 50*time.Millisecond)
+	assert.ErrorIs(t, err, dlock.ErrLockNotAcquired)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// 释放后等待者获取锁
+	done := make(chan error, 1)
+	go func() {
+		unlock2, err := locker.Acquire(ctx, "loader-key", time.Second)
+		if err == nil {
+			err = unlock2(ctx)
+		}
+		done <- err
+	}()
+	require.NoError(t, unlock(ctx))
+	require.NoError(t, <-done)
+	assert.False(t, s.Exists("loader-key"))
+}
@@ -1,12 +1,24 @@
 package dlock
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	minWaitInterval = 10 * time.Millisecond  // AcquireWait初始重试间隔
+	maxWaitInterval = 100 * time.Millisecond // AcquireWait最大重试间隔
+)
+
 func validateKeyAndTTL(key string, ttl time.Duration) error {
 	if strings.TrimSpace(key) == "" {
 		return ErrInvalidKey
@@ -17,6 +29,107 @@ func validateKeyAndTTL(key string, ttl time.Duration) error {
 	return nil
 }
 
+// recoverError 将后台goroutine中recover得到的panic转为error，包含调用栈
+func recoverError(r interface{}) error {
+	return fmt.Errorf("dlock: panic recovered: %v, stack:\n%s", r, debug.Stack())
+}
+
 func lockValue() string {
 	return uuid.New().String()
 }
+
+// lockOwner 锁持有者的元数据，用于排查长时间未释放的锁
+type lockOwner struct {
+	Value      string    `json:"value"` // 锁的值(UUID)，释放、续期时只比较该字段
+	Host       string    `json:"host"`
+	PID        int       `json:"pid"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+}
+
+func newLockOwner(value string) lockOwner {
+	host, _ := os.Hostname()
+	return lockOwner{
+		Value:      value,
+		Host:       host,
+		PID:        os.Getpid(),
+		AcquiredAt: time.Now(),
+	}
+}
+
+// String 持有者标识，格式为"host:pid"
+func (o lockOwner) String() string {
+	return fmt.Sprintf("%s:%d", o.Host, o.PID)
+}
+
+// JSON redis中存储的锁的值
+func (o lockOwner) JSON() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+// acquireWait 持续尝试获取锁直到成功、超过maxWait或ctx结束，重试间隔指数增长
+// 只在锁被占用(ErrLockAlreadyHeld)时重试，后端错误等其他错误立即返回
+// wake收到消息时立即重试，为nil时只按间隔重试
+func acquireWait[T any](ctx context.Context, maxWait time.Duration, wake <-chan T, acquire func(ctx context.Context) (Lock, error)) (Lock, error) {
+	deadline := time.Now().Add(maxWait)
+	interval := minWaitInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		lock, err := acquire(ctx)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, ErrLockAlreadyHeld) {
+			return nil, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrLockNotAcquired
+		}
+
+		// 等待后重试
+		select {
+		case <-time.After(min(interval, remaining)):
+			interval = min(interval*2, maxWaitInterval)
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// acquireMulti 按key排序后依次获取锁，保证多个调用方加锁顺序一致，避免死锁
+// 任意一个获取失败时释放已获取的锁并返回错误，要么全部获取成功，要么都不持有
+func acquireMulti(ctx context.Context, keys []string, ttl time.Duration, acquire func(ctx context.Context, key string, ttl time.Duration) (Lock, error)) ([]Lock, error) {
+	for _, key := range keys {
+		if err := validateKeyAndTTL(key, ttl); err != nil {
+			return nil, err
+		}
+	}
+	// 排序并去重，重复的key只加一次锁
+	sorted := slices.Compact(slices.Sorted(slices.Values(keys)))
+
+	locks := make([]Lock, 0, len(sorted))
+	for _, key := range sorted {
+		lock, err := acquire(ctx, key, ttl)
+		if err != nil {
+			releaseLocks(context.WithoutCancel(ctx), locks)
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// releaseLocks 逆序释放锁，忽略释放错误(锁最终会过期)
+func releaseLocks(ctx context.Context, locks []Lock) {
+	for i := len(locks) - 1; i >= 0; i-- {
+		_ = locks[i].Unlock(ctx)
+	}
+}
@@ -0,0 +1,82 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAcquireMulti 两种实现共用的AcquireMulti测试
+func testAcquireMulti(t *testing.T, locker Locker) {
+	ctx := context.Background()
+
+	t.Run("all or nothing", func(t *testing.T) {
+		locks, err := locker.AcquireMulti(ctx, []string{"multi-b", "multi-a", "multi-b"}, 10*time.Second)
+		require.NoError(t, err)
+		require.Len(t, locks, 2)
+		assert.Equal(t, "multi-a", locks[0].Key())
+		assert.Equal(t, "multi-b", locks[1].Key())
+
+		// 全部持有中
+		_, err = locker.Acquire(ctx, "multi-a", 10*time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		_, err = locker.Acquire(ctx, "multi-b", 10*time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+
+		releaseLocks(ctx, locks)
+		lock, err := locker.Acquire(ctx, "multi-a", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("release on partial failure", func(t *testing.T) {
+		held, err := locker.Acquire(ctx, "multi-partial-c", 10*time.Second)
+		require.NoError(t, err)
+
+		_, err = locker.AcquireMulti(ctx, []string{"multi-partial-a", "multi-partial-b", "multi-partial-c"}, 10*time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+
+		// 失败前已获取的锁被释放
+		locks, err := locker.AcquireMulti(ctx, []string{"multi-partial-a", "multi-partial-b"}, 10*time.Second)
+		require.NoError(t, err)
+		releaseLocks(ctx, locks)
+		require.NoError(t, held.Unlock(ctx))
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		_, err := locker.AcquireMulti(ctx, []string{"multi-x", ""}, 10*time.Second)
+		assert.Equal(t, ErrInvalidKey, err)
+		_, err = locker.AcquireMulti(ctx, []string{"multi-x"}, 0)
+		assert.Equal(t, ErrInvalidTTL, err)
+	})
+
+	t.Run("sorted order prevents deadlock", func(t *testing.T) {
+		// 两个goroutine以相反顺序传入key，排序后都先抢同一个key，每轮至少有一个成功
+		for i := 0; i < 20; i++ {
+			var wg sync.WaitGroup
+			results := make([]error, 2)
+			order := [][]string{{"multi-x", "multi-y"}, {"multi-y", "multi-x"}}
+			start := make(chan struct{})
+			for j := 0; j < 2; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					<-start
+					locks, err := locker.AcquireMulti(ctx, order[j], 10*time.Second)
+					results[j] = err
+					if err == nil {
+						time.Sleep(time.Millisecond)
+						releaseLocks(ctx, locks)
+					}
+				}(j)
+			}
+			close(start)
+			wg.Wait()
+			assert.True(t, results[0] == nil || results[1] == nil, "round %d: %v", i, results)
+		}
+	})
+}
@@ -2,12 +2,76 @@ package dlock
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const defaultJanitorInterval = time.Minute
+
+// Columns 锁表的列名，为空的字段使用默认列名
+type Columns struct {
+	Key        string // 锁的key，默认: lock_key
+	Value      string // 锁的值，默认: lock_value
+	ExpireTime string // 过期时间，默认: expire_time
+	CreatedAt  string // 创建时间，默认: created_at
+	UpdatedAt  string // 更新时间，默认: updated_at
+	Owner      string // 持有者信息("host:pid")，默认为空，不存储；表中需要有对应的列
+}
+
+func defaultColumns() Columns {
+	return Columns{
+		Key:        "lock_key",
+		Value:      "lock_value",
+		ExpireTime: "expire_time",
+		CreatedAt:  "created_at",
+		UpdatedAt:  "updated_at",
+	}
+}
+
+// DatabaseLockerOption 数据库锁配置选项
+type DatabaseLockerOption func(*dbLocker)
+
+// JanitorErrorFn 后台清理过期锁失败(锁表不存在、数据库不可用等)或panic时的回调
+type JanitorErrorFn func(ctx context.Context, err error)
+
+// WithJanitorErrorHandler 设置后台清理过期锁失败时的回调，用于打印日志或上报监控
+// 未设置时使用标准库log输出
+func WithJanitorErrorHandler(fn JanitorErrorFn) DatabaseLockerOption {
+	return func(ml *dbLocker) {
+		ml.janitorErrFn = fn
+	}
+}
+
+// WithColumns 设置锁表的列名，用于复用已有的表结构
+func WithColumns(columns Columns) DatabaseLockerOption {
+	return func(ml *dbLocker) {
+		if columns.Key != "" {
+			ml.columns.Key = columns.Key
+		}
+		if columns.Value != "" {
+			ml.columns.Value = columns.Value
+		}
+		if columns.ExpireTime != "" {
+			ml.columns.ExpireTime = columns.ExpireTime
+		}
+		if columns.CreatedAt != "" {
+			ml.columns.CreatedAt = columns.CreatedAt
+		}
+		if columns.UpdatedAt != "" {
+			ml.columns.UpdatedAt = columns.UpdatedAt
+		}
+		if columns.Owner != "" {
+			ml.columns.Owner = columns.Owner
+		}
+	}
+}
+
 type lockModel struct {
 	ID         uint      `gorm:"column:id"`
 	LockKey    string    `gorm:"column:lock_key"`
@@ -15,6 +79,7 @@ type lockModel struct {
 	ExpireTime time.Time `gorm:"column:expire_time"`
 	CreatedAt  time.Time `gorm:"column:created_at"`
 	UpdatedAt  time.Time `gorm:"column:updated_at"`
+	Owner      string    `gorm:"column:owner;->"` // 只读，ListLocks按配置的列名查询后映射
 }
 
 type dbLock struct {
@@ -22,11 +87,13 @@ type dbLock struct {
 	lockKey   string
 	lockValue string
 	tableName string
+	columns   Columns
 	mu        sync.Mutex
 	unlocked  bool // 标记是否已释放
 }
 
 // Unlock 释放锁
+// 锁已被其他持有者重新获取时返回ErrLockNotHeld，锁已过期且被清理、无人持有时视为释放成功
 func (li *dbLock) Unlock(ctx context.Context) error {
 	li.mu.Lock()
 	defer li.mu.Unlock()
@@ -36,34 +103,89 @@ func (li *dbLock) Unlock(ctx context.Context) error {
 
 	// 删除锁记录（只有锁的持有者才能删除）
 	result := li.db.WithContext(ctx).Table(li.tableName).
-		Where("lock_key = ? AND lock_value = ?", li.lockKey, li.lockValue).
+		Where(clause.Eq{Column: clause.Column{Name: li.columns.Key}, Value: li.lockKey}).
+		Where(clause.Eq{Column: clause.Column{Name: li.columns.Value}, Value: li.lockValue}).
 		Delete(&lockModel{})
 
 	if result.Error != nil {
-		return result.Error
+		return fmt.Errorf("database error: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return ErrLockNotHeld
+		// 未删除到记录，区分锁已被他人重新获取和锁已不存在（过期后被清理）
+		var count int64
+		err := li.db.WithContext(ctx).Table(li.tableName).
+			Where(clause.Eq{Column: clause.Column{Name: li.columns.Key}, Value: li.lockKey}).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if count > 0 {
+			return ErrLockNotHeld
+		}
 	}
 
 	li.unlocked = true
 	return nil
 }
 
+func (li *dbLock) Key() string {
+	return li.lockKey
+}
+
+func (li *dbLock) Value() string {
+	return li.lockValue
+}
+
+// Refresh 续期，只更新未过期且属于自己的锁记录
+func (li *dbLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	if li.unlocked {
+		return ErrLockNotHeld
+	}
+
+	now := time.Now()
+	result := li.db.WithContext(ctx).Table(li.tableName).
+		Where(clause.Eq{Column: clause.Column{Name: li.columns.Key}, Value: li.lockKey}).
+		Where(clause.Eq{Column: clause.Column{Name: li.columns.Value}, Value: li.lockValue}).
+		Where(clause.Gte{Column: clause.Column{Name: li.columns.ExpireTime}, Value: now}).
+		Updates(map[string]interface{}{
+			li.columns.ExpireTime: now.Add(ttl),
+			li.columns.UpdatedAt:  now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
 type dbLocker struct {
-	db        *gorm.DB
-	tableName string
+	db           *gorm.DB
+	tableName    string
+	columns      Columns
+	janitorErrFn JanitorErrorFn // 后台清理失败时的回调，为nil时使用标准库log输出
 }
 
-func newDatabaseLocker(db *gorm.DB, table string) *dbLocker {
+func newDatabaseLocker(db *gorm.DB, table string, opts ...DatabaseLockerOption) *dbLocker {
 	if table == "" {
 		table = "distributed_lock"
 	}
 
-	return &dbLocker{
+	ml := &dbLocker{
 		db:        db,
 		tableName: table,
+		columns:   defaultColumns(),
+	}
+	for _, opt := range opts {
+		opt(ml)
 	}
+	return ml
 }
 
 // Acquire 获取锁
@@ -80,17 +202,21 @@ func (ml *dbLocker) Acquire(ctx context.Context, key string, ttl time.Duration)
 	ml.cleanExpiredLock(ctx, key)
 
 	// 尝试插入锁记录
-	expireTime := time.Now().Add(ttl)
-	lock := &lockModel{
-		LockKey:    key,
-		LockValue:  value,
-		ExpireTime: expireTime,
+	now := time.Now()
+	lock := map[string]interface{}{
+		ml.columns.Key:        key,
+		ml.columns.Value:      value,
+		ml.columns.ExpireTime: now.Add(ttl),
+		ml.columns.CreatedAt:  now,
+		ml.columns.UpdatedAt:  now,
+	}
+	if ml.columns.Owner != "" {
+		lock[ml.columns.Owner] = newLockOwner(value).String()
 	}
 
 	err := ml.db.WithContext(ctx).Table(ml.tableName).Create(lock).Error
 	if err != nil {
-		// 插入失败，锁已被占用
-		return nil, ErrLockAlreadyHeld
+		return nil, ml.acquireError(ctx, key, err)
 	}
 
 	// 创建锁实例
@@ -99,11 +225,28 @@ func (ml *dbLocker) Acquire(ctx context.Context, key string, ttl time.Duration)
 		lockKey:   key,
 		lockValue: value,
 		tableName: ml.tableName,
+		columns:   ml.columns,
 	}
 
 	return instance, nil
 }
 
+// acquireError 区分插入失败的原因，唯一索引冲突返回ErrLockAlreadyHeld，其他错误包装后返回
+// 各数据库的唯一索引冲突错误不同，未开启gorm的TranslateError时查询锁记录是否存在来判断
+func (ml *dbLocker) acquireError(ctx context.Context, key string, err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrLockAlreadyHeld
+	}
+	var count int64
+	cntErr := ml.db.WithContext(ctx).Table(ml.tableName).
+		Where(clause.Eq{Column: clause.Column{Name: ml.columns.Key}, Value: key}).
+		Count(&count).Error
+	if cntErr == nil && count > 0 {
+		return ErrLockAlreadyHeld
+	}
+	return fmt.Errorf("database error: %w", err)
+}
+
 // AcquireWithRetry 带重试获取锁
 func (ml *dbLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (Lock, error) {
 	// 参数校验
@@ -138,9 +281,121 @@ func (ml *dbLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.D
 	return nil, ErrLockNotAcquired
 }
 
+// AcquireWait 持续尝试获取锁，最多等待maxWait
+func (ml *dbLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+
+	return acquireWait(ctx, maxWait, (<-chan struct{})(nil), func(ctx context.Context) (Lock, error) {
+		return ml.Acquire(ctx, key, ttl)
+	})
+}
+
+func (ml *dbLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error) {
+	return acquireMulti(ctx, keys, ttl, ml.Acquire)
+}
+
+// Ping 查询锁表检查数据库连接，同时可发现锁表不存在
+func (ml *dbLocker) Ping(ctx context.Context) error {
+	var ones []int
+	if err := ml.db.WithContext(ctx).Table(ml.tableName).Select("1").Limit(1).Scan(&ones).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+// ListLocks 列出当前的锁，includeExpired为false时过滤已过期的锁
+func (ml *dbLocker) ListLocks(ctx context.Context, includeExpired bool) ([]LockInfo, error) {
+	// 按配置的列名查询，映射到lockModel的字段
+	selects := "? AS lock_key, ? AS lock_value, ? AS expire_time, ? AS created_at"
+	args := []interface{}{
+		clause.Column{Name: ml.columns.Key},
+		clause.Column{Name: ml.columns.Value},
+		clause.Column{Name: ml.columns.ExpireTime},
+		clause.Column{Name: ml.columns.CreatedAt},
+	}
+	if ml.columns.Owner != "" {
+		selects += ", ? AS owner"
+		args = append(args, clause.Column{Name: ml.columns.Owner})
+	}
+	query := ml.db.WithContext(ctx).Table(ml.tableName).Select(selects, args...)
+	if !includeExpired {
+		query = query.Where(clause.Gte{Column: clause.Column{Name: ml.columns.ExpireTime}, Value: time.Now()})
+	}
+
+	var records []lockModel
+	err := query.Order(clause.OrderByColumn{Column: clause.Column{Name: ml.columns.Key}}).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	locks := make([]LockInfo, 0, len(records))
+	for _, record := range records {
+		locks = append(locks, LockInfo{
+			Key:        record.LockKey,
+			Value:      record.LockValue,
+			ExpireTime: record.ExpireTime,
+			CreatedAt:  record.CreatedAt,
+			Owner:      record.Owner,
+		})
+	}
+	return locks, nil
+}
+
+// StartJanitor 后台定期清理所有过期的锁记录，ctx取消时停止
+// 可选：Acquire只会清理当前key的过期记录，不会再被获取的key的记录需要由janitor清理
+func (ml *dbLocker) StartJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultJanitorInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ml.safePurge(ctx); err != nil && ctx.Err() == nil {
+					ml.janitorFailed(ctx, err)
+				}
+			}
+		}
+	}()
+}
+
+// safePurge 清理过期锁，panic时转为error，不影响下一次清理
+func (ml *dbLocker) safePurge(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverError(r)
+		}
+	}()
+	return ml.purgeExpiredLocks(ctx)
+}
+
+// janitorFailed 后台清理失败，锁表不存在或数据库不可用时不能静默忽略
+func (ml *dbLocker) janitorFailed(ctx context.Context, err error) {
+	if ml.janitorErrFn != nil {
+		ml.janitorErrFn(ctx, err)
+		return
+	}
+	log.Printf("dlock: purge expired locks from %s error: %v", ml.tableName, err)
+}
+
+// 清理所有过期锁
+func (ml *dbLocker) purgeExpiredLocks(ctx context.Context) error {
+	return ml.db.WithContext(ctx).Table(ml.tableName).
+		Where(clause.Lt{Column: clause.Column{Name: ml.columns.ExpireTime}, Value: time.Now()}).
+		Delete(&lockModel{}).Error
+}
+
 // 清理过期锁
 func (ml *dbLocker) cleanExpiredLock(ctx context.Context, key string) error {
 	return ml.db.WithContext(ctx).Table(ml.tableName).
-		Where("lock_key = ? AND expire_time < ?", key, time.Now()).
+		Where(clause.Eq{Column: clause.Column{Name: ml.columns.Key}, Value: key}).
+		Where(clause.Lt{Column: clause.Column{Name: ml.columns.ExpireTime}, Value: time.Now()}).
 		Delete(&lockModel{}).Error
 }
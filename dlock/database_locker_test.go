@@ -3,10 +3,12 @@ package dlock
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
@@ -168,6 +170,42 @@ func TestDBLock(t *testing.T) {
 		db.Table("distributed_lock").Where("lock_key = ?", "test-key-5").Delete(&lockModel{})
 	})
 
+	t.Run("TestUnlockAfterReclaimed", func(t *testing.T) {
+		locker := newDatabaseLocker(db, "distributed_lock")
+
+		lock1, err := locker.Acquire(ctx, "reclaim-key", 100*time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		// 过期后被其他持有者重新获取
+		lock2, err := locker.Acquire(ctx, "reclaim-key", 10*time.Second)
+		require.NoError(t, err)
+
+		err = lock1.Unlock(ctx)
+		assert.Equal(t, ErrLockNotHeld, err)
+
+		// 新持有者的锁不受影响
+		var count int64
+		err = db.Table("distributed_lock").Where("lock_key = ?", "reclaim-key").Count(&count).Error
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		require.NoError(t, lock2.Unlock(ctx))
+	})
+
+	t.Run("TestUnlockAfterExpiredWithoutReclaim", func(t *testing.T) {
+		locker := newDatabaseLocker(db, "distributed_lock")
+
+		lock, err := locker.Acquire(ctx, "expired-gone-key", 100*time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		// 模拟过期记录被清理，无人重新获取
+		require.NoError(t, locker.cleanExpiredLock(ctx, "expired-gone-key"))
+
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
 	t.Run("TestExpiredLockCleanup", func(t *testing.T) {
 		locker := newDatabaseLocker(db, "distributed_lock")
 
@@ -478,6 +516,72 @@ func TestDBLock(t *testing.T) {
 		assert.Equal(t, 1, successCount, "应该只有一个goroutine能成功获取锁")
 		assert.Equal(t, 9, errorCount, "应该有9个goroutine获取锁失败")
 	})
+
+	t.Run("TestLockKeyValue", func(t *testing.T) {
+		locker := newDatabaseLocker(db, "distributed_lock")
+		lock, err := locker.Acquire(ctx, "key-value-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "key-value-key", lock.Key())
+
+		// 验证value与数据库中存储的一致
+		var lockRecord lockModel
+		err = db.Table("distributed_lock").Where("lock_key = ?", "key-value-key").First(&lockRecord).Error
+		require.NoError(t, err)
+		assert.Equal(t, lockRecord.LockValue, lock.Value())
+		_, err = uuid.Parse(lock.Value())
+		assert.NoError(t, err)
+
+		err = lock.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestAcquireWait", func(t *testing.T) {
+		locker := newDatabaseLocker(db, "distributed_lock")
+
+		// 持有锁，100ms后释放
+		lock1, err := locker.Acquire(ctx, "wait-key", 10*time.Second)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = lock1.Unlock(ctx)
+		}()
+
+		// 等待窗口内释放，可以获取到锁
+		start := time.Now()
+		lock2, err := locker.AcquireWait(ctx, "wait-key", 10*time.Second, 2*time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, lock2)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// 等待窗口内未释放，超时
+		start = time.Now()
+		_, err = locker.AcquireWait(ctx, "wait-key", 10*time.Second, 200*time.Millisecond)
+		assert.Equal(t, ErrLockNotAcquired, err)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+		// ctx取消
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = locker.AcquireWait(cctx, "wait-key", 10*time.Second, 2*time.Second)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		err = lock2.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestAcquireWaitBackendError", func(t *testing.T) {
+		// 锁表不存在，后端错误不重试，立即返回
+		locker := newDatabaseLocker(db, "missing_lock_table")
+		start := time.Now()
+		_, err := locker.AcquireWait(ctx, "wait-key", 10*time.Second, 2*time.Second)
+		assert.ErrorContains(t, err, "database error")
+		assert.NotErrorIs(t, err, ErrLockNotAcquired)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("TestAcquireMulti", func(t *testing.T) {
+		testAcquireMulti(t, newDatabaseLocker(db, "distributed_lock"))
+	})
 }
 
 // TestDBLockEdgeCases 测试边界情况
@@ -582,3 +686,394 @@ func TestDBLockEdgeCases(t *testing.T) {
 		lock2.Unlock(ctx)
 	})
 }
+
+// TestDBLockList 测试列出锁
+func TestDBLockList(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	// 使用独立的表，避免受其他测试影响
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_list (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	locker := NewDatabaseLocker(db, "distributed_lock_list")
+
+	lock1, err := locker.Acquire(ctx, "list-key-1", 10*time.Second)
+	require.NoError(t, err)
+	lock2, err := locker.Acquire(ctx, "list-key-2", 10*time.Second)
+	require.NoError(t, err)
+	expired, err := locker.Acquire(ctx, "list-key-expired", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("TestExcludeExpired", func(t *testing.T) {
+		locks, err := locker.ListLocks(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, locks, 2)
+		assert.Equal(t, lock1.Key(), locks[0].Key)
+		assert.Equal(t, lock1.Value(), locks[0].Value)
+		assert.Equal(t, lock2.Key(), locks[1].Key)
+		assert.Equal(t, lock2.Value(), locks[1].Value)
+		assert.True(t, locks[0].ExpireTime.After(time.Now()))
+		assert.False(t, locks[0].CreatedAt.IsZero())
+	})
+
+	t.Run("TestIncludeExpired", func(t *testing.T) {
+		locks, err := locker.ListLocks(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, locks, 3)
+		assert.Equal(t, expired.Key(), locks[2].Key)
+		assert.True(t, locks[2].ExpireTime.Before(time.Now()))
+	})
+
+	require.NoError(t, lock1.Unlock(ctx))
+	require.NoError(t, lock2.Unlock(ctx))
+	require.NoError(t, expired.Unlock(ctx))
+}
+
+// TestDBLockJanitor 测试后台清理过期锁
+func TestDBLockJanitor(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	// 使用独立的表，避免受其他测试影响
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_janitor (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	locker := NewDatabaseLocker(db, "distributed_lock_janitor")
+
+	// 插入多个过期锁和一个未过期锁
+	for i := 0; i < 5; i++ {
+		_, err := locker.Acquire(ctx, fmt.Sprintf("janitor-expired-%d", i), time.Millisecond)
+		require.NoError(t, err)
+	}
+	lock, err := locker.Acquire(ctx, "janitor-alive", 10*time.Second)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	locker.StartJanitor(ctx, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		locks, err := locker.ListLocks(ctx, true)
+		return err == nil && len(locks) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	locks, err := locker.ListLocks(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	assert.Equal(t, "janitor-alive", locks[0].Key)
+
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+// TestDBLockJanitorError 清理失败时回调错误，不静默忽略
+func TestDBLockJanitorError(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	locker := NewDatabaseLocker(db, "distributed_lock_janitor_not_exist", WithJanitorErrorHandler(func(ctx context.Context, err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	}))
+	locker.StartJanitor(ctx, 10*time.Millisecond)
+
+	select {
+	case err := <-errCh:
+		assert.ErrorContains(t, err, "distributed_lock_janitor_not_exist")
+	case <-time.After(time.Second):
+		t.Fatal("janitor error not reported")
+	}
+}
+
+func TestDBLockCustomColumns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	// 使用自定义列名的表
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_columns (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			k TEXT NOT NULL UNIQUE,
+			v TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			ctime DATETIME NOT NULL,
+			mtime DATETIME NOT NULL
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	locker := NewDatabaseLocker(db, "distributed_lock_columns", WithColumns(Columns{
+		Key:        "k",
+		Value:      "v",
+		ExpireTime: "expires_at",
+		CreatedAt:  "ctime",
+		UpdatedAt:  "mtime",
+	}))
+
+	t.Run("TestAcquireAndUnlock", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "columns-key", 10*time.Second)
+		require.NoError(t, err)
+
+		var count int64
+		err = db.Table("distributed_lock_columns").Where("k = ? AND v = ?", lock.Key(), lock.Value()).Count(&count).Error
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		_, err = locker.Acquire(ctx, "columns-key", 10*time.Second)
+		assert.ErrorIs(t, err, ErrLockAlreadyHeld)
+
+		locks, err := locker.ListLocks(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		assert.Equal(t, lock.Key(), locks[0].Key)
+		assert.Equal(t, lock.Value(), locks[0].Value)
+		assert.False(t, locks[0].CreatedAt.IsZero())
+
+		require.NoError(t, lock.Unlock(ctx))
+		err = db.Table("distributed_lock_columns").Where("k = ?", "columns-key").Count(&count).Error
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("TestExpiredLockReacquire", func(t *testing.T) {
+		_, err := locker.Acquire(ctx, "columns-expired", time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		lock, err := locker.Acquire(ctx, "columns-expired", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+}
+
+// TestDBLockOwner 测试存储持有者信息
+func TestDBLockOwner(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_owner (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			lock_owner TEXT NOT NULL DEFAULT ''
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	wantOwner := fmt.Sprintf("%s:%d", host, os.Getpid())
+
+	t.Run("TestOwnerPersisted", func(t *testing.T) {
+		locker := NewDatabaseLocker(db, "distributed_lock_owner", WithColumns(Columns{Owner: "lock_owner"}))
+		lock, err := locker.Acquire(ctx, "owner-key", 10*time.Second)
+		require.NoError(t, err)
+
+		var owner string
+		err = db.Table("distributed_lock_owner").Select("lock_owner").Where("lock_key = ?", lock.Key()).Scan(&owner).Error
+		require.NoError(t, err)
+		assert.Equal(t, wantOwner, owner)
+
+		locks, err := locker.ListLocks(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		assert.Equal(t, wantOwner, locks[0].Owner)
+
+		require.NoError(t, RefreshLock(ctx, lock, 10*time.Second))
+		require.NoError(t, lock.Unlock(ctx))
+		locks, err = locker.ListLocks(ctx, true)
+		require.NoError(t, err)
+		assert.Empty(t, locks)
+	})
+
+	t.Run("TestOwnerDisabled", func(t *testing.T) {
+		locker := NewDatabaseLocker(db, "distributed_lock_owner")
+		lock, err := locker.Acquire(ctx, "owner-disabled", 10*time.Second)
+		require.NoError(t, err)
+
+		locks, err := locker.ListLocks(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+		assert.Empty(t, locks[0].Owner)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+}
+
+// TestDBLockPing 测试数据库连通性检查
+func TestDBLockPing(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:dlock_ping?mode=memory"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_ping (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("TestHealthy", func(t *testing.T) {
+		locker := NewDatabaseLocker(db, "distributed_lock_ping")
+		assert.NoError(t, locker.Ping(ctx))
+	})
+
+	t.Run("TestTableNotExist", func(t *testing.T) {
+		locker := NewDatabaseLocker(db, "distributed_lock_not_exist")
+		assert.ErrorContains(t, locker.Ping(ctx), "database error")
+	})
+
+	t.Run("TestUnreachable", func(t *testing.T) {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+
+		locker := NewDatabaseLocker(db, "distributed_lock_ping")
+		assert.Error(t, locker.Ping(ctx))
+	})
+}
+
+// TestDBLockBackendError 测试数据库错误与锁被占用区分
+func TestDBLockBackendError(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:dlock_backend?mode=memory"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_backend (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	locker := NewDatabaseLocker(db, "distributed_lock_backend")
+
+	t.Run("TestLockHeld", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "backend-key", 10*time.Second)
+		require.NoError(t, err)
+		defer lock.Unlock(ctx)
+
+		_, err = locker.Acquire(ctx, "backend-key", 10*time.Second)
+		assert.ErrorIs(t, err, ErrLockAlreadyHeld)
+	})
+
+	t.Run("TestTableNotExist", func(t *testing.T) {
+		locker := NewDatabaseLocker(db, "distributed_lock_not_exist")
+		_, err := locker.Acquire(ctx, "backend-key", 10*time.Second)
+		assert.ErrorContains(t, err, "database error")
+		assert.NotErrorIs(t, err, ErrLockAlreadyHeld)
+	})
+
+	t.Run("TestUnreachable", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "backend-key-1", 10*time.Second)
+		require.NoError(t, err)
+
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+
+		_, err = locker.Acquire(ctx, "backend-key-2", 10*time.Second)
+		assert.ErrorContains(t, err, "database error")
+		assert.NotErrorIs(t, err, ErrLockAlreadyHeld)
+
+		assert.ErrorContains(t, RefreshLock(ctx, lock, 10*time.Second), "database error")
+		assert.ErrorContains(t, lock.Unlock(ctx), "database error")
+		assert.ErrorContains(t, locker.Ping(ctx), "database error")
+		_, err = locker.ListLocks(ctx, true)
+		assert.ErrorContains(t, err, "database error")
+	})
+}
+
+// BenchmarkDBAcquireRelease 无竞争时加锁、释放锁的吞吐
+func BenchmarkDBAcquireRelease(b *testing.B) {
+	db, err := gorm.Open(sqlite.Open("file:dlock_bench?mode=memory&cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(b, err)
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(b, err)
+
+	ctx := context.Background()
+	locker := newDatabaseLocker(db, "distributed_lock")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lock, err := locker.Acquire(ctx, "bench-key", 10*time.Second)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := lock.Unlock(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
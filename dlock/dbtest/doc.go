@@ -0,0 +1,4 @@
+// Package dbtest 使用MySQL、PostgreSQL驱动测试dlock的数据库锁
+// 单独作为一个模块，避免dlock为了测试依赖数据库驱动
+// 连接真实数据库的测试通过DLOCK_TEST_MYSQL_DSN、DLOCK_TEST_POSTGRES_DSN指定数据库，未设置时跳过
+package dbtest
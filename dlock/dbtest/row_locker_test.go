@@ -0,0 +1,228 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/kakkk/gopkg/dlock"
+)
+
+// dryRunPool DryRun模式下不执行语句，只用于开启、结束事务
+type dryRunPool struct{}
+
+var errDryRun = errors.New("dry run")
+
+func (*dryRunPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errDryRun
+}
+
+func (*dryRunPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errDryRun
+}
+
+func (*dryRunPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errDryRun
+}
+
+func (*dryRunPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunPool) Commit() error {
+	return nil
+}
+
+func (*dryRunPool) Rollback() error {
+	return nil
+}
+
+// TestRowLockSQL 使用DryRun记录Acquire在MySQL/PostgreSQL上生成的完整语句，不需要真实数据库
+// DryRun时查询不到哨兵行，依次执行：查询哨兵行、插入哨兵行、事务中锁定哨兵行
+func TestRowLockSQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialector gorm.Dialector
+		want      []string
+	}{
+		{
+			name:      "mysql",
+			dialector: mysql.New(mysql.Config{Conn: &dryRunPool{}, SkipInitializeWithVersion: true}),
+			want: []string{
+				"SELECT `lock_key` FROM `distributed_row_lock` WHERE `lock_key` = ? LIMIT ?",
+				"INSERT INTO `distributed_row_lock` (`created_at`,`expire_time`,`lock_key`,`lock_value`,`updated_at`) VALUES (?,?,?,?,?)",
+				"SELECT `lock_key` FROM `distributed_row_lock` WHERE `lock_key` = ? FOR UPDATE SKIP LOCKED",
+			},
+		},
+		{
+			name:      "postgres",
+			dialector: postgres.New(postgres.Config{Conn: &dryRunPool{}}),
+			want: []string{
+				`SELECT "lock_key" FROM "distributed_row_lock" WHERE "lock_key" = $1 LIMIT $2`,
+				`INSERT INTO "distributed_row_lock" ("created_at","expire_time","lock_key","lock_value","updated_at") VALUES ($1,$2,$3,$4,$5)`,
+				`SELECT "lock_key" FROM "distributed_row_lock" WHERE "lock_key" = $1 FOR UPDATE SKIP LOCKED`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(tt.dialector, &gorm.Config{
+				DryRun:                 true,
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+				Logger:                 logger.Default.LogMode(logger.Silent),
+			})
+			require.NoError(t, err)
+
+			var (
+				mu    sync.Mutex
+				stmts []string
+			)
+			record := func(db *gorm.DB) {
+				mu.Lock()
+				defer mu.Unlock()
+				stmts = append(stmts, db.Statement.SQL.String())
+			}
+			require.NoError(t, db.Callback().Query().After("gorm:query").Register("dbtest:record", record))
+			require.NoError(t, db.Callback().Create().After("gorm:create").Register("dbtest:record", record))
+
+			_, err = dlock.NewRowLocker(db, "").Acquire(context.Background(), "test-key", time.Second)
+			assert.Equal(t, dlock.ErrLockAlreadyHeld, err)
+			assert.Equal(t, tt.want, stmts)
+		})
+	}
+}
+
+// TestRowLock 使用真实的MySQL/PostgreSQL测试行锁
+// 通过DLOCK_TEST_MYSQL_DSN、DLOCK_TEST_POSTGRES_DSN指定数据库，未设置时跳过
+func TestRowLock(t *testing.T) {
+	backends := []struct {
+		name string
+		env  string
+		open func(dsn string) gorm.Dialector
+		ddl  string
+	}{
+		{
+			name: "mysql",
+			env:  "DLOCK_TEST_MYSQL_DSN",
+			open: mysql.Open,
+			ddl: `CREATE TABLE IF NOT EXISTS distributed_row_lock (
+				id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+				lock_key VARCHAR(128) NOT NULL,
+				lock_value VARCHAR(36) NOT NULL,
+				expire_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				UNIQUE KEY (lock_key)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+		},
+		{
+			name: "postgres",
+			env:  "DLOCK_TEST_POSTGRES_DSN",
+			open: postgres.Open,
+			ddl: `CREATE TABLE IF NOT EXISTS distributed_row_lock (
+				id SERIAL PRIMARY KEY,
+				lock_key VARCHAR(128) NOT NULL UNIQUE,
+				lock_value VARCHAR(36) NOT NULL,
+				expire_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			dsn := os.Getenv(backend.env)
+			if dsn == "" {
+				t.Skipf("%s not set", backend.env)
+			}
+			db, err := gorm.Open(backend.open(dsn), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Silent),
+			})
+			require.NoError(t, err)
+			require.NoError(t, db.Exec(backend.ddl).Error)
+			testRowLock(t, db)
+		})
+	}
+}
+
+func testRowLock(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	locker := dlock.NewRowLocker(db, "")
+	require.NoError(t, locker.Ping(ctx))
+
+	t.Run("互斥", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "row-key-1", 10*time.Second)
+		require.NoError(t, err)
+
+		// 哨兵行被锁定时立即返回，不等待行锁
+		start := time.Now()
+		_, err = locker.Acquire(ctx, "row-key-1", 10*time.Second)
+		assert.Equal(t, dlock.ErrLockAlreadyHeld, err)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// 提交事务后可以再次获取
+		require.NoError(t, lock.Unlock(ctx))
+		require.NoError(t, lock.Unlock(ctx))
+		lock, err = locker.Acquire(ctx, "row-key-1", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, dlock.RefreshLock(ctx, lock, 10*time.Second))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("ttl到期回滚", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "row-key-2", 200*time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(400 * time.Millisecond)
+
+		lock2, err := locker.Acquire(ctx, "row-key-2", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, dlock.ErrLockNotHeld, dlock.RefreshLock(ctx, lock, time.Second))
+		assert.Equal(t, dlock.ErrLockNotHeld, lock.Unlock(ctx))
+		require.NoError(t, lock2.Unlock(ctx))
+	})
+
+	t.Run("并发等待不重叠", func(t *testing.T) {
+		var (
+			wg      sync.WaitGroup
+			holding int32
+			overlap int32
+		)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lock, err := locker.AcquireWait(ctx, "row-key-3", 10*time.Second, 10*time.Second)
+				if !assert.NoError(t, err) {
+					return
+				}
+				if atomic.AddInt32(&holding, 1) > 1 {
+					atomic.StoreInt32(&overlap, 1)
+				}
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&holding, -1)
+				assert.NoError(t, lock.Unlock(ctx))
+			}()
+		}
+		wg.Wait()
+		assert.Zero(t, atomic.LoadInt32(&overlap))
+	})
+}
@@ -1,3 +1,9 @@
+-- 可选: 使用WithColumns(Columns{Owner: "lock_owner"})存储持有者信息("host:pid")时，需要额外添加列
+-- MySQL/PostgreSQL: lock_owner VARCHAR(255) NOT NULL DEFAULT ''
+-- SQLite:           lock_owner TEXT NOT NULL DEFAULT ''
+
+-- NewRowLocker使用相同的表结构，表名默认为distributed_row_lock，不能与NewDatabaseLocker共用同一张表(仅支持MySQL/PostgreSQL)
+
 ----------------------- MySQL -----------------------
 CREATE TABLE IF NOT EXISTS distributed_lock (
     id INT UNSIGNED NOT NULL AUTO_INCREMENT,
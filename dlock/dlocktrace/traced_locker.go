@@ -0,0 +1,158 @@
+// Package dlocktrace 为dlock.Locker提供基于OpenTelemetry的链路追踪装饰器，
+// 独立为单独的module，只有引入本包的调用方才会依赖otel
+package dlocktrace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kakkk/gopkg/dlock"
+)
+
+// span属性
+const (
+	attrKey      = "dlock.key"
+	attrKeys     = "dlock.keys"
+	attrTTL      = "dlock.ttl_ms"
+	attrMaxRetry = "dlock.max_retry"   // 调用时传入的最大重试次数
+	attrMaxWait  = "dlock.max_wait_ms" // 调用时传入的最长等待时间
+	attrOutcome  = "dlock.outcome"
+)
+
+// 加解锁结果
+const (
+	outcomeAcquired  = "acquired"
+	outcomeContended = "contended" // 锁被他人持有或重试耗尽
+	outcomeReleased  = "released"
+	outcomeNotHeld   = "not_held" // 解锁时锁已不属于自己
+	outcomeError     = "error"
+)
+
+type tracedLocker struct {
+	inner  dlock.Locker
+	tracer trace.Tracer
+}
+
+// NewTracedLocker 包装inner，为Acquire/AcquireWithRetry/AcquireWait/AcquireMulti及返回锁的Unlock创建span，
+// 记录key、调用时传入的重试参数(max_retry/max_wait，不是实际尝试次数)及结果；锁竞争失败不视为span错误，其他错误记录到span并标记Error状态
+func NewTracedLocker(inner dlock.Locker, tracer trace.Tracer) dlock.Locker {
+	return &tracedLocker{inner: inner, tracer: tracer}
+}
+
+func (t *tracedLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (dlock.Lock, error) {
+	ctx, span := t.tracer.Start(ctx, "dlock.Acquire", trace.WithAttributes(
+		attribute.String(attrKey, key),
+		attribute.Int64(attrTTL, ttl.Milliseconds()),
+	))
+	defer span.End()
+
+	lock, err := t.inner.Acquire(ctx, key, ttl)
+	return t.wrap(lock), endAcquire(span, err)
+}
+
+func (t *tracedLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (dlock.Lock, error) {
+	ctx, span := t.tracer.Start(ctx, "dlock.AcquireWithRetry", trace.WithAttributes(
+		attribute.String(attrKey, key),
+		attribute.Int64(attrTTL, ttl.Milliseconds()),
+		attribute.Int64(attrMaxRetry, maxRetry),
+	))
+	defer span.End()
+
+	lock, err := t.inner.AcquireWithRetry(ctx, key, ttl, maxRetry, interval)
+	return t.wrap(lock), endAcquire(span, err)
+}
+
+func (t *tracedLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (dlock.Lock, error) {
+	ctx, span := t.tracer.Start(ctx, "dlock.AcquireWait", trace.WithAttributes(
+		attribute.String(attrKey, key),
+		attribute.Int64(attrTTL, ttl.Milliseconds()),
+		attribute.Int64(attrMaxWait, maxWait.Milliseconds()),
+	))
+	defer span.End()
+
+	lock, err := t.inner.AcquireWait(ctx, key, ttl, maxWait)
+	return t.wrap(lock), endAcquire(span, err)
+}
+
+func (t *tracedLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]dlock.Lock, error) {
+	ctx, span := t.tracer.Start(ctx, "dlock.AcquireMulti", trace.WithAttributes(
+		attribute.StringSlice(attrKeys, keys),
+		attribute.Int64(attrTTL, ttl.Milliseconds()),
+	))
+	defer span.End()
+
+	locks, err := t.inner.AcquireMulti(ctx, keys, ttl)
+	for i := range locks {
+		locks[i] = t.wrap(locks[i])
+	}
+	return locks, endAcquire(span, err)
+}
+
+// Ping 直接透传，探针调用频繁，不创建span
+func (t *tracedLocker) Ping(ctx context.Context) error {
+	return t.inner.Ping(ctx)
+}
+
+func (t *tracedLocker) wrap(lock dlock.Lock) dlock.Lock {
+	if lock == nil {
+		return nil
+	}
+	return &tracedLock{Lock: lock, tracer: t.tracer}
+}
+
+// endAcquire 记录加锁结果，原样返回err
+func endAcquire(span trace.Span, err error) error {
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.String(attrOutcome, outcomeAcquired))
+	case errors.Is(err, dlock.ErrLockAlreadyHeld), errors.Is(err, dlock.ErrLockNotAcquired):
+		span.SetAttributes(attribute.String(attrOutcome, outcomeContended))
+	default:
+		recordError(span, err)
+	}
+	return err
+}
+
+func recordError(span trace.Span, err error) {
+	span.SetAttributes(attribute.String(attrOutcome, outcomeError))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+type tracedLock struct {
+	dlock.Lock
+	tracer trace.Tracer
+}
+
+// Refresh 续期被包装的锁，不支持时返回dlock.ErrRefreshNotSupported
+func (l *tracedLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	return dlock.RefreshLock(ctx, l.Lock, ttl)
+}
+
+// FenceToken 被包装的锁的fencing token，不支持时返回0
+func (l *tracedLock) FenceToken() int64 {
+	return dlock.FenceToken(l.Lock)
+}
+
+func (l *tracedLock) Unlock(ctx context.Context) error {
+	ctx, span := l.tracer.Start(ctx, "dlock.Unlock", trace.WithAttributes(
+		attribute.String(attrKey, l.Key()),
+	))
+	defer span.End()
+
+	err := l.Lock.Unlock(ctx)
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.String(attrOutcome, outcomeReleased))
+	case errors.Is(err, dlock.ErrLockNotHeld):
+		span.SetAttributes(attribute.String(attrOutcome, outcomeNotHeld))
+	default:
+		recordError(span, err)
+	}
+	return err
+}
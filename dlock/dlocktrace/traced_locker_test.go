@@ -0,0 +1,123 @@
+package dlocktrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/kakkk/gopkg/dlock"
+)
+
+func newTestLocker(t *testing.T) (dlock.Locker, *tracetest.SpanRecorder) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+
+	cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = cli.Close() })
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewTracedLocker(dlock.NewRedisLocker(cli), tp.Tracer("dlock")), recorder
+}
+
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracedLocker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		locker, recorder := newTestLocker(t)
+
+		lock, err := locker.Acquire(ctx, "trace-key", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+
+		assert.Equal(t, "dlock.Acquire", spans[0].Name())
+		attrs := spanAttrs(spans[0])
+		assert.Equal(t, "trace-key", attrs[attrKey].AsString())
+		assert.Equal(t, int64(10000), attrs[attrTTL].AsInt64())
+		assert.Equal(t, outcomeAcquired, attrs[attrOutcome].AsString())
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+		assert.Equal(t, "dlock.Unlock", spans[1].Name())
+		attrs = spanAttrs(spans[1])
+		assert.Equal(t, "trace-key", attrs[attrKey].AsString())
+		assert.Equal(t, outcomeReleased, attrs[attrOutcome].AsString())
+	})
+
+	t.Run("fence token", func(t *testing.T) {
+		s, err := miniredis.Run()
+		require.NoError(t, err)
+		defer s.Close()
+		cli := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		defer cli.Close()
+		locker := NewTracedLocker(dlock.NewRedisLocker(cli, dlock.WithFenceToken(true)), sdktrace.NewTracerProvider().Tracer("dlock"))
+
+		// 包装后仍可获取fencing token
+		lock, err := locker.Acquire(ctx, "trace-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), dlock.FenceToken(lock))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("contention", func(t *testing.T) {
+		locker, recorder := newTestLocker(t)
+
+		held, err := locker.Acquire(ctx, "trace-key", 10*time.Second)
+		require.NoError(t, err)
+
+		_, err = locker.Acquire(ctx, "trace-key", 10*time.Second)
+		assert.Equal(t, dlock.ErrLockAlreadyHeld, err)
+		_, err = locker.AcquireWithRetry(ctx, "trace-key", 10*time.Second, 2, 10*time.Millisecond)
+		assert.Equal(t, dlock.ErrLockNotAcquired, err)
+		require.NoError(t, held.Unlock(ctx))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 4)
+
+		attrs := spanAttrs(spans[1])
+		assert.Equal(t, "dlock.Acquire", spans[1].Name())
+		assert.Equal(t, outcomeContended, attrs[attrOutcome].AsString())
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+
+		attrs = spanAttrs(spans[2])
+		assert.Equal(t, "dlock.AcquireWithRetry", spans[2].Name())
+		assert.Equal(t, "trace-key", attrs[attrKey].AsString())
+		assert.Equal(t, int64(2), attrs[attrMaxRetry].AsInt64())
+		assert.Equal(t, outcomeContended, attrs[attrOutcome].AsString())
+		assert.Equal(t, codes.Unset, spans[2].Status().Code)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		locker, recorder := newTestLocker(t)
+
+		_, err := locker.Acquire(ctx, "", 10*time.Second)
+		assert.True(t, errors.Is(err, dlock.ErrInvalidKey))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, outcomeError, spanAttrs(spans[0])[attrOutcome].AsString())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Len(t, spans[0].Events(), 1)
+	})
+}
@@ -3,10 +3,16 @@ package dlock
 import "errors"
 
 // 错误定义
+//
+// 后端(redis、数据库)本身的错误不会映射为以下错误，而是包装后返回("redis error: ..."、"database error: ...")，
+// 可通过errors.Is/errors.As判断原始错误，与锁被占用区分开
 var (
-	ErrLockNotAcquired = errors.New("lock not acquired")
-	ErrLockAlreadyHeld = errors.New("lock already held")
-	ErrLockNotHeld     = errors.New("lock not held")
-	ErrInvalidTTL      = errors.New("invalid ttl")
-	ErrInvalidKey      = errors.New("invalid lockKey")
+	ErrLockNotAcquired = errors.New("lock not acquired") // AcquireWithRetry、AcquireWait重试耗尽或等待超时仍未获取到锁
+	ErrLockAlreadyHeld = errors.New("lock already held") // Acquire时锁已被其他持有者占用
+	ErrLockNotHeld     = errors.New("lock not held")     // Unlock、Refresh时锁已过期或已被其他持有者获取
+	ErrInvalidTTL      = errors.New("invalid ttl")       // ttl小于等于0
+	ErrInvalidKey      = errors.New("invalid lockKey")   // key为空
+	ErrInvalidInterval = errors.New("invalid interval")  // NewHeartbeatLock的心跳间隔不在(0, ttl)范围内
+
+	ErrRefreshNotSupported = errors.New("lock refresh not supported") // 锁未实现RefreshableLock
 )
@@ -0,0 +1,109 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LockLostFn 心跳续期发现锁已丢失时的回调，err为续期返回的错误
+type LockLostFn func(ctx context.Context, key string, err error)
+
+type heartbeatLock struct {
+	Lock
+	stop     context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Refresh 手动续期，心跳仍按interval继续
+func (l *heartbeatLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	return RefreshLock(ctx, l.Lock, ttl)
+}
+
+// FenceToken 被续期的锁的fencing token，不支持时返回0
+func (l *heartbeatLock) FenceToken() int64 {
+	return FenceToken(l.Lock)
+}
+
+// NewHeartbeatLock 获取锁后每隔interval使用ttl续期，适合持有时间不确定的长任务：
+// 使用较短的ttl，持有者崩溃后锁很快过期，正常运行时心跳保证锁不会过期
+// 续期返回ErrLockNotHeld，或连续续期失败超过ttl时认为锁已丢失，调用onLost并停止心跳，任务应尽快中止
+// Unlock时先停止心跳再释放锁；心跳不受ctx取消影响，只在Unlock或锁丢失时停止
+func NewHeartbeatLock(ctx context.Context, locker Locker, key string, ttl time.Duration, interval time.Duration, onLost LockLostFn) (Lock, error) {
+	if interval <= 0 || interval >= ttl {
+		return nil, ErrInvalidInterval
+	}
+	lock, err := locker.Acquire(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	// 不支持续期的锁无法维持心跳
+	if _, ok := lock.(RefreshableLock); !ok {
+		return nil, errors.Join(ErrRefreshNotSupported, lock.Unlock(ctx))
+	}
+
+	hbCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	hl := &heartbeatLock{
+		Lock: lock,
+		stop: stop,
+		done: make(chan struct{}),
+	}
+	go func() {
+		err := hl.safeHeartbeat(hbCtx, ttl, interval)
+		close(hl.done)
+		// 心跳已停止后再回调，回调中可以直接调用Unlock
+		if err != nil && onLost != nil {
+			onLost(hbCtx, key, err)
+		}
+	}()
+	return hl, nil
+}
+
+// safeHeartbeat 续期时panic(如自定义Locker的Refresh)无法保证锁仍然持有，视为锁已丢失
+func (l *heartbeatLock) safeHeartbeat(ctx context.Context, ttl time.Duration, interval time.Duration) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverError(r)
+		}
+	}()
+	return l.heartbeat(ctx, ttl, interval)
+}
+
+// heartbeat 定期续期直到ctx取消，锁丢失时返回续期的错误
+func (l *heartbeatLock) heartbeat(ctx context.Context, ttl time.Duration, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := RefreshLock(ctx, l.Lock, ttl)
+		if err == nil {
+			lastRenew = time.Now()
+			continue
+		}
+		// Unlock停止心跳时的续期失败不是锁丢失
+		if ctx.Err() != nil {
+			return nil
+		}
+		// 其他错误(如网络抖动)在锁过期前继续重试
+		if errors.Is(err, ErrLockNotHeld) || time.Since(lastRenew) >= ttl {
+			return err
+		}
+	}
+}
+
+// Unlock 停止心跳并释放锁
+func (l *heartbeatLock) Unlock(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		l.stop()
+		<-l.done
+	})
+	return l.Lock.Unlock(ctx)
+}
@@ -0,0 +1,159 @@
+package dlock
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestHeartbeatLock 使用SQLite测试，过期时间按真实时间计算
+func TestHeartbeatLock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	// 使用独立的表，避免受其他测试影响
+	err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_lock_heartbeat (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lock_key TEXT NOT NULL UNIQUE,
+			lock_value TEXT NOT NULL,
+			expire_time DATETIME NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`).Error
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	locker := NewDatabaseLocker(db, "distributed_lock_heartbeat")
+
+	t.Run("TestAliveAcrossTTLs", func(t *testing.T) {
+		var lost atomic.Bool
+		lock, err := NewHeartbeatLock(ctx, locker, "hb-alive", 200*time.Millisecond, 50*time.Millisecond,
+			func(ctx context.Context, key string, err error) { lost.Store(true) })
+		require.NoError(t, err)
+
+		// 超过多个ttl后锁依然被持有
+		time.Sleep(700 * time.Millisecond)
+		_, err = locker.Acquire(ctx, "hb-alive", time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		assert.False(t, lost.Load())
+
+		// Unlock后心跳停止，锁可以被重新获取
+		require.NoError(t, lock.Unlock(ctx))
+		other, err := locker.Acquire(ctx, "hb-alive", time.Second)
+		require.NoError(t, err)
+		require.NoError(t, other.Unlock(ctx))
+		assert.False(t, lost.Load())
+	})
+
+	t.Run("TestLostOnExternalDelete", func(t *testing.T) {
+		lostKey := make(chan string, 1)
+		lock, err := NewHeartbeatLock(ctx, locker, "hb-lost", 200*time.Millisecond, 50*time.Millisecond,
+			func(ctx context.Context, key string, err error) {
+				assert.ErrorIs(t, err, ErrLockNotHeld)
+				lostKey <- key
+			})
+		require.NoError(t, err)
+
+		require.NoError(t, db.Table("distributed_lock_heartbeat").Where("lock_key = ?", "hb-lost").Delete(&lockModel{}).Error)
+
+		select {
+		case key := <-lostKey:
+			assert.Equal(t, "hb-lost", key)
+		case <-time.After(time.Second):
+			t.Fatal("lost callback not called")
+		}
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestInvalidInterval", func(t *testing.T) {
+		_, err := NewHeartbeatLock(ctx, locker, "hb-invalid", time.Second, time.Second, nil)
+		assert.ErrorIs(t, err, ErrInvalidInterval)
+		_, err = NewHeartbeatLock(ctx, locker, "hb-invalid", time.Second, 0, nil)
+		assert.ErrorIs(t, err, ErrInvalidInterval)
+	})
+}
+
+// plainLocker 返回的锁只实现Lock，不支持续期
+type plainLocker struct {
+	Locker
+}
+
+type plainLock struct {
+	Lock
+}
+
+func (l plainLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	lock, err := l.Locker.Acquire(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return plainLock{Lock: lock}, nil
+}
+
+func TestRedisHeartbeatLock(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	locker := NewRedisLocker(client)
+
+	t.Run("TestRefresh", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "hb-refresh", time.Second)
+		require.NoError(t, err)
+		require.NoError(t, RefreshLock(ctx, lock, 10*time.Second))
+		assert.Equal(t, 10*time.Second, s.TTL("hb-refresh"))
+
+		require.NoError(t, lock.Unlock(ctx))
+		assert.Equal(t, ErrLockNotHeld, RefreshLock(ctx, lock, time.Second))
+	})
+
+	t.Run("TestRefreshNotSupported", func(t *testing.T) {
+		plain := plainLocker{Locker: locker}
+		lock, err := plain.Acquire(ctx, "hb-plain", time.Second)
+		require.NoError(t, err)
+		assert.ErrorIs(t, RefreshLock(ctx, lock, time.Second), ErrRefreshNotSupported)
+		require.NoError(t, lock.Unlock(ctx))
+
+		// 不支持续期时不创建心跳，已获取的锁被释放
+		_, err = NewHeartbeatLock(ctx, plain, "hb-plain", time.Second, 100*time.Millisecond, nil)
+		assert.ErrorIs(t, err, ErrRefreshNotSupported)
+		assert.False(t, s.Exists("hb-plain"))
+	})
+
+	t.Run("TestLostOnExternalDelete", func(t *testing.T) {
+		lost := make(chan error, 1)
+		lock, err := NewHeartbeatLock(ctx, locker, "hb-redis-lost", 200*time.Millisecond, 20*time.Millisecond,
+			func(ctx context.Context, key string, err error) { lost <- err })
+		require.NoError(t, err)
+
+		s.Del("hb-redis-lost")
+
+		select {
+		case err := <-lost:
+			assert.ErrorIs(t, err, ErrLockNotHeld)
+		case <-time.After(time.Second):
+			t.Fatal("lost callback not called")
+		}
+		assert.Equal(t, ErrLockNotHeld, lock.Unlock(ctx))
+	})
+}
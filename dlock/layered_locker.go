@@ -0,0 +1,126 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// layeredLocker 先获取本地锁再获取分布式锁
+// 同一进程内竞争同一个key时，只有持有本地锁的调用方访问redis/数据库，其余调用方在本地等待，减少无效的网络请求
+type layeredLocker struct {
+	local       Locker
+	distributed Locker
+}
+
+func newLayeredLocker(local, distributed Locker) *layeredLocker {
+	return &layeredLocker{
+		local:       local,
+		distributed: distributed,
+	}
+}
+
+func (l *layeredLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	localLock, err := l.local.Acquire(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return l.acquireDistributed(ctx, localLock, func() (Lock, error) {
+		return l.distributed.Acquire(ctx, key, ttl)
+	})
+}
+
+// AcquireWithRetry 本地锁和分布式锁分别按maxRetry、interval重试
+func (l *layeredLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (Lock, error) {
+	localLock, err := l.local.AcquireWithRetry(ctx, key, ttl, maxRetry, interval)
+	if err != nil {
+		return nil, err
+	}
+	return l.acquireDistributed(ctx, localLock, func() (Lock, error) {
+		return l.distributed.AcquireWithRetry(ctx, key, ttl, maxRetry, interval)
+	})
+}
+
+// AcquireWait 等待本地锁和分布式锁的总时长不超过maxWait
+func (l *layeredLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	deadline := time.Now().Add(maxWait)
+	localLock, err := l.local.AcquireWait(ctx, key, ttl, maxWait)
+	if err != nil {
+		return nil, err
+	}
+	return l.acquireDistributed(ctx, localLock, func() (Lock, error) {
+		return l.distributed.AcquireWait(ctx, key, ttl, time.Until(deadline))
+	})
+}
+
+func (l *layeredLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error) {
+	return acquireMulti(ctx, keys, ttl, l.Acquire)
+}
+
+// Ping 检查本地锁和分布式锁
+func (l *layeredLocker) Ping(ctx context.Context) error {
+	if err := l.local.Ping(ctx); err != nil {
+		return err
+	}
+	return l.distributed.Ping(ctx)
+}
+
+// acquireDistributed 持有本地锁时获取分布式锁，失败时释放本地锁
+func (l *layeredLocker) acquireDistributed(ctx context.Context, localLock Lock, acquire func() (Lock, error)) (Lock, error) {
+	distLock, err := acquire()
+	if err != nil {
+		_ = localLock.Unlock(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	return &layeredLock{
+		local:       localLock,
+		distributed: distLock,
+	}, nil
+}
+
+// layeredLock Key、Value、FenceToken与分布式锁一致
+type layeredLock struct {
+	local       Lock
+	distributed Lock
+	mu          sync.Mutex
+	unlocked    bool // 标记是否已释放
+}
+
+// Unlock 先释放分布式锁再释放本地锁，保证本地下一个等待者获取分布式锁时已释放
+func (l *layeredLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return nil // 幂等性：已经释放的锁再次释放不报错
+	}
+	err := l.distributed.Unlock(ctx)
+	if errors.Is(err, ErrLockNotHeld) || err == nil {
+		// 分布式锁已不再持有，本地锁无论如何都要释放，否则同一进程内的其他调用方会一直等待
+		l.unlocked = true
+		return errors.Join(err, l.local.Unlock(ctx))
+	}
+	// redis/数据库错误时保留本地锁，调用方可以重试Unlock，本地锁最终会过期
+	return err
+}
+
+func (l *layeredLock) Key() string {
+	return l.distributed.Key()
+}
+
+func (l *layeredLock) Value() string {
+	return l.distributed.Value()
+}
+
+// FenceToken 分布式锁的fencing token，分布式锁不支持时返回0
+func (l *layeredLock) FenceToken() int64 {
+	return FenceToken(l.distributed)
+}
+
+// Refresh 续期分布式锁和本地锁
+func (l *layeredLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if err := RefreshLock(ctx, l.distributed, ttl); err != nil {
+		return err
+	}
+	return RefreshLock(ctx, l.local, ttl)
+}
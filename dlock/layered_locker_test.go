@@ -0,0 +1,150 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLocker 统计访问分布式锁的次数和同时访问的调用方数量
+type countingLocker struct {
+	Locker
+	calls    atomic.Int64
+	inFlight atomic.Int64 // 正在获取或持有分布式锁的调用方数量
+	maxIn    atomic.Int64
+}
+
+func (c *countingLocker) enter() {
+	c.calls.Add(1)
+	n := c.inFlight.Add(1)
+	for {
+		old := c.maxIn.Load()
+		if n <= old || c.maxIn.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func (c *countingLocker) wrap(lock Lock, err error) (Lock, error) {
+	if err != nil {
+		c.inFlight.Add(-1)
+		return nil, err
+	}
+	return &countingLock{Lock: lock, locker: c}, nil
+}
+
+func (c *countingLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	c.enter()
+	return c.wrap(c.Locker.Acquire(ctx, key, ttl))
+}
+
+func (c *countingLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	c.enter()
+	return c.wrap(c.Locker.AcquireWait(ctx, key, ttl, maxWait))
+}
+
+type countingLock struct {
+	Lock
+	locker *countingLocker
+}
+
+func (l *countingLock) Unlock(ctx context.Context) error {
+	l.locker.inFlight.Add(-1)
+	return l.Lock.Unlock(ctx)
+}
+
+func (l *countingLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	return RefreshLock(ctx, l.Lock, ttl)
+}
+
+func (l *countingLock) FenceToken() int64 {
+	return FenceToken(l.Lock)
+}
+
+func TestLayeredLocker(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("TestAcquireFastFail", func(t *testing.T) {
+		dist := &countingLocker{Locker: NewRedisLocker(client, WithFenceToken(true))}
+		locker := NewLayeredLocker(NewLocalLocker(), dist)
+
+		lock, err := locker.Acquire(ctx, "layered-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.True(t, s.Exists("layered-key"))
+		assert.Equal(t, int64(1), FenceToken(lock))
+		assert.Equal(t, int64(1), dist.calls.Load())
+
+		// 本地已持有，不访问redis
+		_, err = locker.Acquire(ctx, "layered-key", 10*time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		assert.Equal(t, int64(1), dist.calls.Load())
+
+		require.NoError(t, RefreshLock(ctx, lock, 20*time.Second))
+		require.NoError(t, lock.Unlock(ctx))
+		assert.False(t, s.Exists("layered-key"))
+
+		lock, err = locker.Acquire(ctx, "layered-key", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestDistributedHeldReleasesLocal", func(t *testing.T) {
+		redisLocker := NewRedisLocker(client)
+		other, err := redisLocker.Acquire(ctx, "layered-key-2", 10*time.Second)
+		require.NoError(t, err)
+
+		locker := NewLayeredLocker(NewLocalLocker(), redisLocker)
+		_, err = locker.Acquire(ctx, "layered-key-2", 10*time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+
+		// 分布式锁获取失败时本地锁已释放，其他进程释放后可以获取
+		require.NoError(t, other.Unlock(ctx))
+		lock, err := locker.Acquire(ctx, "layered-key-2", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestOneLocalWaiterAtATime", func(t *testing.T) {
+		dist := &countingLocker{Locker: NewRedisLocker(client)}
+		locker := NewLayeredLocker(NewLocalLocker(), dist)
+
+		const workers = 20
+		var wg sync.WaitGroup
+		var counter int
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lock, err := locker.AcquireWait(ctx, "layered-key-3", 10*time.Second, 10*time.Second)
+				if !assert.NoError(t, err) {
+					return
+				}
+				counter++
+				time.Sleep(time.Millisecond)
+				assert.NoError(t, lock.Unlock(ctx))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, workers, counter)
+		// 每个调用方只访问一次分布式锁，且同一时刻只有一个
+		assert.Equal(t, int64(workers), dist.calls.Load())
+		assert.Equal(t, int64(1), dist.maxIn.Load())
+	})
+}
@@ -0,0 +1,219 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// localPruneMin 持有者数量达到该值后才开始清理过期的持有者
+const localPruneMin = 64
+
+// localHolder 本地锁的持有者
+type localHolder struct {
+	value    string
+	expireAt time.Time
+}
+
+type localLocker struct {
+	mu      sync.Mutex
+	holders map[string]localHolder
+	waiters map[string]map[chan struct{}]struct{} // 等待获取锁的调用方，释放时唤醒
+	// nextPrune 持有者数量达到该值时清理过期的持有者，清理后设为剩余数量的2倍，均摊每次获取的开销
+	// 过期后未释放的锁只有在同一个key再次获取时才会被覆盖，不清理会一直占用内存
+	nextPrune int
+}
+
+func newLocalLocker() *localLocker {
+	return &localLocker{
+		holders:   make(map[string]localHolder),
+		waiters:   make(map[string]map[chan struct{}]struct{}),
+		nextPrune: localPruneMin,
+	}
+}
+
+// prune 清理过期的持有者，调用方需持有l.mu
+func (l *localLocker) prune(now time.Time) {
+	if len(l.holders) < l.nextPrune {
+		return
+	}
+	for key, h := range l.holders {
+		if !now.Before(h.expireAt) {
+			delete(l.holders, key)
+		}
+	}
+	l.nextPrune = max(2*len(l.holders), localPruneMin)
+}
+
+// subscribe 注册释放通知，返回的函数用于取消注册
+func (l *localLocker) subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.waiters[key] == nil {
+		l.waiters[key] = make(map[chan struct{}]struct{})
+	}
+	l.waiters[key][ch] = struct{}{}
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.waiters[key], ch)
+		if len(l.waiters[key]) == 0 {
+			delete(l.waiters, key)
+		}
+	}
+}
+
+// notify 唤醒key的所有等待者，调用方需持有l.mu
+func (l *localLocker) notify(key string) {
+	for ch := range l.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *localLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h, ok := l.holders[key]; ok && now.Before(h.expireAt) {
+		return nil, ErrLockAlreadyHeld
+	}
+
+	l.prune(now)
+	value := lockValue()
+	l.holders[key] = localHolder{value: value, expireAt: now.Add(ttl)}
+	return &localLock{
+		locker:    l,
+		lockKey:   key,
+		lockValue: value,
+	}, nil
+}
+
+func (l *localLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+	if maxRetry < 0 {
+		maxRetry = 0
+	}
+
+	wake, unsubscribe := l.subscribe(key)
+	defer unsubscribe()
+
+	for i := int64(0); i <= maxRetry; i++ {
+		lock, err := l.Acquire(ctx, key, ttl)
+		if err == nil {
+			return lock, nil
+		}
+		if err != ErrLockAlreadyHeld {
+			return nil, err
+		}
+
+		// 等待后重试，锁释放时立即重试
+		select {
+		case <-time.After(interval):
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, ErrLockNotAcquired
+}
+
+func (l *localLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+
+	wake, unsubscribe := l.subscribe(key)
+	defer unsubscribe()
+
+	return acquireWait(ctx, maxWait, wake, func(ctx context.Context) (Lock, error) {
+		return l.Acquire(ctx, key, ttl)
+	})
+}
+
+func (l *localLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error) {
+	return acquireMulti(ctx, keys, ttl, l.Acquire)
+}
+
+// Ping 本地锁始终可用
+func (l *localLocker) Ping(ctx context.Context) error {
+	return nil
+}
+
+type localLock struct {
+	locker    *localLocker
+	lockKey   string
+	lockValue string
+	mu        sync.Mutex
+	unlocked  bool // 标记是否已释放
+}
+
+func (l *localLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return nil // 幂等性：已经释放的锁再次释放不报错
+	}
+
+	locker := l.locker
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+	h, ok := locker.holders[l.lockKey]
+	if !ok || h.value != l.lockValue {
+		return ErrLockNotHeld
+	}
+	delete(locker.holders, l.lockKey)
+	locker.notify(l.lockKey)
+	l.unlocked = true
+	if !time.Now().Before(h.expireAt) {
+		// 已过期，与redis一致返回ErrLockNotHeld
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *localLock) Key() string {
+	return l.lockKey
+}
+
+func (l *localLock) Value() string {
+	return l.lockValue
+}
+
+func (l *localLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return ErrLockNotHeld
+	}
+
+	now := time.Now()
+	locker := l.locker
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+	h, ok := locker.holders[l.lockKey]
+	if !ok || h.value != l.lockValue || !now.Before(h.expireAt) {
+		return ErrLockNotHeld
+	}
+	locker.holders[l.lockKey] = localHolder{value: l.lockValue, expireAt: now.Add(ttl)}
+	return nil
+}
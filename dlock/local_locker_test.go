@@ -0,0 +1,123 @@
+package dlock
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalLocker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TestAcquireAndUnlock", func(t *testing.T) {
+		locker := NewLocalLocker()
+		lock, err := locker.Acquire(ctx, "key", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "key", lock.Key())
+		assert.NotEmpty(t, lock.Value())
+		_, ok := lock.(FenceTokenLock)
+		assert.False(t, ok)
+		assert.Zero(t, FenceToken(lock))
+
+		_, err = locker.Acquire(ctx, "key", time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+
+		require.NoError(t, lock.Unlock(ctx))
+		// 幂等
+		require.NoError(t, lock.Unlock(ctx))
+
+		lock, err = locker.Acquire(ctx, "key", time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestInvalidParams", func(t *testing.T) {
+		locker := NewLocalLocker()
+		_, err := locker.Acquire(ctx, " ", time.Second)
+		assert.Equal(t, ErrInvalidKey, err)
+		_, err = locker.Acquire(ctx, "key", 0)
+		assert.Equal(t, ErrInvalidTTL, err)
+	})
+
+	t.Run("TestExpire", func(t *testing.T) {
+		locker := NewLocalLocker()
+		lock1, err := locker.Acquire(ctx, "key", 20*time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+
+		// 过期后可以被其他调用方获取，旧锁不能续期、释放
+		lock2, err := locker.Acquire(ctx, "key", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, ErrLockNotHeld, RefreshLock(ctx, lock1, time.Second))
+		assert.Equal(t, ErrLockNotHeld, lock1.Unlock(ctx))
+
+		_, err = locker.Acquire(ctx, "key", time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		require.NoError(t, lock2.Unlock(ctx))
+	})
+
+	t.Run("TestPruneExpired", func(t *testing.T) {
+		locker := newLocalLocker()
+		for i := 0; i < localPruneMin-1; i++ {
+			_, err := locker.Acquire(ctx, fmt.Sprintf("abandoned-%d", i), 10*time.Millisecond)
+			require.NoError(t, err)
+		}
+		held, err := locker.Acquire(ctx, "held", time.Minute)
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		// 过期未释放的持有者在后续获取时被清理，未过期的保留
+		lock, err := locker.Acquire(ctx, "new", time.Minute)
+		require.NoError(t, err)
+		locker.mu.Lock()
+		assert.Len(t, locker.holders, 2)
+		locker.mu.Unlock()
+		_, err = locker.Acquire(ctx, "held", time.Minute)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		require.NoError(t, held.Unlock(ctx))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestRefresh", func(t *testing.T) {
+		locker := NewLocalLocker()
+		lock, err := locker.Acquire(ctx, "key", 30*time.Millisecond)
+		require.NoError(t, err)
+		require.NoError(t, RefreshLock(ctx, lock, time.Second))
+		time.Sleep(40 * time.Millisecond)
+
+		_, err = locker.Acquire(ctx, "key", time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestAcquireWaitWakeOnUnlock", func(t *testing.T) {
+		locker := NewLocalLocker()
+		lock, err := locker.Acquire(ctx, "key", time.Minute)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = lock.Unlock(ctx)
+		}()
+
+		start := time.Now()
+		lock2, err := locker.AcquireWait(ctx, "key", time.Minute, time.Second)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		require.NoError(t, lock2.Unlock(ctx))
+	})
+
+	t.Run("TestAcquireWithRetryExhausted", func(t *testing.T) {
+		locker := NewLocalLocker()
+		lock, err := locker.Acquire(ctx, "key", time.Minute)
+		require.NoError(t, err)
+		defer lock.Unlock(ctx)
+
+		_, err = locker.AcquireWithRetry(ctx, "key", time.Minute, 2, 5*time.Millisecond)
+		assert.Equal(t, ErrLockNotAcquired, err)
+	})
+}
@@ -10,19 +10,92 @@ import (
 
 type Lock interface {
 	Unlock(ctx context.Context) error
+	Key() string   // 锁的key
+	Value() string // 锁的值(UUID)，用于标识持有者
+}
+
+// RefreshableLock 可选，支持续期的锁，内置的锁都实现了该接口
+// 通过类型断言或RefreshLock(ctx, lock, ttl)续期
+type RefreshableLock interface {
+	Lock
+	Refresh(ctx context.Context, ttl time.Duration) error // 续期，将过期时间重置为ttl，锁已过期或不再属于自己时返回ErrLockNotHeld
+}
+
+// RefreshLock 续期锁，锁未实现RefreshableLock时返回ErrRefreshNotSupported
+func RefreshLock(ctx context.Context, lock Lock, ttl time.Duration) error {
+	if rl, ok := lock.(RefreshableLock); ok {
+		return rl.Refresh(ctx, ttl)
+	}
+	return ErrRefreshNotSupported
+}
+
+// FenceTokenLock 可选，支持fencing token的锁，目前只有开启WithFenceToken的Redis锁及其组合(NewLayeredLocker等)
+// 通过类型断言或FenceToken(lock)获取
+type FenceTokenLock interface {
+	Lock
+	// FenceToken 获取锁时分配的fencing token，同一个key每次获取锁单调递增，未开启时返回0
+	// 下游存储写入时携带该token，拒绝token小于已见过的最大值的写入，避免失去锁的旧持有者(如GC停顿超过TTL)继续写入
+	FenceToken() int64
+}
+
+// FenceToken 获取锁的fencing token，锁未实现FenceTokenLock时返回0
+func FenceToken(lock Lock) int64 {
+	if fl, ok := lock.(FenceTokenLock); ok {
+		return fl.FenceToken()
+	}
+	return 0
 }
 
 type Locker interface {
 	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
 	AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (Lock, error)
+	AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) // 持续尝试获取锁，最多等待maxWait
+	AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error)                  // 按key排序后依次获取多个锁，全部成功或全部释放
+	Ping(ctx context.Context) error                                                                      // 检查后端是否可用，可用于启动探针
+}
+
+// LockInfo 锁信息
+type LockInfo struct {
+	Key        string    // 锁的key
+	Value      string    // 锁的值(UUID)，用于标识持有者
+	ExpireTime time.Time // 过期时间
+	CreatedAt  time.Time // 创建时间
+	Owner      string    // 持有者，格式为"host:pid"，未配置Columns.Owner时为空
+}
+
+// DatabaseLocker 基于数据库的分布式锁，额外提供查询锁信息的能力
+type DatabaseLocker interface {
+	Locker
+	ListLocks(ctx context.Context, includeExpired bool) ([]LockInfo, error) // 列出当前的锁，includeExpired为false时过滤已过期的锁
+	StartJanitor(ctx context.Context, interval time.Duration)               // 可选，后台定期清理所有过期的锁记录，ctx取消时停止，清理失败通过WithJanitorErrorHandler回调
 }
 
 // NewDatabaseLocker 基于Redis的分布式锁，SetNX加锁，LUA脚本释放
-func NewRedisLocker(cli *redis.Client) Locker {
-	return newRedisLocker(cli)
+func NewRedisLocker(cli *redis.Client, opts ...RedisLockerOption) Locker {
+	return newRedisLocker(cli, opts...)
 }
 
 // NewDatabaseLocker 基于数据库的分布式锁，唯一索引+Insert实现，兼容MySQL、PostgreSQL、SQLite
-func NewDatabaseLocker(db *gorm.DB, table string) Locker {
-	return newDatabaseLocker(db, table)
+func NewDatabaseLocker(db *gorm.DB, table string, opts ...DatabaseLockerOption) DatabaseLocker {
+	return newDatabaseLocker(db, table, opts...)
+}
+
+// NewLocalLocker 进程内的锁，不依赖外部存储，不支持fencing token，可与NewLayeredLocker组合使用
+func NewLocalLocker() Locker {
+	return newLocalLocker()
+}
+
+// NewLayeredLocker 先获取local锁再获取distributed锁，Unlock时都释放
+// 同一进程内高并发竞争同一个key时，同一时刻只有一个调用方访问distributed，减少redis/数据库的压力
+// local通常为NewLocalLocker，ttl、重试参数同时作用于两层
+func NewLayeredLocker(local, distributed Locker) Locker {
+	return newLayeredLocker(local, distributed)
+}
+
+// NewRowLocker 基于数据库行锁的分布式锁，兼容MySQL 8.0+、PostgreSQL 9.5+
+// 每个key对应锁表中的一行哨兵记录，获取锁时开启事务并使用SELECT ... FOR UPDATE SKIP LOCKED锁定该行，Unlock时提交事务
+// 锁的生命周期与事务绑定：持有期间占用一个数据库连接，连接断开或ttl到期时事务回滚、锁自动释放
+// 表结构与NewDatabaseLocker相同(见ddl.sql)，table为空时使用distributed_row_lock，不能与NewDatabaseLocker共用同一张表
+func NewRowLocker(db *gorm.DB, table string, opts ...DatabaseLockerOption) Locker {
+	return newRowLocker(db, table, opts...)
 }
@@ -4,16 +4,56 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// lockTokenLua 定义token函数，取出锁的值中的UUID，开启WithOwnerMetadata时锁的值为JSON
+const lockTokenLua = `
+	local function token(v)
+		if type(v) == "string" and string.sub(v, 1, 1) == "{" then
+			local ok, m = pcall(cjson.decode, v)
+			if ok and type(m) == "table" then
+				return m["value"]
+			end
+		end
+		return v
+	end
+`
+
+// acquireFenceScript 加锁成功后递增fencing token计数器，返回新的token，加锁失败返回0
+var acquireFenceScript = redis.NewScript(`
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return redis.call("INCR", KEYS[2])
+	end
+	return 0
+`)
+
+// refreshScript 锁的值匹配时重置过期时间，返回1表示成功，0表示锁不再属于自己
+var refreshScript = redis.NewScript(lockTokenLua + `
+	if token(redis.call("GET", KEYS[1])) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+// unlockScript 锁的值匹配时才删除，保证原子性，返回1表示成功，0表示锁不再属于自己
+var unlockScript = redis.NewScript(lockTokenLua + `
+	if token(redis.call("GET", KEYS[1])) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
 type redisLock struct {
 	client    *redis.Client
 	lockKey   string
 	lockValue string // UUID 值，用于安全释放锁
+	token     int64  // fencing token，未开启时为0
+	notify    bool   // 释放后是否发布通知
 	mu        sync.Mutex
 	unlocked  bool // 标记是否已释放
 }
@@ -25,16 +65,7 @@ func (l *redisLock) Unlock(ctx context.Context) error {
 		return nil // 幂等性：已经释放的锁再次释放不报错
 	}
 
-	// 使用 Lua 脚本确保原子性：只有锁的值匹配时才删除
-	script := redis.NewScript(`
-		if redis.call("GET", KEYS[1]) == ARGV[1] then
-			return redis.call("DEL", KEYS[1])
-		else
-			return 0
-		end
-	`)
-
-	result, err := script.Run(ctx, l.client, []string{l.lockKey}, l.lockValue).Int64()
+	result, err := unlockScript.Run(ctx, l.client, []string{l.lockKey}, l.lockValue).Int64()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			// 锁已经过期或被删除
@@ -50,17 +81,128 @@ func (l *redisLock) Unlock(ctx context.Context) error {
 	}
 
 	l.unlocked = true
+
+	// 通知等待者，发布失败不影响释放结果，等待者会退化为轮询
+	if l.notify {
+		_ = l.client.Publish(ctx, notifyChannel(l.lockKey), l.lockValue).Err()
+	}
 	return nil
 }
 
-func newRedisLocker(client *redis.Client) *redisLocker {
-	return &redisLocker{
+func (l *redisLock) Key() string {
+	return l.lockKey
+}
+
+func (l *redisLock) Value() string {
+	return l.lockValue
+}
+
+func (l *redisLock) FenceToken() int64 {
+	return l.token
+}
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return ErrLockNotHeld
+	}
+
+	// PEXPIRE最小为1ms
+	ttlMs := max(ttl.Milliseconds(), 1)
+	result, err := refreshScript.Run(ctx, l.client, []string{l.lockKey}, l.lockValue, ttlMs).Int64()
+	if err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	if result == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// RedisLockerOption redis锁配置选项
+type RedisLockerOption func(*redisLocker)
+
+// WithUnlockNotify 设置是否开启释放通知
+// 开启后Unlock会PUBLISH到锁对应的channel，AcquireWithRetry、AcquireWait订阅该channel，
+// 锁释放时立即重试，而不是等待完整的重试间隔；错过通知时仍按间隔轮询兜底
+func WithUnlockNotify(enable bool) RedisLockerOption {
+	return func(r *redisLocker) {
+		r.notify = enable
+	}
+}
+
+// WithFenceToken 设置是否为锁分配fencing token
+// 开启后加锁与递增计数器通过LUA脚本原子执行，每个key对应一个"dlock:fence:{hash tag}:key"计数器，与锁的key在同一个slot，
+// 通过FenceToken(lock)获取，同一个key的token单调递增；计数器不设置过期时间，会一直保留
+// key包含"}"但没有hash tag时(如"a}b")计数器无法与锁在同一个slot，Acquire返回ErrInvalidKey，应为key加上hash tag，如"{a}b"
+func WithFenceToken(enable bool) RedisLockerOption {
+	return func(r *redisLocker) {
+		r.fence = enable
+	}
+}
+
+// WithOwnerMetadata 设置是否在锁的值中存储持有者信息
+// 开启后redis中锁的值为JSON: {"value":UUID,"host":...,"pid":...,"acquiredAt":...}，便于排查长时间未释放的锁，
+// 释放、续期时只比较其中的value；Lock.Value仍返回UUID
+func WithOwnerMetadata(enable bool) RedisLockerOption {
+	return func(r *redisLocker) {
+		r.owner = enable
+	}
+}
+
+func newRedisLocker(client *redis.Client, opts ...RedisLockerOption) *redisLocker {
+	r := &redisLocker{
 		client: client,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 type redisLocker struct {
 	client *redis.Client
+	notify bool // 是否开启释放通知
+	fence  bool // 是否分配fencing token
+	owner  bool // 是否在锁的值中存储持有者信息
+}
+
+// notifyChannel 锁释放通知的channel
+func notifyChannel(key string) string {
+	return "dlock:unlock:" + key
+}
+
+// fenceKey fencing token计数器的key，使用锁的key的hash tag，Redis Cluster下与锁的key在同一个slot，LUA脚本可以同时访问
+func fenceKey(key string) string {
+	return "dlock:fence:{" + hashTag(key) + "}:" + key
+}
+
+// hashTag Redis Cluster计算slot使用的部分：key包含非空的"{...}"时为第一个花括号内的内容，否则为整个key
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// subscribe 订阅锁释放通知，未开启或订阅失败时返回nil channel，退化为轮询
+func (r *redisLocker) subscribe(ctx context.Context, key string) (<-chan *redis.Message, func()) {
+	if !r.notify {
+		return nil, func() {}
+	}
+	sub := r.client.Subscribe(ctx, notifyChannel(key))
+	// 等待订阅生效，避免错过订阅前的释放通知
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, func() {}
+	}
+	return sub.Channel(), func() { _ = sub.Close() }
 }
 
 func (r *redisLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
@@ -70,8 +212,13 @@ func (r *redisLocker) Acquire(ctx context.Context, key string, ttl time.Duration
 	}
 
 	value := lockValue()
+	stored := r.storedValue(value)
 
-	success, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	if r.fence {
+		return r.acquireWithFence(ctx, key, value, stored, ttl)
+	}
+
+	success, err := r.client.SetNX(ctx, key, stored, ttl).Result()
 	if err != nil {
 		return nil, fmt.Errorf("redis error: %w", err)
 	}
@@ -83,6 +230,40 @@ func (r *redisLocker) Acquire(ctx context.Context, key string, ttl time.Duration
 		client:    r.client,
 		lockKey:   key,
 		lockValue: value,
+		notify:    r.notify,
+	}, nil
+}
+
+// storedValue redis中存储的锁的值，开启WithOwnerMetadata时为包含持有者信息的JSON
+func (r *redisLocker) storedValue(value string) string {
+	if r.owner {
+		return newLockOwner(value).JSON()
+	}
+	return value
+}
+
+// acquireWithFence 加锁并分配fencing token
+func (r *redisLocker) acquireWithFence(ctx context.Context, key string, value string, stored string, ttl time.Duration) (Lock, error) {
+	// PX最小为1ms
+	ttlMs := max(ttl.Milliseconds(), 1)
+	// 计数器与锁的key不在同一个slot时Redis Cluster返回CROSSSLOT，提前拒绝
+	if hashTag(fenceKey(key)) != hashTag(key) {
+		return nil, fmt.Errorf("%w: key contains '}' without a hash tag", ErrInvalidKey)
+	}
+	token, err := acquireFenceScript.Run(ctx, r.client, []string{key, fenceKey(key)}, stored, ttlMs).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if token == 0 {
+		return nil, ErrLockAlreadyHeld
+	}
+
+	return &redisLock{
+		client:    r.client,
+		lockKey:   key,
+		lockValue: value,
+		token:     token,
+		notify:    r.notify,
 	}, nil
 }
 
@@ -95,6 +276,9 @@ func (r *redisLocker) AcquireWithRetry(ctx context.Context, key string, ttl time
 		maxRetry = 0
 	}
 
+	wake, unsubscribe := r.subscribe(ctx, key)
+	defer unsubscribe()
+
 	for i := int64(0); i <= maxRetry; i++ {
 		select {
 		case <-ctx.Done():
@@ -107,10 +291,12 @@ func (r *redisLocker) AcquireWithRetry(ctx context.Context, key string, ttl time
 			return lock, nil
 		}
 
-		// 等待后重试
+		// 等待后重试，收到释放通知时立即重试
 		select {
 		case <-time.After(interval):
 			continue
+		case <-wake:
+			continue
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
@@ -118,3 +304,29 @@ func (r *redisLocker) AcquireWithRetry(ctx context.Context, key string, ttl time
 
 	return nil, ErrLockNotAcquired
 }
+
+func (r *redisLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+
+	wake, unsubscribe := r.subscribe(ctx, key)
+	defer unsubscribe()
+
+	return acquireWait(ctx, maxWait, wake, func(ctx context.Context) (Lock, error) {
+		return r.Acquire(ctx, key, ttl)
+	})
+}
+
+func (r *redisLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error) {
+	return acquireMulti(ctx, keys, ttl, r.Acquire)
+}
+
+// Ping 发送PING检查redis连接
+func (r *redisLocker) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis error: %w", err)
+	}
+	return nil
+}
@@ -2,12 +2,18 @@ package dlock
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -366,6 +372,61 @@ func TestRedisLocker(t *testing.T) {
 		// 清理
 		client.Del(ctx, "atomic-key")
 	})
+
+	t.Run("TestLockKeyValue", func(t *testing.T) {
+		locker := newRedisLocker(client)
+		lock, err := locker.Acquire(ctx, "key-value-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "key-value-key", lock.Key())
+
+		// 验证value与redis中存储的一致
+		val, err := client.Get(ctx, "key-value-key").Result()
+		require.NoError(t, err)
+		assert.Equal(t, val, lock.Value())
+		_, err = uuid.Parse(lock.Value())
+		assert.NoError(t, err)
+
+		err = lock.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestAcquireWait", func(t *testing.T) {
+		locker := newRedisLocker(client)
+
+		// 持有锁，100ms后释放
+		lock1, err := locker.Acquire(ctx, "wait-key", 10*time.Second)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = lock1.Unlock(ctx)
+		}()
+
+		// 等待窗口内释放，可以获取到锁
+		start := time.Now()
+		lock2, err := locker.AcquireWait(ctx, "wait-key", 10*time.Second, 2*time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, lock2)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// 等待窗口内未释放，超时
+		start = time.Now()
+		_, err = locker.AcquireWait(ctx, "wait-key", 10*time.Second, 200*time.Millisecond)
+		assert.Equal(t, ErrLockNotAcquired, err)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+		// ctx取消
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = locker.AcquireWait(cctx, "wait-key", 10*time.Second, 2*time.Second)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		err = lock2.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestAcquireMulti", func(t *testing.T) {
+		testAcquireMulti(t, newRedisLocker(client))
+	})
 }
 
 func TestRedisLockConcurrent(t *testing.T) {
@@ -414,3 +475,422 @@ func TestRedisLockConcurrent(t *testing.T) {
 	// 在并发情况下，应该只有一个goroutine能成功获取锁
 	assert.Equal(t, int32(1), atomic.LoadInt32(&successCount))
 }
+
+func TestRedisLockNotify(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("TestAcquireWithRetryWakeOnUnlock", func(t *testing.T) {
+		locker := newRedisLocker(client, WithUnlockNotify(true))
+		lock1, err := locker.Acquire(ctx, "notify-key", 10*time.Second)
+		require.NoError(t, err)
+
+		released := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = lock1.Unlock(ctx)
+			released <- time.Now()
+		}()
+
+		// 重试间隔5s，收到通知后立即获取
+		lock2, err := locker.AcquireWithRetry(ctx, "notify-key", 10*time.Second, 3, 5*time.Second)
+		require.NoError(t, err)
+		acquired := time.Now()
+		assert.Less(t, acquired.Sub(<-released), 200*time.Millisecond)
+
+		err = lock2.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestAcquireWaitWakeOnUnlock", func(t *testing.T) {
+		locker := newRedisLocker(client, WithUnlockNotify(true))
+		lock1, err := locker.Acquire(ctx, "notify-wait-key", 10*time.Second)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = lock1.Unlock(ctx)
+		}()
+
+		lock2, err := locker.AcquireWait(ctx, "notify-wait-key", 10*time.Second, 5*time.Second)
+		require.NoError(t, err)
+		err = lock2.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestPollingFallback", func(t *testing.T) {
+		locker := newRedisLocker(client, WithUnlockNotify(true))
+		// 锁过期不会发布通知，依赖轮询获取
+		_, err := locker.Acquire(ctx, "notify-expire-key", 10*time.Second)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			client.Del(ctx, "notify-expire-key")
+		}()
+
+		lock, err := locker.AcquireWithRetry(ctx, "notify-expire-key", 10*time.Second, 5, 100*time.Millisecond)
+		require.NoError(t, err)
+		err = lock.Unlock(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("TestNotifyDisabled", func(t *testing.T) {
+		locker := newRedisLocker(client)
+		lock1, err := locker.Acquire(ctx, "no-notify-key", 10*time.Second)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = lock1.Unlock(ctx)
+		}()
+
+		// 未开启通知，需要等待完整间隔
+		start := time.Now()
+		lock2, err := locker.AcquireWithRetry(ctx, "no-notify-key", 10*time.Second, 3, 300*time.Millisecond)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+		err = lock2.Unlock(ctx)
+		require.NoError(t, err)
+	})
+}
+
+func TestRedisLockFenceToken(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("TestTokenIncreasing", func(t *testing.T) {
+		locker := newRedisLocker(client, WithFenceToken(true))
+
+		var last int64
+		for i := 0; i < 3; i++ {
+			lock, err := locker.Acquire(ctx, "fence-key", 10*time.Second)
+			require.NoError(t, err)
+			assert.Greater(t, FenceToken(lock), last)
+			last = FenceToken(lock)
+			require.NoError(t, lock.Unlock(ctx))
+		}
+
+		// 其他key有独立的计数器
+		lock, err := locker.Acquire(ctx, "fence-other-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), FenceToken(lock))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestTokenAfterExpire", func(t *testing.T) {
+		locker := newRedisLocker(client, WithFenceToken(true))
+
+		stale, err := locker.Acquire(ctx, "fence-expire-key", time.Second)
+		require.NoError(t, err)
+		_, err = locker.Acquire(ctx, "fence-expire-key", time.Second)
+		assert.Equal(t, ErrLockAlreadyHeld, err)
+
+		// 旧持有者的锁过期后被重新获取，新token更大
+		s.FastForward(2 * time.Second)
+		lock, err := locker.AcquireWithRetry(ctx, "fence-expire-key", 10*time.Second, 3, 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, FenceToken(stale)+1, FenceToken(lock))
+		assert.Equal(t, ErrLockNotHeld, stale.Unlock(ctx))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("TestFenceKeySlot", func(t *testing.T) {
+		locker := newRedisLocker(client, WithFenceToken(true))
+		lock, err := locker.Acquire(ctx, "fence-slot-key", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+		got, err := s.Get("dlock:fence:{fence-slot-key}:fence-slot-key")
+		require.NoError(t, err)
+		assert.Equal(t, "1", got)
+
+		// 计数器与锁的key的hash tag相同，Redis Cluster下在同一个slot
+		for key, tag := range map[string]string{
+			"order:1":       "order:1",
+			"{user:1}:a":    "user:1",
+			"a{user:1}b{c}": "user:1",
+		} {
+			assert.Equal(t, tag, hashTag(key), key)
+			assert.Equal(t, hashTag(key), hashTag(fenceKey(key)), key)
+		}
+
+		// 没有hash tag的key包含"}"时无法放在同一个slot
+		for _, key := range []string{"{}a", "a}b"} {
+			_, err := locker.Acquire(ctx, key, 10*time.Second)
+			assert.ErrorIs(t, err, ErrInvalidKey, key)
+		}
+	})
+
+	t.Run("TestFenceDisabled", func(t *testing.T) {
+		locker := newRedisLocker(client)
+		lock, err := locker.Acquire(ctx, "no-fence-key", 10*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), FenceToken(lock))
+		require.NoError(t, lock.Unlock(ctx))
+	})
+}
+
+func TestRedisLockerPing(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	locker := NewRedisLocker(client)
+
+	t.Run("TestHealthy", func(t *testing.T) {
+		assert.NoError(t, locker.Ping(ctx))
+	})
+
+	t.Run("TestUnreachable", func(t *testing.T) {
+		s.Close()
+		err := locker.Ping(ctx)
+		assert.ErrorContains(t, err, "redis error")
+	})
+}
+
+func TestRedisLockOwnerMetadata(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	for _, fence := range []bool{false, true} {
+		t.Run(fmt.Sprintf("TestFence=%v", fence), func(t *testing.T) {
+			locker := newRedisLocker(client, WithOwnerMetadata(true), WithFenceToken(fence))
+			before := time.Now()
+			lock, err := locker.Acquire(ctx, "owner-key", 10*time.Second)
+			require.NoError(t, err)
+
+			raw, err := s.Get("owner-key")
+			require.NoError(t, err)
+			var owner lockOwner
+			require.NoError(t, json.Unmarshal([]byte(raw), &owner))
+			// Lock.Value仍是UUID，redis中存储带持有者信息的JSON
+			assert.Equal(t, lock.Value(), owner.Value)
+			assert.Equal(t, host, owner.Host)
+			assert.Equal(t, os.Getpid(), owner.PID)
+			assert.False(t, owner.AcquiredAt.Before(before.Truncate(time.Second)))
+
+			_, err = locker.Acquire(ctx, "owner-key", 10*time.Second)
+			assert.ErrorIs(t, err, ErrLockAlreadyHeld)
+
+			require.NoError(t, RefreshLock(ctx, lock, 20*time.Second))
+			assert.Equal(t, 20*time.Second, s.TTL("owner-key"))
+			require.NoError(t, lock.Unlock(ctx))
+			assert.False(t, s.Exists("owner-key"))
+		})
+	}
+
+	t.Run("TestNotHeld", func(t *testing.T) {
+		locker := newRedisLocker(client, WithOwnerMetadata(true))
+		lock, err := locker.Acquire(ctx, "owner-stolen", 10*time.Second)
+		require.NoError(t, err)
+
+		// 锁被其他持有者重新获取
+		require.NoError(t, s.Set("owner-stolen", newLockOwner(lockValue()).JSON()))
+		assert.ErrorIs(t, RefreshLock(ctx, lock, 10*time.Second), ErrLockNotHeld)
+		assert.ErrorIs(t, lock.Unlock(ctx), ErrLockNotHeld)
+		assert.True(t, s.Exists("owner-stolen"))
+	})
+
+	t.Run("TestMixedWithPlainValue", func(t *testing.T) {
+		// 未开启的locker获取的锁仍可以正常释放
+		lock, err := newRedisLocker(client).Acquire(ctx, "owner-plain", 10*time.Second)
+		require.NoError(t, err)
+		raw, err := s.Get("owner-plain")
+		require.NoError(t, err)
+		assert.Equal(t, lock.Value(), raw)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+}
+
+func TestRedisLockBackendError(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	for _, fence := range []bool{false, true} {
+		t.Run(fmt.Sprintf("TestFence=%v", fence), func(t *testing.T) {
+			locker := NewRedisLocker(client, WithFenceToken(fence))
+
+			// 模拟redis返回错误
+			s.SetError("LOADING Redis is loading the dataset in memory")
+			defer s.SetError("")
+			_, err := locker.Acquire(ctx, "backend-key", 10*time.Second)
+			assert.ErrorContains(t, err, "redis error")
+			assert.ErrorContains(t, err, "LOADING")
+			assert.NotErrorIs(t, err, ErrLockAlreadyHeld)
+		})
+	}
+
+	t.Run("TestUnreachable", func(t *testing.T) {
+		locker := NewRedisLocker(client)
+		s.Close()
+		_, err := locker.Acquire(ctx, "backend-key", 10*time.Second)
+		assert.ErrorContains(t, err, "redis error")
+		assert.NotErrorIs(t, err, ErrLockAlreadyHeld)
+	})
+}
+
+// BenchmarkRedisAcquireRelease 无竞争时加锁、释放锁的吞吐
+func BenchmarkRedisAcquireRelease(b *testing.B) {
+	s, err := miniredis.Run()
+	require.NoError(b, err)
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	locker := newRedisLocker(client)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lock, err := locker.Acquire(ctx, "bench-key", 10*time.Second)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := lock.Unlock(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestRedisUnlockScript 所有Unlock共用同一个脚本，首次EVALSHA未命中后由go-redis加载，之后直接使用EVALSHA
+func TestRedisUnlockScript(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	counter := &scriptCounter{hash: unlockScript.Hash()}
+	client.AddHook(counter)
+
+	ctx := context.Background()
+	locker := newRedisLocker(client)
+	for i := 0; i < 3; i++ {
+		lock, err := locker.Acquire(ctx, "script-key", 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	}
+	// 新的redis上第一次EVALSHA返回NOSCRIPT后EVAL加载脚本，之后都只发送sha
+	assert.Equal(t, 3, counter.count("evalsha"))
+	assert.Equal(t, 1, counter.count("eval"))
+	assert.Zero(t, counter.count("script"))
+}
+
+// scriptCounter 统计执行指定脚本的命令次数
+type scriptCounter struct {
+	hash string
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *scriptCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (c *scriptCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (c *scriptCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			c.record(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (c *scriptCounter) record(cmd redis.Cmder) {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return
+	}
+	// EVAL/SCRIPT LOAD的参数是脚本内容，EVALSHA的参数是sha
+	arg := fmt.Sprint(args[len(args)-1])
+	if name := cmd.Name(); name == "eval" || name == "evalsha" {
+		arg = fmt.Sprint(args[1])
+	}
+	sum := sha1.Sum([]byte(arg))
+	if arg != c.hash && hex.EncodeToString(sum[:]) != c.hash {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[cmd.Name()]++
+}
+
+func (c *scriptCounter) count(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[name]
+}
+
+// BenchmarkRedisUnlock 只统计Unlock的开销
+func BenchmarkRedisUnlock(b *testing.B) {
+	s, err := miniredis.Run()
+	require.NoError(b, err)
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	locker := newRedisLocker(client)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		lock, err := locker.Acquire(ctx, "bench-key", 10*time.Second)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := lock.Unlock(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
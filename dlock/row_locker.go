@@ -0,0 +1,245 @@
+package dlock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultRowLockTable = "distributed_row_lock"
+
+// rowLock 持有事务中对哨兵行的行锁，事务结束(提交、回滚、连接断开)时数据库自动释放
+type rowLock struct {
+	tx        *gorm.DB
+	lockKey   string
+	lockValue string
+	timer     *time.Timer // ttl到期时回滚事务
+	mu        sync.Mutex
+	unlocked  bool // 标记是否已释放
+	expired   bool // ttl到期，事务已回滚
+}
+
+// Unlock 提交事务释放行锁，ttl到期事务已回滚时返回ErrLockNotHeld
+func (l *rowLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked {
+		return nil // 幂等性：已经释放的锁再次释放不报错
+	}
+	if l.expired {
+		return ErrLockNotHeld
+	}
+	l.timer.Stop()
+	l.unlocked = true
+	if err := l.tx.Commit().Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+func (l *rowLock) Key() string {
+	return l.lockKey
+}
+
+func (l *rowLock) Value() string {
+	return l.lockValue
+}
+
+// Refresh 重置ttl，并检查事务所在的连接是否可用，连接断开时数据库已释放行锁
+func (l *rowLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked || l.expired {
+		return ErrLockNotHeld
+	}
+	if err := l.tx.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	l.timer.Reset(ttl)
+	return nil
+}
+
+// expire ttl到期，回滚事务释放行锁
+func (l *rowLock) expire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked || l.expired {
+		return
+	}
+	l.expired = true
+	_ = l.tx.Rollback().Error
+}
+
+type rowLocker struct {
+	db        *gorm.DB
+	tableName string
+	columns   Columns
+}
+
+func newRowLocker(db *gorm.DB, table string, opts ...DatabaseLockerOption) *rowLocker {
+	if table == "" {
+		table = defaultRowLockTable
+	}
+	// 复用数据库锁的列名配置
+	cfg := newDatabaseLocker(db, table, opts...)
+	return &rowLocker{
+		db:        cfg.db,
+		tableName: cfg.tableName,
+		columns:   cfg.columns,
+	}
+}
+
+// Acquire 确保key的哨兵行存在，开启事务后使用SELECT ... FOR UPDATE SKIP LOCKED锁定该行
+// 行已被其他事务锁定时立即返回ErrLockAlreadyHeld
+func (rl *rowLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+	if err := rl.ensureRow(rl.db.WithContext(ctx), key); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// 事务需要在Acquire返回后继续持有，不随ctx取消而回滚
+	tx := rl.db.WithContext(context.WithoutCancel(ctx)).Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("database error: %w", tx.Error)
+	}
+	var keys []string
+	if err := rl.lockRow(tx.WithContext(ctx), key, &keys).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if len(keys) == 0 {
+		// 哨兵行被其他事务锁定，已跳过
+		tx.Rollback()
+		return nil, ErrLockAlreadyHeld
+	}
+
+	lock := &rowLock{
+		tx:        tx,
+		lockKey:   key,
+		lockValue: lockValue(),
+	}
+	lock.timer = time.AfterFunc(ttl, lock.expire)
+	return lock, nil
+}
+
+// lockRow 在事务中锁定key的哨兵行，已被其他事务锁定时跳过，dest为空
+func (rl *rowLocker) lockRow(tx *gorm.DB, key string, dest *[]string) *gorm.DB {
+	return tx.Table(rl.tableName).
+		Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+		Select("?", clause.Column{Name: rl.columns.Key}).
+		Where(clause.Eq{Column: clause.Column{Name: rl.columns.Key}, Value: key}).
+		Find(dest)
+}
+
+// ensureRow 确保key的哨兵行存在
+// 不使用ON DUPLICATE KEY UPDATE/INSERT IGNORE，MySQL中哨兵行被其他事务锁定时这类语句会阻塞到锁释放，
+// 先用不加锁的查询判断，只在不存在时插入，并发插入导致主键冲突时视为已存在
+func (rl *rowLocker) ensureRow(db *gorm.DB, key string) error {
+	var keys []string
+	if err := rl.findRow(db, key, &keys).Error; err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return nil
+	}
+	err := rl.insertRow(db, key).Error
+	if err == nil {
+		return nil
+	}
+	// 未开启TranslateError时无法识别主键冲突，重新查询确认哨兵行是否已被并发插入
+	if findErr := rl.findRow(db, key, &keys).Error; findErr == nil && len(keys) > 0 {
+		return nil
+	}
+	return err
+}
+
+// findRow 不加锁查询key的哨兵行
+func (rl *rowLocker) findRow(db *gorm.DB, key string, dest *[]string) *gorm.DB {
+	return db.Table(rl.tableName).
+		Select("?", clause.Column{Name: rl.columns.Key}).
+		Where(clause.Eq{Column: clause.Column{Name: rl.columns.Key}, Value: key}).
+		Limit(1).
+		Find(dest)
+}
+
+// insertRow 插入key的哨兵行
+func (rl *rowLocker) insertRow(db *gorm.DB, key string) *gorm.DB {
+	now := time.Now()
+	row := map[string]interface{}{
+		rl.columns.Key:        key,
+		rl.columns.Value:      "",
+		rl.columns.ExpireTime: now,
+		rl.columns.CreatedAt:  now,
+		rl.columns.UpdatedAt:  now,
+	}
+	return db.Table(rl.tableName).Create(row)
+}
+
+// AcquireWithRetry 带重试获取锁
+func (rl *rowLocker) AcquireWithRetry(ctx context.Context, key string, ttl time.Duration, maxRetry int64, interval time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+	if maxRetry < 0 {
+		maxRetry = 0
+	}
+
+	for i := int64(0); i <= maxRetry; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		lock, err := rl.Acquire(ctx, key, ttl)
+		if err == nil {
+			return lock, nil
+		}
+
+		// 等待后重试
+		select {
+		case <-time.After(interval):
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, ErrLockNotAcquired
+}
+
+// AcquireWait 持续尝试获取锁，最多等待maxWait
+func (rl *rowLocker) AcquireWait(ctx context.Context, key string, ttl time.Duration, maxWait time.Duration) (Lock, error) {
+	// 参数校验
+	if err := validateKeyAndTTL(key, ttl); err != nil {
+		return nil, err
+	}
+
+	return acquireWait(ctx, maxWait, (<-chan struct{})(nil), func(ctx context.Context) (Lock, error) {
+		return rl.Acquire(ctx, key, ttl)
+	})
+}
+
+func (rl *rowLocker) AcquireMulti(ctx context.Context, keys []string, ttl time.Duration) ([]Lock, error) {
+	return acquireMulti(ctx, keys, ttl, rl.Acquire)
+}
+
+// Ping 查询锁表检查数据库连接，同时可发现锁表不存在
+func (rl *rowLocker) Ping(ctx context.Context) error {
+	var ones []int
+	if err := rl.db.WithContext(ctx).Table(rl.tableName).Select("1").Limit(1).Scan(&ones).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package dlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRowLockValidation 参数校验不依赖数据库的行锁语义
+func TestRowLockValidation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	locker := NewRowLocker(db, "")
+	ctx := context.Background()
+
+	_, err = locker.Acquire(ctx, "", time.Second)
+	assert.Equal(t, ErrInvalidKey, err)
+	_, err = locker.Acquire(ctx, "test-key", 0)
+	assert.Equal(t, ErrInvalidTTL, err)
+	_, err = locker.AcquireWait(ctx, "test-key", -time.Second, time.Second)
+	assert.Equal(t, ErrInvalidTTL, err)
+
+	// 锁表不存在
+	assert.Error(t, locker.Ping(ctx))
+}
@@ -0,0 +1,35 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type lockCtxKey struct{}
+
+// ContextWithLock 将锁放入ctx，临界区内可通过LockFromContext获取，用于在日志中输出锁的key、value、fencing token
+func ContextWithLock(ctx context.Context, lock Lock) context.Context {
+	return context.WithValue(ctx, lockCtxKey{}, lock)
+}
+
+// LockFromContext 获取ctx中的锁，不在WithLock的临界区内时返回false
+func LockFromContext(ctx context.Context) (Lock, bool) {
+	lock, ok := ctx.Value(lockCtxKey{}).(Lock)
+	return lock, ok
+}
+
+// WithLock 获取锁后执行fn，fn返回后释放锁
+// fn的ctx中携带当前的锁，可通过LockFromContext获取
+// 返回fn的错误与释放锁的错误，释放返回ErrLockNotHeld说明fn执行期间锁已过期，临界区可能被并发执行
+func WithLock(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) (err error) {
+	lock, err := locker.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	// fn panic或ctx取消时也要释放锁，避免等到过期
+	defer func() {
+		err = errors.Join(err, lock.Unlock(context.WithoutCancel(ctx)))
+	}()
+	return fn(ContextWithLock(ctx, lock))
+}
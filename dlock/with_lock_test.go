@@ -0,0 +1,89 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLock(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	locker := NewRedisLocker(client, WithFenceToken(true))
+
+	t.Run("TestLockFromContext", func(t *testing.T) {
+		_, ok := LockFromContext(ctx)
+		assert.False(t, ok)
+
+		var got Lock
+		err := WithLock(ctx, locker, "with-lock-key", 10*time.Second, func(ctx context.Context) error {
+			lock, ok := LockFromContext(ctx)
+			require.True(t, ok)
+			got = lock
+			// 临界区内锁被持有
+			assert.True(t, s.Exists("with-lock-key"))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "with-lock-key", got.Key())
+		assert.NotEmpty(t, got.Value())
+		assert.Greater(t, FenceToken(got), int64(0))
+		// 返回后锁已释放
+		assert.False(t, s.Exists("with-lock-key"))
+	})
+
+	t.Run("TestFnError", func(t *testing.T) {
+		fnErr := errors.New("fn failed")
+		err := WithLock(ctx, locker, "with-lock-error", 10*time.Second, func(ctx context.Context) error {
+			return fnErr
+		})
+		assert.ErrorIs(t, err, fnErr)
+		assert.False(t, s.Exists("with-lock-error"))
+	})
+
+	t.Run("TestLockHeld", func(t *testing.T) {
+		lock, err := locker.Acquire(ctx, "with-lock-held", 10*time.Second)
+		require.NoError(t, err)
+		defer lock.Unlock(ctx)
+
+		called := false
+		err = WithLock(ctx, locker, "with-lock-held", 10*time.Second, func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrLockAlreadyHeld)
+		assert.False(t, called)
+	})
+
+	t.Run("TestLockLost", func(t *testing.T) {
+		err := WithLock(ctx, locker, "with-lock-lost", 10*time.Second, func(ctx context.Context) error {
+			// 临界区执行期间锁被其他持有者获取
+			return s.Set("with-lock-lost", "other")
+		})
+		assert.ErrorIs(t, err, ErrLockNotHeld)
+	})
+
+	t.Run("TestPanicReleasesLock", func(t *testing.T) {
+		assert.Panics(t, func() {
+			_ = WithLock(ctx, locker, "with-lock-panic", 10*time.Second, func(ctx context.Context) error {
+				panic("boom")
+			})
+		})
+		assert.False(t, s.Exists("with-lock-panic"))
+	})
+}
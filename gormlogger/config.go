@@ -23,6 +23,11 @@ type config struct {
 	// 决定了哪些 SQL 会被记录
 	// 默认: logger.Warn
 	logLevel logger.LogLevel
+
+	// name 数据库连接名称
+	// 不为空时每条日志都会带上 db=name 字段，用于区分多个数据库连接
+	// 默认: ""
+	name string
 }
 
 // defaultConfig 返回默认配置
@@ -104,3 +109,23 @@ func WithLogLevel(level logger.LogLevel) Option {
 		c.logLevel = level
 	}
 }
+
+// WithName 设置数据库连接名称
+//
+// 参数:
+//
+//	name - 连接名称，如 "primary"、"analytics"、"shard-1"
+//
+// 作用:
+//   - 每条日志都会带上 db=name 字段
+//   - GORM 可以为每个 *gorm.DB 设置独立的 logger，多数据库场景下可以区分日志来自哪个连接
+//
+// 示例:
+//
+//	primary, _ := gorm.Open(dsn1, &gorm.Config{Logger: gormlogger.New(gormlogger.WithName("primary"))})
+//	analytics, _ := gorm.Open(dsn2, &gorm.Config{Logger: gormlogger.New(gormlogger.WithName("analytics"))})
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
@@ -32,3 +32,10 @@ func TestWithLogLevel(t *testing.T) {
 	WithLogLevel(logger.Info)(cfg)
 	assert.Equal(t, logger.Info, cfg.logLevel)
 }
+
+func TestWithName(t *testing.T) {
+	cfg := defaultConfig()
+	assert.Empty(t, cfg.name)
+	WithName("primary")(cfg)
+	assert.Equal(t, "primary", cfg.name)
+}
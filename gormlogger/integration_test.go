@@ -0,0 +1,114 @@
+package gormlogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gLogger "gorm.io/gorm/logger"
+)
+
+type integrationUser struct {
+	ID   uint
+	Name string
+}
+
+// TestGormIntegration 将gormlogger设置为gorm的Logger，执行真实的查询
+func TestGormIntegration(t *testing.T) {
+	buf := &bytes.Buffer{}
+	jsonLogger := logrus.New()
+	jsonLogger.SetOutput(buf)
+	jsonLogger.SetFormatter(&logrus.JSONFormatter{})
+
+	l := New(WithLogLevel(gLogger.Info), WithIgnoreRecordNotFoundError(true)).(*gormLogger)
+	l.ctx = jsonLogger.WithContext
+
+	db, err := gorm.Open(sqlite.Open("file:gormlogger_integration?mode=memory"), &gorm.Config{
+		Logger: l,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&integrationUser{}))
+	buf.Reset()
+
+	// readEntry 读取一条日志并清空
+	readEntry := func(t *testing.T) map[string]interface{} {
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		buf.Reset()
+		require.Len(t, lines, 1)
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &data))
+		return data
+	}
+	// assertSource source字段指向本文件中执行查询的行
+	assertSource := func(t *testing.T, data map[string]interface{}, line int) {
+		_, file, _, _ := runtime.Caller(0)
+		assert.Equal(t, fmt.Sprintf("%s:%d", file, line), data["source"])
+	}
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		user := &integrationUser{Name: "alice"}
+		_, _, line, _ := runtime.Caller(0)
+		require.NoError(t, db.WithContext(ctx).Create(user).Error)
+
+		data := readEntry(t)
+		assert.Equal(t, "info", data["level"])
+		assert.Contains(t, data["sql"], "INSERT INTO `integration_users`")
+		assert.Contains(t, data["sql"], "alice")
+		assert.Equal(t, float64(1), data["rows"])
+		assertSource(t, data, line+1)
+	})
+
+	t.Run("first", func(t *testing.T) {
+		var user integrationUser
+		_, _, line, _ := runtime.Caller(0)
+		require.NoError(t, db.WithContext(ctx).Where("name = ?", "alice").First(&user).Error)
+
+		data := readEntry(t)
+		assert.Contains(t, data["sql"], "SELECT * FROM `integration_users` WHERE name = \"alice\"")
+		assertSource(t, data, line+1)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		_, _, line, _ := runtime.Caller(0)
+		require.NoError(t, db.WithContext(ctx).Model(&integrationUser{}).Where("name = ?", "alice").Update("name", "bob").Error)
+
+		data := readEntry(t)
+		assert.Contains(t, data["sql"], "UPDATE `integration_users` SET `name`=\"bob\"")
+		assert.Equal(t, float64(1), data["rows"])
+		assertSource(t, data, line+1)
+	})
+
+	t.Run("record not found", func(t *testing.T) {
+		var user integrationUser
+		_, _, line, _ := runtime.Caller(0)
+		err := db.WithContext(ctx).Where("name = ?", "alice").First(&user).Error
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// 忽略ErrRecordNotFound，按普通查询输出
+		data := readEntry(t)
+		assert.Equal(t, "info", data["level"])
+		assert.Equal(t, float64(0), data["rows"])
+		assertSource(t, data, line+1)
+	})
+
+	t.Run("sql error", func(t *testing.T) {
+		_, _, line, _ := runtime.Caller(0)
+		err := db.WithContext(ctx).Exec("SELECT * FROM not_exist").Error
+		assert.Error(t, err)
+
+		data := readEntry(t)
+		assert.Equal(t, "error", data["level"])
+		assert.Contains(t, data["error"], "no such table")
+		assertSource(t, data, line+1)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	gLogger "gorm.io/gorm/logger"
 
@@ -16,6 +17,7 @@ import (
 
 type gormLogger struct {
 	cfg *config
+	ctx func(ctx context.Context) *logrus.Entry // 获取日志entry，默认使用全局logger
 }
 
 func New(opts ...Option) gLogger.Interface {
@@ -25,6 +27,7 @@ func New(opts ...Option) gLogger.Interface {
 	}
 	return &gormLogger{
 		cfg: cfg,
+		ctx: logger.Ctx,
 	}
 }
 
@@ -33,24 +36,25 @@ func (l *gormLogger) LogMode(level gLogger.LogLevel) gLogger.Interface {
 	newCfg.logLevel = level
 	return &gormLogger{
 		cfg: &newCfg,
+		ctx: l.ctx,
 	}
 }
 
 func (l *gormLogger) Info(ctx context.Context, s string, args ...interface{}) {
 	if l.cfg.logLevel >= gLogger.Info {
-		logger.Ctx(ctx).Infof(s, args...)
+		l.entry(ctx).Infof(s, args...)
 	}
 }
 
 func (l *gormLogger) Warn(ctx context.Context, s string, args ...interface{}) {
 	if l.cfg.logLevel >= gLogger.Warn {
-		logger.Ctx(ctx).Warnf(s, args...)
+		l.entry(ctx).Warnf(s, args...)
 	}
 }
 
 func (l *gormLogger) Error(ctx context.Context, s string, args ...interface{}) {
 	if l.cfg.logLevel >= gLogger.Error {
-		logger.Ctx(ctx).Errorf(s, args...)
+		l.entry(ctx).Errorf(s, args...)
 	}
 }
 
@@ -62,22 +66,42 @@ func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (stri
 	elapsed := time.Since(begin)
 	sql, rows := fc()
 	src := fileWithLineNum()
+	elapsedMs := float64(elapsed.Nanoseconds()) / 1e6
+
+	// SQL相关信息作为独立字段输出，JSON格式下可以按字段查询
+	entry := func() *logrus.Entry {
+		return l.entry(ctx).WithFields(logrus.Fields{
+			"sql":        sql,
+			"elapsed_ms": elapsedMs,
+			"rows":       rows,
+			"source":     src,
+		})
+	}
 
 	if err != nil && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.cfg.ignoreRecordNotFoundError) {
 		if l.cfg.logLevel >= gLogger.Error {
-			logger.Ctx(ctx).WithError(err).Errorf("%s %s [%s] [rows:%d]", src, sql, elapsed, rows)
+			entry().WithError(err).Errorf("sql error [%.3fms] [rows:%d]", elapsedMs, rows)
 		}
 		return
 	}
 
 	if l.cfg.slowThreshold != 0 && elapsed > l.cfg.slowThreshold && l.cfg.logLevel >= gLogger.Warn {
-		logger.Ctx(ctx).Warnf("%s %s [%s] [rows:%d]", src, sql, elapsed, rows)
+		entry().Warnf("slow sql >= %v [%.3fms] [rows:%d]", l.cfg.slowThreshold, elapsedMs, rows)
 		return
 	}
 
 	if l.cfg.logLevel == gLogger.Info {
-		logger.Ctx(ctx).Infof("%s %s [%s] [rows:%d]", src, sql, elapsed, rows)
+		entry().Infof("sql [%.3fms] [rows:%d]", elapsedMs, rows)
+	}
+}
+
+// entry 获取日志entry，配置了连接名称时带上db字段
+func (l *gormLogger) entry(ctx context.Context) *logrus.Entry {
+	e := l.ctx(ctx)
+	if l.cfg.name != "" {
+		e = e.WithField("db", l.cfg.name)
 	}
+	return e
 }
 
 func fileWithLineNum() string {
@@ -1,7 +1,9 @@
 package gormlogger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 	gLogger "gorm.io/gorm/logger"
 
@@ -224,3 +227,65 @@ func TestSourceLocation(t *testing.T) {
 	assert.Contains(t, logContent, "logger_test.go", "Should contain the caller filename")
 	assert.NotContains(t, logContent, "gormlogger/logger.go", "Should NOT contain the logger library filename as source")
 }
+
+func TestTraceStructuredFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	jsonLogger := logrus.New()
+	jsonLogger.SetOutput(buf)
+	jsonLogger.SetFormatter(&logrus.JSONFormatter{})
+
+	l := New(WithSlowThreshold(100 * time.Millisecond)).(*gormLogger)
+	l.ctx = jsonLogger.WithContext
+	ll := l.LogMode(gLogger.Info)
+
+	ll.Trace(context.Background(), time.Now().Add(-200*time.Millisecond), func() (string, int64) {
+		return "SELECT * FROM users", 3
+	}, nil)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, "warning", data["level"])
+	assert.Equal(t, "SELECT * FROM users", data["sql"])
+	assert.Equal(t, float64(3), data["rows"])
+	elapsed, ok := data["elapsed_ms"].(float64)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, elapsed, float64(200))
+	source, ok := data["source"].(string)
+	require.True(t, ok)
+	assert.NotEmpty(t, source)
+	assert.Contains(t, data["msg"], "rows:3")
+	assert.NotContains(t, data["msg"], "SELECT")
+}
+
+func TestWithNameField(t *testing.T) {
+	buf := &bytes.Buffer{}
+	jsonLogger := logrus.New()
+	jsonLogger.SetOutput(buf)
+	jsonLogger.SetFormatter(&logrus.JSONFormatter{})
+
+	newNamed := func(opts ...Option) gLogger.Interface {
+		l := New(opts...).(*gormLogger)
+		l.ctx = jsonLogger.WithContext
+		return l.LogMode(gLogger.Info)
+	}
+	primary := newNamed(WithName("primary"))
+	analytics := newNamed(WithName("analytics"))
+	unnamed := newNamed()
+
+	ctx := context.Background()
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+	primary.Trace(ctx, time.Now(), fc, nil)
+	analytics.Trace(ctx, time.Now(), fc, nil)
+	analytics.Info(ctx, "info message")
+	unnamed.Trace(ctx, time.Now(), fc, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	dbs := make([]interface{}, 0, len(lines))
+	for _, line := range lines {
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &data))
+		dbs = append(dbs, data["db"])
+	}
+	assert.Equal(t, []interface{}{"primary", "analytics", "analytics", nil}, dbs)
+}
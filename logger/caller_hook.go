@@ -9,10 +9,12 @@ import (
 )
 
 type callerHook struct {
+	skipPackages []string // 额外跳过的包前缀
+	withFunc     bool     // 是否添加调用者函数名
 }
 
-func newCallerHook() *callerHook {
-	return &callerHook{}
+func newCallerHook(skipPackages ...string) *callerHook {
+	return &callerHook{skipPackages: skipPackages}
 }
 
 func (h *callerHook) Levels() []logrus.Level {
@@ -23,10 +25,25 @@ func (h *callerHook) Fire(entry *logrus.Entry) error {
 	frame := h.findCaller()
 	if frame != nil {
 		entry.Data["file"] = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		if h.withFunc {
+			entry.Data["func"] = shortFuncName(frame.Function)
+		}
 	}
 	return nil
 }
 
+// shortFuncName 去掉函数全名中的包路径
+// 如 "example.com/app/pkg.(*Server).Handle" 返回 "(*Server).Handle"
+func shortFuncName(funcName string) string {
+	if i := strings.LastIndexByte(funcName, '/'); i >= 0 {
+		funcName = funcName[i+1:]
+	}
+	if i := strings.IndexByte(funcName, '.'); i >= 0 {
+		funcName = funcName[i+1:]
+	}
+	return funcName
+}
+
 func (h *callerHook) findCaller() *runtime.Frame {
 	// 遍历调用栈，找到第一个不在 logger 包和 logrus 包中的调用者
 	pcs := make([]uintptr, 25)
@@ -39,7 +56,9 @@ func (h *callerHook) findCaller() *runtime.Frame {
 	for {
 		frame, more := frames.Next()
 		if !h.isLoggerPackage(frame.Function) &&
-			!strings.Contains(frame.Function, "sirupsen/logrus") {
+			!strings.Contains(frame.Function, "sirupsen/logrus") &&
+			!strings.HasPrefix(frame.Function, "log.") && // 通过StdLogger转发的标准库log调用
+			!h.isSkipPackage(frame.Function) {
 			return &frame
 		}
 		if !more {
@@ -53,3 +72,13 @@ func (h *callerHook) isLoggerPackage(funcName string) bool {
 	pkg := "github.com/kakkk/gopkg/logger"
 	return strings.HasPrefix(funcName, pkg+".") || strings.HasPrefix(funcName, pkg+"/")
 }
+
+// isSkipPackage 是否在用户配置的跳过列表中，按函数全名前缀匹配
+func (h *callerHook) isSkipPackage(funcName string) bool {
+	for _, pkg := range h.skipPackages {
+		if strings.HasPrefix(funcName, pkg) {
+			return true
+		}
+	}
+	return false
+}
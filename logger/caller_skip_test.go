@@ -0,0 +1,89 @@
+package logger_test
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger"
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+// facadeInfo 模拟业务对logger的封装，返回调用l.Info的行号
+func facadeInfo(l *logrus.Logger, msg string) int {
+	l.Info(msg)
+	_, _, line, _ := runtime.Caller(0)
+	return line - 1
+}
+
+func TestCallerSkipPackages(t *testing.T) {
+	// 返回日志中的调用位置、调用facadeInfo的行号、facadeInfo中调用logger的行号
+	logCaller := func(t *testing.T, opts ...logger.Option) (string, int, int) {
+		l, err := logger.NewLogger(append(opts, logger.WithLineNumber(true), logger.WithJSONFormat(true))...)
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+
+		_, _, line, _ := runtime.Caller(0)
+		facadeLine := facadeInfo(l, "through facade")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		file, _ := entries[0].Fields["file"].(string)
+		return file, line + 1, facadeLine
+	}
+
+	t.Run("default reports facade", func(t *testing.T) {
+		file, _, facadeLine := logCaller(t)
+		assert.True(t, strings.HasSuffix(file, "caller_skip_test.go:"+strconv.Itoa(facadeLine)), file)
+	})
+
+	t.Run("skip facade", func(t *testing.T) {
+		file, line, _ := logCaller(t, logger.WithCallerSkipPackages([]string{"github.com/kakkk/gopkg/logger_test.facade"}))
+		assert.True(t, strings.HasSuffix(file, "caller_skip_test.go:"+strconv.Itoa(line)), file)
+	})
+}
+
+func TestCallerFunc(t *testing.T) {
+	l, err := logger.NewLogger(logger.WithLineNumber(true), logger.WithCallerFunc(true), logger.WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	_, _, line, _ := runtime.Caller(0)
+	l.Info("direct")
+	facadeLine := facadeInfo(l, "through facade")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	file, _ := entries[0].Fields["file"].(string)
+	assert.True(t, strings.HasSuffix(file, "caller_skip_test.go:"+strconv.Itoa(line+1)), file)
+	assert.Equal(t, "TestCallerFunc", entries[0].Fields["func"])
+
+	file, _ = entries[1].Fields["file"].(string)
+	assert.True(t, strings.HasSuffix(file, "caller_skip_test.go:"+strconv.Itoa(facadeLine)), file)
+	assert.Equal(t, "facadeInfo", entries[1].Fields["func"])
+}
+
+func TestCallerFuncDisabled(t *testing.T) {
+	l, err := logger.NewLogger(logger.WithLineNumber(true), logger.WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	l.Info("direct")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Fields, "file")
+	assert.NotContains(t, entries[0].Fields, "func")
+}
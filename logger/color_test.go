@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ansiEscape 颜色控制字符
+const ansiEscape = "\x1b["
+
+// TestColorDetection 测试非终端输出时不使用颜色
+func TestColorDetection(t *testing.T) {
+	t.Run("控制台输出到buffer", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := newLogger(WithConsoleWriter(&buf))
+		require.NoError(t, err)
+		l.WithField("key", "value").Info("no color")
+		assert.Contains(t, buf.String(), "no color")
+		assert.NotContains(t, buf.String(), ansiEscape)
+	})
+
+	t.Run("文件和控制台", func(t *testing.T) {
+		var buf bytes.Buffer
+		fileName := filepath.Join(t.TempDir(), "app.log")
+		l, err := newLogger(WithFileName(fileName), WithConsoleWriter(&buf))
+		require.NoError(t, err)
+		l.WithField("key", "value").Info("no color")
+
+		content, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "no color")
+		assert.NotContains(t, string(content), ansiEscape)
+		assert.Contains(t, buf.String(), "no color")
+		assert.NotContains(t, buf.String(), ansiEscape)
+	})
+
+	t.Run("WithColor强制使用颜色", func(t *testing.T) {
+		var buf bytes.Buffer
+		fileName := filepath.Join(t.TempDir(), "app.log")
+		l, err := newLogger(WithFileName(fileName), WithConsoleWriter(&buf), WithColor(true))
+		require.NoError(t, err)
+		l.Info("color")
+		assert.Contains(t, buf.String(), ansiEscape)
+
+		// 文件始终不使用颜色
+		content, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), ansiEscape)
+	})
+
+	t.Run("文件不是终端", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+		require.NoError(t, err)
+		defer f.Close()
+		assert.False(t, isTerminal(f))
+		assert.False(t, isTerminal(&bytes.Buffer{}))
+	})
+
+	t.Run("终端", func(t *testing.T) {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			t.Skipf("no tty available: %v", err)
+		}
+		defer tty.Close()
+		assert.True(t, isTerminal(tty))
+		assert.True(t, defaultConfig().colors(tty))
+	})
+}
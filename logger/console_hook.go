@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"io"
 	"os"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -9,6 +11,10 @@ import (
 // consoleHook 控制台输出的Hook
 type consoleHook struct {
 	formatter logrus.Formatter
+	tail      *tailHook // 不为nil时，只缓存的日志不输出
+	out       io.Writer // 输出目标，为nil时输出到os.Stdout
+
+	mu sync.Mutex // hook在logger的锁外执行，串行写入避免多个goroutine的日志交错
 }
 
 func (hook *consoleHook) Levels() []logrus.Level {
@@ -16,11 +22,27 @@ func (hook *consoleHook) Levels() []logrus.Level {
 }
 
 func (hook *consoleHook) Fire(entry *logrus.Entry) error {
+	if hook.tail != nil && hook.tail.buffered(entry) {
+		return nil
+	}
 	line, err := hook.formatter.Format(entry)
 	if err != nil {
 		return err
 	}
 
-	os.Stdout.Write(line)
+	hook.write(line)
 	return nil
 }
+
+func (hook *consoleHook) write(line []byte) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	_, _ = hook.writer().Write(line)
+}
+
+func (hook *consoleHook) writer() io.Writer {
+	if hook.out == nil {
+		return os.Stdout
+	}
+	return hook.out
+}
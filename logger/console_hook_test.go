@@ -1,23 +1,30 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestConsoleHook 测试控制台hook
 func TestConsoleHook(t *testing.T) {
 	t.Run("控制台hook格式化", func(t *testing.T) {
-		formatter := getConsoleFormatter(false)
+		formatter := getConsoleFormatter(false, true)
 		_, ok := formatter.(*logrus.TextFormatter)
 		assert.True(t, ok)
 
-		formatter = getConsoleFormatter(true)
+		formatter = getConsoleFormatter(true, true)
 		_, ok = formatter.(*logrus.JSONFormatter)
 		assert.True(t, ok)
 	})
@@ -82,7 +89,7 @@ func TestConsoleHook(t *testing.T) {
 func TestAddConsoleHook(t *testing.T) {
 	t.Run("添加文本格式控制台hook", func(t *testing.T) {
 		logger := logrus.New()
-		addConsoleHook(logger, false)
+		addConsoleHook(logger, false, true, nil)
 
 		hasConsoleHook := false
 		for _, hooks := range logger.Hooks {
@@ -98,7 +105,7 @@ func TestAddConsoleHook(t *testing.T) {
 
 	t.Run("添加JSON格式控制台hook", func(t *testing.T) {
 		logger := logrus.New()
-		addConsoleHook(logger, true)
+		addConsoleHook(logger, true, true, nil)
 
 		hasConsoleHook := false
 		for _, hooks := range logger.Hooks {
@@ -112,3 +119,125 @@ func TestAddConsoleHook(t *testing.T) {
 		assert.True(t, hasConsoleHook)
 	})
 }
+
+// TestConsoleFormat 测试文件与控制台使用不同格式
+func TestConsoleFormat(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	l, err := newLogger(
+		WithFileName(fileName),
+		WithJSONFormat(true),
+		WithConsoleFormat(false),
+		WithLineNumber(false),
+	)
+	require.NoError(t, err)
+
+	// 捕获标准输出
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	l.WithField("key", "value").Info("dual format")
+	w.Close()
+	output, _ := io.ReadAll(r)
+	os.Stdout = oldStdout
+
+	// 控制台为文本格式
+	assert.Contains(t, string(output), "dual format")
+	assert.Contains(t, string(output), "key")
+	assert.False(t, json.Valid(bytes.TrimSpace(output)))
+
+	// 文件为JSON格式
+	content, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &data))
+	assert.Equal(t, "dual format", data["msg"])
+	assert.Equal(t, "value", data["key"])
+
+	// 未单独设置时与文件格式一致
+	cfg := defaultConfig()
+	WithJSONFormat(true)(cfg)
+	assert.True(t, cfg.consoleJSON())
+	WithConsoleFormat(false)(cfg)
+	assert.False(t, cfg.consoleJSON())
+}
+
+// TestConsoleWriter 测试自定义控制台输出目标
+func TestConsoleWriter(t *testing.T) {
+	// 捕获标准输出，确认日志没有输出到stdout
+	captureStdout := func(fn func()) string {
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		fn()
+		w.Close()
+		output, _ := io.ReadAll(r)
+		os.Stdout = oldStdout
+		return string(output)
+	}
+
+	t.Run("只输出到控制台", func(t *testing.T) {
+		var buf bytes.Buffer
+		stdout := captureStdout(func() {
+			l, err := newLogger(WithConsoleWriter(&buf), WithJSONFormat(true))
+			require.NoError(t, err)
+			l.Info("console only")
+		})
+		assert.Empty(t, stdout)
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+		assert.Equal(t, "console only", data["msg"])
+	})
+
+	for _, jsonFormat := range []bool{false, true} {
+		t.Run(fmt.Sprintf("文件和控制台 json=%v", jsonFormat), func(t *testing.T) {
+			var buf bytes.Buffer
+			fileName := filepath.Join(t.TempDir(), "app.log")
+			stdout := captureStdout(func() {
+				l, err := newLogger(
+					WithFileName(fileName),
+					WithConsoleFormat(jsonFormat),
+					WithConsoleWriter(&buf),
+				)
+				require.NoError(t, err)
+				l.Info("dual output")
+			})
+			assert.Empty(t, stdout)
+			assert.Contains(t, buf.String(), "dual output")
+			assert.Equal(t, jsonFormat, json.Valid(bytes.TrimSpace(buf.Bytes())))
+
+			content, err := os.ReadFile(fileName)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "dual output")
+		})
+	}
+
+	t.Run("并发写入", func(t *testing.T) {
+		var buf bytes.Buffer
+		fileName := filepath.Join(t.TempDir(), "app.log")
+		l, err := newLogger(WithFileName(fileName), WithConsoleFormat(true), WithConsoleWriter(&buf))
+		require.NoError(t, err)
+
+		// bytes.Buffer不是并发安全的，hook需要串行写入
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.Infof("concurrent %d", i)
+			}()
+		}
+		wg.Wait()
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 10)
+		for _, line := range lines {
+			assert.True(t, json.Valid([]byte(line)), line)
+		}
+	})
+
+	t.Run("nil使用stdout", func(t *testing.T) {
+		cfg := defaultConfig()
+		WithConsoleWriter(nil)(cfg)
+		assert.Equal(t, os.Stdout, cfg.console())
+	})
+}
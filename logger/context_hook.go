@@ -1,12 +1,16 @@
 package logger
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
 
 	"github.com/kakkk/gopkg/requestid"
 )
 
-type contextHook struct{}
+type contextHook struct {
+	deadlineField bool // context已过deadline时是否添加ctx_expired、ctx_deadline字段
+}
 
 func (h contextHook) Levels() []logrus.Level {
 	return logrus.AllLevels
@@ -18,6 +22,12 @@ func (h contextHook) Fire(entry *logrus.Entry) error {
 		if requestID != "" {
 			entry.Data["request_id"] = requestID
 		}
+		if h.deadlineField {
+			if deadline, ok := entry.Context.Deadline(); ok && !time.Now().Before(deadline) {
+				entry.Data["ctx_expired"] = true
+				entry.Data["ctx_deadline"] = deadline.Format("2006-01-02 15:04:05.000")
+			}
+		}
 	}
 	return nil
 }
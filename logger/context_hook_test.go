@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
@@ -144,3 +145,92 @@ func TestContextHook(t *testing.T) {
 		assert.True(t, len(entry.Data) == firstCallFieldCount || len(entry.Data) == firstCallFieldCount)
 	})
 }
+
+// TestContextHookDeadline 测试context deadline字段
+func TestContextHookDeadline(t *testing.T) {
+	newEntry := func(ctx context.Context) *logrus.Entry {
+		return &logrus.Entry{
+			Logger:  logrus.New(),
+			Time:    time.Now(),
+			Level:   logrus.InfoLevel,
+			Message: "test",
+			Data:    make(logrus.Fields),
+			Context: ctx,
+		}
+	}
+
+	t.Run("context已超时", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+
+		hook := &contextHook{deadlineField: true}
+		entry := newEntry(ctx)
+		assert.NoError(t, hook.Fire(entry))
+		assert.Equal(t, true, entry.Data["ctx_expired"])
+		assert.NotEmpty(t, entry.Data["ctx_deadline"])
+	})
+
+	t.Run("context未超时", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		hook := &contextHook{deadlineField: true}
+		entry := newEntry(ctx)
+		assert.NoError(t, hook.Fire(entry))
+		_, exists := entry.Data["ctx_expired"]
+		assert.False(t, exists)
+	})
+
+	t.Run("未开启", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+
+		hook := &contextHook{}
+		entry := newEntry(ctx)
+		assert.NoError(t, hook.Fire(entry))
+		_, exists := entry.Data["ctx_expired"]
+		assert.False(t, exists)
+	})
+
+	t.Run("WithContextDeadlineField", func(t *testing.T) {
+		cfg := defaultConfig()
+		WithContextDeadlineField(true)(cfg)
+		assert.True(t, cfg.contextDeadlineField)
+	})
+}
+
+// levelRecordHook 记录触发时的日志级别
+type levelRecordHook struct {
+	levels []logrus.Level
+}
+
+func (h *levelRecordHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *levelRecordHook) Fire(entry *logrus.Entry) error {
+	h.levels = append(h.levels, entry.Level)
+	return nil
+}
+
+// TestHookLevelDisabled 级别未开启时logrus在触发hook前过滤，hook不需要自行判断级别
+func TestHookLevelDisabled(t *testing.T) {
+	logger, err := newLogger(WithLevel(logrus.InfoLevel), WithLineNumber(true))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	hook := &levelRecordHook{}
+	logger.AddHook(hook)
+	ctx := requestid.Ctx(context.Background())
+
+	logger.WithContext(ctx).Debug("filtered")
+	logger.WithContext(ctx).Log(logrus.DebugLevel, "filtered")
+	assert.Empty(t, hook.levels)
+	assert.Empty(t, buf.String())
+
+	logger.WithContext(ctx).Info("emitted")
+	assert.Equal(t, []logrus.Level{logrus.InfoLevel}, hook.levels)
+	assert.Contains(t, buf.String(), "request_id")
+}
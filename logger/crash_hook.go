@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// crashHook 将Panic、Fatal级别的日志连同调用栈额外写入单独的崩溃文件
+type crashHook struct {
+	path      string
+	formatter logrus.Formatter
+	stack     *stackHook // 复用stack hook获取调用栈
+	mu        sync.Mutex
+}
+
+func newCrashHook(path string, skipPackages ...string) (*crashHook, error) {
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("[logger] logger create crash file dir fail: %w", err)
+		}
+	}
+	return &crashHook{
+		path: path,
+		formatter: &logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+		},
+		stack: newStackHook(0, skipPackages...),
+	}, nil
+}
+
+func (h *crashHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel}
+}
+
+func (h *crashHook) Fire(entry *logrus.Entry) error {
+	// entry.Data可能与其他entry共享，在副本上添加stack字段
+	e := entry.Dup()
+	e.Level = entry.Level
+	e.Message = entry.Message
+	if _, ok := e.Data["stack"]; !ok {
+		if stack := h.stack.stack(); stack != "" {
+			e.Data["stack"] = stack
+		}
+	}
+	line, err := h.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+	return h.write(line)
+}
+
+// write 每次打开文件追加写入并落盘，崩溃日志很少，保证进程退出前已写入
+func (h *crashHook) write(line []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
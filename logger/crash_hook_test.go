@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestCrashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crash", "crash.log")
+	l, err := newLogger(WithCrashFile(path), WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	l.Error("not a crash")
+	func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+		l.WithField("user", "alice").Panic("boom")
+	}()
+
+	// 主输出不受影响，也不会添加stack字段
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.NotContains(t, entries[1].Fields, "stack")
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	crashes, err := testutil.ParseEntries(f)
+	require.NoError(t, err)
+	require.Len(t, crashes, 1)
+	assert.Equal(t, "panic", crashes[0].Level)
+	assert.Equal(t, "boom", crashes[0].Msg)
+	assert.Equal(t, "alice", crashes[0].Fields["user"])
+	stack, ok := crashes[0].Fields["stack"].(string)
+	require.True(t, ok)
+	// 测试函数本身在logger包内也会被跳过，调用栈从testing包开始
+	assert.Contains(t, stack, "testing.tRunner")
+}
+
+func TestCrashFileInvalidPath(t *testing.T) {
+	// 父路径是普通文件，无法创建目录
+	parent := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(parent, nil, 0644))
+
+	_, err := newLogger(WithCrashFile(filepath.Join(parent, "crash.log")))
+	assert.Error(t, err)
+}
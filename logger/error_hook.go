@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// errorHook 将error字段展开为多个结构化字段
+type errorHook struct {
+	expander func(error) logrus.Fields
+}
+
+func (h *errorHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *errorHook) Fire(entry *logrus.Entry) error {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok || err == nil {
+		return nil
+	}
+	for k, v := range h.expander(err) {
+		entry.Data[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codeError struct {
+	code int
+	msg  string
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code=%d, msg=%s", e.code, e.msg)
+}
+
+func expandCodeError(err error) logrus.Fields {
+	var ce *codeError
+	if errors.As(err, &ce) {
+		return logrus.Fields{
+			"error_code": ce.code,
+			"error_msg":  ce.msg,
+		}
+	}
+	return nil
+}
+
+// TestErrorHook 测试error字段展开
+func TestErrorHook(t *testing.T) {
+	t.Run("JSON格式展开error", func(t *testing.T) {
+		logger, err := newLogger(
+			WithJSONFormat(true),
+			WithLineNumber(false),
+			WithErrorFieldExpander(expandCodeError),
+		)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		logger.SetOutput(&buf)
+
+		wrapped := fmt.Errorf("query failed: %w", &codeError{code: 1001, msg: "not found"})
+		logger.WithError(wrapped).Error("test error")
+
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+		assert.Equal(t, wrapped.Error(), data[logrus.ErrorKey])
+		assert.Equal(t, float64(1001), data["error_code"])
+		assert.Equal(t, "not found", data["error_msg"])
+	})
+
+	t.Run("默认不展开", func(t *testing.T) {
+		logger, err := newLogger(WithJSONFormat(true), WithLineNumber(false))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		logger.SetOutput(&buf)
+
+		logger.WithError(&codeError{code: 1001, msg: "not found"}).Error("test error")
+
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+		assert.Equal(t, "code=1001, msg=not found", data[logrus.ErrorKey])
+		_, exists := data["error_code"]
+		assert.False(t, exists)
+	})
+
+	t.Run("普通error不展开", func(t *testing.T) {
+		hook := &errorHook{expander: expandCodeError}
+		entry := logrus.NewEntry(logrus.New()).WithError(errors.New("plain"))
+		assert.NoError(t, hook.Fire(entry))
+		assert.Len(t, entry.Data, 1)
+	})
+}
@@ -0,0 +1,13 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// NewLogger 导出给外部测试包使用
+var NewLogger = newLogger
+
+// ReplaceGlobalLogger 替换全局logger，返回恢复函数，导出给外部测试包使用
+func ReplaceGlobalLogger(l *logrus.Logger) func() {
+	original := globalLogger
+	globalLogger = l
+	return func() { globalLogger = original }
+}
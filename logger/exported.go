@@ -5,12 +5,24 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/kakkk/gopkg/requestid"
 )
 
 func Ctx(ctx context.Context) *logrus.Entry {
 	return globalLogger.WithContext(ctx)
 }
 
+// NewRequestLogger 创建绑定了请求context的entry，可以保存后在整个请求中复用
+// context中有request_id时直接写入字段，后续WithField等调用会保留该字段
+func NewRequestLogger(ctx context.Context) *logrus.Entry {
+	entry := globalLogger.WithContext(ctx)
+	if requestID := requestid.Get(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	return entry
+}
+
 func WithError(err error) *logrus.Entry {
 	return globalLogger.WithField(logrus.ErrorKey, err)
 }
@@ -12,6 +12,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/kakkk/gopkg/requestid"
 )
 
 // setupTestLogger 设置测试用的logger
@@ -70,6 +72,35 @@ func TestCtxFunctions(t *testing.T) {
 	})
 }
 
+// TestNewRequestLogger 测试请求级别的entry
+func TestNewRequestLogger(t *testing.T) {
+	buf, cleanup := setupTestLogger(t)
+	defer cleanup()
+
+	t.Run("绑定request_id", func(t *testing.T) {
+		ctx := requestid.Ctx(context.Background())
+		entry := NewRequestLogger(ctx)
+		assert.Equal(t, ctx, entry.Context)
+		assert.Equal(t, requestid.Get(ctx), entry.Data["request_id"])
+
+		// 链式调用保留request_id
+		child := entry.WithField("method", "GET").WithField("path", "/users")
+		assert.Equal(t, requestid.Get(ctx), child.Data["request_id"])
+		assert.Equal(t, "GET", child.Data["method"])
+		assert.NotContains(t, entry.Data, "method")
+
+		child.Info("request log")
+		assert.Contains(t, buf.String(), "request_id="+requestid.Get(ctx))
+		assert.Contains(t, buf.String(), "path=/users")
+		buf.Reset()
+	})
+
+	t.Run("无request_id", func(t *testing.T) {
+		entry := NewRequestLogger(context.Background())
+		assert.NotContains(t, entry.Data, "request_id")
+	})
+}
+
 // TestWithFunctions 测试With前缀的函数
 func TestWithFunctions(t *testing.T) {
 	buf, cleanup := setupTestLogger(t)
@@ -0,0 +1,54 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMode(t *testing.T) {
+	t.Run("new file", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "logs", "app.log")
+		l, err := newLogger(WithFileName(logFile), WithConsoleOutput(false), WithFileMode(0640))
+		require.NoError(t, err)
+		l.Info("test log")
+
+		info, err := os.Stat(logFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		content, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "test log")
+	})
+
+	t.Run("existing file", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "app.log")
+		require.NoError(t, os.WriteFile(logFile, []byte("old\n"), 0600))
+		l, err := newLogger(WithFileName(logFile), WithConsoleOutput(false), WithFileMode(0644))
+		require.NoError(t, err)
+		l.Info("test log")
+
+		info, err := os.Stat(logFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+		content, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "old\n")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "app.log")
+		l, err := newLogger(WithFileName(logFile), WithConsoleOutput(false))
+		require.NoError(t, err)
+		l.Info("test log")
+
+		info, err := os.Stat(logFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+}
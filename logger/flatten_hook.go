@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+// flattenHook 将嵌套的map字段展开为以"."连接的多个字段
+type flattenHook struct {
+	maxDepth int // 最大展开层数
+}
+
+func (h flattenHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h flattenHook) Fire(entry *logrus.Entry) error {
+	// entry.Data可能与其他entry共享，展开结果写入新的map
+	var data logrus.Fields
+	for k, v := range entry.Data {
+		if !isStringKeyMap(v) {
+			continue
+		}
+		if data == nil {
+			data = make(logrus.Fields, len(entry.Data))
+			for kk, vv := range entry.Data {
+				data[kk] = vv
+			}
+		}
+		delete(data, k)
+		h.flatten(data, k, v, 1)
+	}
+	if data != nil {
+		entry.Data = data
+	}
+	return nil
+}
+
+func (h flattenHook) flatten(dst logrus.Fields, prefix string, val interface{}, depth int) {
+	if depth > h.maxDepth || !isStringKeyMap(val) {
+		dst[prefix] = val
+		return
+	}
+	rv := reflect.ValueOf(val)
+	iter := rv.MapRange()
+	for iter.Next() {
+		h.flatten(dst, prefix+"."+iter.Key().String(), iter.Value().Interface(), depth+1)
+	}
+}
+
+// isStringKeyMap 是否为key为string的非空map
+func isStringKeyMap(val interface{}) bool {
+	if val == nil {
+		return false
+	}
+	rv := reflect.ValueOf(val)
+	return rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String && rv.Len() > 0
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestFlattenHook(t *testing.T) {
+	user := map[string]interface{}{
+		"name": "John",
+		"addr": map[string]string{"city": "Beijing"},
+	}
+
+	t.Run("flatten nested map", func(t *testing.T) {
+		l, err := newLogger(WithMaxFieldDepth(2), WithJSONFormat(true))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+		l.WithField("user", user).WithField("plain", 1).Info("login")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		fields := entries[0].Fields
+		assert.Equal(t, "John", fields["user.name"])
+		assert.Equal(t, "Beijing", fields["user.addr.city"])
+		assert.NotContains(t, fields, "user")
+		assert.Contains(t, fields, "plain")
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		entry := logrus.NewEntry(logrus.New()).WithField("user", user)
+		require.NoError(t, flattenHook{maxDepth: 1}.Fire(entry))
+		assert.Equal(t, "John", entry.Data["user.name"])
+		assert.Equal(t, map[string]string{"city": "Beijing"}, entry.Data["user.addr"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l, err := newLogger(WithJSONFormat(true))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+		l.WithField("user", user).Info("login")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Contains(t, entries[0].Fields, "user")
+		assert.NotContains(t, entries[0].Fields, "user.name")
+	})
+}
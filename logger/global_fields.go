@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// globalFields 运行时添加的全局字段，所有logger共享
+var globalFields = &fieldSet{}
+
+// fieldSet 并发安全的字段集合
+type fieldSet struct {
+	mu     sync.RWMutex
+	fields logrus.Fields
+}
+
+func (s *fieldSet) set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 写时复制，Fire中持有的旧map不受影响
+	fields := make(logrus.Fields, len(s.fields)+1)
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	s.fields = fields
+}
+
+func (s *fieldSet) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.fields[key]; !ok {
+		return
+	}
+	fields := make(logrus.Fields, len(s.fields))
+	for k, v := range s.fields {
+		if k != key {
+			fields[k] = v
+		}
+	}
+	s.fields = fields
+}
+
+func (s *fieldSet) load() logrus.Fields {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fields
+}
+
+// AddGlobalField 运行时添加全局字段，之后所有日志都会带上该字段，并发安全
+// 日志自身通过WithField设置的同名字段优先
+func AddGlobalField(key string, value interface{}) {
+	globalFields.set(key, value)
+}
+
+// RemoveGlobalField 删除通过AddGlobalField添加的全局字段，并发安全
+func RemoveGlobalField(key string) {
+	globalFields.remove(key)
+}
+
+// globalFieldHook 为日志添加全局字段
+type globalFieldHook struct {
+	fields *fieldSet
+}
+
+func (h globalFieldHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h globalFieldHook) Fire(entry *logrus.Entry) error {
+	for k, v := range h.fields.load() {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
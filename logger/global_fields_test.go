@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestGlobalFields(t *testing.T) {
+	l, err := newLogger(WithJSONFormat(true), WithLineNumber(false))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	defer RemoveGlobalField("role")
+
+	AddGlobalField("role", "leader")
+	l.Info("with role")
+	l.WithField("role", "override").Info("override role")
+	RemoveGlobalField("role")
+	l.Info("without role")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "leader", entries[0].Fields["role"])
+	assert.Equal(t, "override", entries[1].Fields["role"])
+	assert.NotContains(t, entries[2].Fields, "role")
+}
+
+func TestGlobalFieldsConcurrent(t *testing.T) {
+	l, err := newLogger(WithJSONFormat(true))
+	require.NoError(t, err)
+	l.SetOutput(&bytes.Buffer{})
+	l.SetLevel(logrus.InfoLevel)
+	defer RemoveGlobalField("concurrent")
+
+	// 并发添加、删除字段与打印日志，配合-race检查
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				AddGlobalField("concurrent", j)
+				RemoveGlobalField("concurrent")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+}
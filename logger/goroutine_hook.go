@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// goroutineHook 添加当前goroutine id字段
+type goroutineHook struct {
+}
+
+func (h goroutineHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h goroutineHook) Fire(entry *logrus.Entry) error {
+	if id, ok := goroutineID(); ok {
+		entry.Data["goid"] = id
+	}
+	return nil
+}
+
+// goroutineID 从runtime.Stack的首行"goroutine 123 [running]:"中解析goroutine id
+func goroutineID() (uint64, bool) {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestGoroutineID(t *testing.T) {
+	l, err := newLogger(WithGoroutineID(true), WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	// 两个goroutine同时存活，id一定不同
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			l.Info("from goroutine")
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	id1, ok := entries[0].Fields["goid"]
+	require.True(t, ok)
+	id2, ok := entries[1].Fields["goid"]
+	require.True(t, ok)
+	assert.NotEqual(t, id1, id2)
+
+	id, ok := goroutineID()
+	assert.True(t, ok)
+	assert.NotZero(t, id)
+}
+
+func TestGoroutineIDDisabled(t *testing.T) {
+	l, err := newLogger(WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	l.Info("test")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Fields, "goid")
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// orderedJSONFormatter 按指定顺序输出字段的JSON格式化器
+// fieldOrder中的字段按顺序最先输出，其余字段按key字母序输出
+type orderedJSONFormatter struct {
+	timestampFormat string
+	fieldOrder      []string
+}
+
+func (f *orderedJSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data)+3)
+	for k, v := range entry.Data {
+		// 与logrus.JSONFormatter一致，error输出为字符串
+		if err, ok := v.(error); ok {
+			data[k] = err.Error()
+			continue
+		}
+		data[k] = v
+	}
+	data[logrus.FieldKeyTime] = entry.Time.Format(f.timestampFormat)
+	data[logrus.FieldKeyLevel] = entry.Level.String()
+	data[logrus.FieldKeyMsg] = entry.Message
+
+	keys := make([]string, 0, len(data))
+	ordered := make(map[string]struct{}, len(f.fieldOrder))
+	for _, k := range f.fieldOrder {
+		if _, ok := data[k]; !ok {
+			continue
+		}
+		if _, ok := ordered[k]; ok {
+			continue
+		}
+		ordered[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	rest := make([]string, 0, len(data)-len(keys))
+	for k := range data {
+		if _, ok := ordered[k]; !ok {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal field key to JSON, %w", err)
+		}
+		val, err := json.Marshal(data[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal field %s to JSON, %w", k, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
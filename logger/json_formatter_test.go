@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedJSONFormatter(t *testing.T) {
+	t.Run("configured order first", func(t *testing.T) {
+		l, err := newLogger(
+			WithJSONFormat(true),
+			WithLineNumber(false),
+			WithJSONFieldOrder([]string{"time", "level", "msg", "request_id", "missing"}),
+		)
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+		l.WithTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)).
+			WithFields(logrus.Fields{"b": 2, "a": "x", "request_id": "rid"}).
+			WithError(errors.New("boom")).
+			Info("hello")
+
+		expected := `{"time":"2024-01-02 03:04:05","level":"info","msg":"hello","request_id":"rid","a":"x","b":2,"error":"boom"}` + "\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("default alphabetical", func(t *testing.T) {
+		f := &orderedJSONFormatter{timestampFormat: time.RFC3339}
+		entry := logrus.NewEntry(logrus.New()).WithField("z", 1)
+		entry.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		entry.Level = logrus.WarnLevel
+		entry.Message = "m"
+		out, err := f.Format(entry)
+		require.NoError(t, err)
+		assert.Equal(t, `{"level":"warning","msg":"m","time":"2024-01-02T03:04:05Z","z":1}`+"\n", string(out))
+	})
+}
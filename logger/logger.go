@@ -2,11 +2,16 @@ package logger
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -15,6 +20,10 @@ var (
 	globalLogger *logrus.Logger
 )
 
+// unlimitedMaxSize maxSize为0时传给lumberjack的值(单位：MB)
+// lumberjack会把0当作默认的100MB，这里用一个足够大的值表示实际上不限制文件大小
+const unlimitedMaxSize = math.MaxInt32
+
 // Init 初始化Logger
 // 如果不传入任何选项，则只输出到控制台
 // 初始化错误降级到默认配置
@@ -22,6 +31,10 @@ func Init(options ...Option) {
 	once.Do(func() {
 		logger, err := newLogger(options...)
 		if err != nil {
+			// 正常情况下init()已保证globalLogger不为nil，这里兜底避免空指针
+			if globalLogger == nil {
+				globalLogger = createFallbackLogger()
+			}
 			globalLogger.WithError(err).Errorf("[logger] init logger failed, fallback to default logger")
 			return
 		}
@@ -46,11 +59,9 @@ func createFallbackLogger() *logrus.Logger {
 	logger.SetLevel(logrus.DebugLevel)
 	logger.SetOutput(os.Stdout)
 	logger.AddHook(newCallerHook())
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-	})
+	logger.AddHook(globalFieldHook{fields: globalFields})
+	// 与默认配置一致，只有终端使用颜色
+	logger.SetFormatter(getConsoleFormatter(false, isTerminal(os.Stdout)))
 	return logger
 }
 
@@ -97,6 +108,11 @@ type config struct {
 	// 默认: false
 	jsonFormat bool
 
+	// jsonFieldOrder JSON格式下优先输出的字段及顺序，其余字段按key字母序输出
+	// 只在jsonFormat为true时生效
+	// 默认: nil，所有字段按key字母序输出
+	jsonFieldOrder []string
+
 	// withConsole 是否同时输出到控制台
 	// 当设置了fileName时，默认会将日志同时输出到文件和控制台
 	// 将此字段设置为false可禁用控制台输出，只写入文件
@@ -104,9 +120,84 @@ type config struct {
 	// 注意: 当fileName为空时，此字段会被忽略，始终输出到控制台
 	withConsole bool
 
+	// consoleJSONFormat 控制台输出是否使用JSON格式
+	// 为nil时与jsonFormat保持一致
+	// 默认: nil
+	// 注意: 只在同时输出到文件和控制台时生效
+	consoleJSONFormat *bool
+
 	// showLine 是否在日志中包含文件名和行号
 	// 默认: true
 	showLine bool
+
+	// callerFunc 是否在日志中包含调用者的函数名(func字段)
+	// 只在showLine为true时生效
+	// 默认: false
+	callerFunc bool
+
+	// errorFieldExpander 将error展开为多个结构化字段
+	// 为nil时error字段只输出error.Error()字符串
+	// 默认: nil
+	errorFieldExpander func(error) logrus.Fields
+
+	// contextDeadlineField 日志context已过deadline时是否添加ctx_expired、ctx_deadline字段
+	// 默认: false
+	contextDeadlineField bool
+
+	// callerSkipPackages 查找调用者时额外跳过的包前缀
+	// 用于封装了logger的场景，使file字段指向真正的调用者
+	// 默认: nil
+	callerSkipPackages []string
+
+	// maxFieldDepth 嵌套map字段展开的最大层数，展开后的key以"."连接
+	// 默认: 0，不展开
+	maxFieldDepth int
+
+	// goroutineID 是否在日志中包含goroutine id(goid字段)
+	// 默认: false
+	goroutineID bool
+
+	// errorStackTrace Error及以上级别的日志是否添加调用栈(stack字段)
+	// 默认: false
+	errorStackTrace bool
+
+	// errorStackInterval 两次记录调用栈的最小间隔，间隔内的日志不添加stack字段
+	// 默认: 0，不限制
+	errorStackInterval time.Duration
+
+	// redactKeys 需要脱敏的字段名，不区分大小写，字段值替换为"***"
+	// 默认: nil
+	redactKeys []string
+
+	// redactValuePatterns 需要脱敏的值模式，字符串字段和日志消息中匹配的部分替换为"***"
+	// 默认: nil
+	redactValuePatterns []*regexp.Regexp
+
+	// crashFile Panic、Fatal级别日志额外写入的崩溃文件路径，包含调用栈
+	// 默认: ""，不写入
+	crashFile string
+
+	// tailOnError 每个context缓存的低于日志级别的日志条数，出现Error及以上级别的日志时一并输出
+	// 默认: 0，不缓存
+	tailOnError int
+
+	// fileMode 日志文件的权限
+	// 默认: 0，使用lumberjack的默认权限0600
+	fileMode os.FileMode
+
+	// consoleWriter 控制台输出的目标
+	// 默认: nil，输出到os.Stdout
+	// 注意: 只输出到控制台和同时输出到文件和控制台时均生效
+	consoleWriter io.Writer
+
+	// color 文本格式是否使用颜色
+	// 默认: nil，只输出到终端时使用颜色，输出到文件、管道时不使用
+	// 注意: 写入日志文件时始终不使用颜色
+	color *bool
+
+	// levelMetrics 每条输出的日志按级别调用一次，用于统计各级别的日志量
+	// 默认: nil，不统计
+	levelMetrics func(level logrus.Level)
 }
 
 // Option 配置选项函数类型
@@ -161,30 +252,92 @@ func newLogger(options ...Option) (*logrus.Logger, error) {
 	logger.SetLevel(cfg.level)
 
 	// 设置格式
-	if cfg.jsonFormat {
+	if cfg.jsonFormat && len(cfg.jsonFieldOrder) > 0 {
+		logger.SetFormatter(&orderedJSONFormatter{
+			timestampFormat: "2006-01-02 15:04:05",
+			fieldOrder:      cfg.jsonFieldOrder,
+		})
+	} else if cfg.jsonFormat {
 		logger.SetFormatter(&logrus.JSONFormatter{
 			TimestampFormat: "2006-01-02 15:04:05",
 		})
 	} else {
+		// 输出到文件时不使用颜色，只输出到控制台时终端才使用颜色
+		colors := cfg.fileName == "" && cfg.colors(cfg.console())
 		logger.SetFormatter(&logrus.TextFormatter{
 			FullTimestamp:   true,
 			TimestampFormat: "2006-01-02 15:04:05",
-			ForceColors:     true,
-			DisableColors:   false,
+			ForceColors:     colors,
+			DisableColors:   !colors,
 		})
 	}
 
 	// context hook
-	logger.AddHook(&contextHook{})
+	logger.AddHook(&contextHook{deadlineField: cfg.contextDeadlineField})
+
+	// global field hook
+	logger.AddHook(globalFieldHook{fields: globalFields})
 
 	// caller hook
 	if cfg.showLine {
-		logger.AddHook(newCallerHook())
+		hook := newCallerHook(cfg.callerSkipPackages...)
+		hook.withFunc = cfg.callerFunc
+		logger.AddHook(hook)
+	}
+
+	// goroutine hook
+	if cfg.goroutineID {
+		logger.AddHook(goroutineHook{})
+	}
+
+	// stack hook
+	if cfg.errorStackTrace {
+		logger.AddHook(newStackHook(cfg.errorStackInterval, cfg.callerSkipPackages...))
+	}
+
+	// error hook
+	if cfg.errorFieldExpander != nil {
+		logger.AddHook(&errorHook{expander: cfg.errorFieldExpander})
+	}
+
+	// flatten hook，放在最后，展开其他hook添加的字段
+	if cfg.maxFieldDepth > 0 {
+		logger.AddHook(flattenHook{maxDepth: cfg.maxFieldDepth})
+	}
+
+	// redact hook，放在展开之后，保证所有字段都经过脱敏
+	if len(cfg.redactKeys) > 0 || len(cfg.redactValuePatterns) > 0 {
+		logger.AddHook(newRedactHook(cfg.redactKeys, cfg.redactValuePatterns))
+	}
+
+	// crash hook，放在脱敏之后，崩溃文件中同样不包含敏感信息
+	if cfg.crashFile != "" {
+		hook, err := newCrashHook(cfg.crashFile, cfg.callerSkipPackages...)
+		if err != nil {
+			return nil, err
+		}
+		logger.AddHook(hook)
+	}
+
+	// level metrics hook
+	if cfg.levelMetrics != nil {
+		logger.AddHook(levelMetricsHook{level: cfg.level, fn: cfg.levelMetrics})
+	}
+
+	// tail hook，放在所有处理字段的hook之后、控制台输出之前，缓存的日志与正常输出的字段一致
+	var tail *tailHook
+	if cfg.tailOnError > 0 && cfg.level < logrus.TraceLevel {
+		tail = newTailHook(cfg.level, cfg.tailOnError)
+		tail.formatter = logger.Formatter
+		// 低于日志级别的日志也需要经过hook才能缓存，由tailFormatter过滤输出
+		logger.SetLevel(logrus.TraceLevel)
+		logger.SetFormatter(&tailFormatter{Formatter: logger.Formatter, tail: tail})
+		logger.AddHook(tail)
 	}
 
 	// 如果没有文件名，只输出到控制台
 	if cfg.fileName == "" {
-		logger.SetOutput(os.Stdout)
+		logger.SetOutput(cfg.console())
 		return logger, nil
 	}
 
@@ -192,6 +345,9 @@ func newLogger(options ...Option) (*logrus.Logger, error) {
 	if err := setupFileOutput(logger, cfg); err != nil {
 		return nil, err
 	}
+	if tail != nil {
+		tail.attachConsole(logger)
+	}
 
 	return logger, nil
 }
@@ -206,10 +362,17 @@ func setupFileOutput(logger *logrus.Logger, cfg *config) error {
 		}
 	}
 
+	// lumberjack新建文件固定使用0600，分割时沿用原文件的权限，预先创建文件并设置权限
+	if cfg.fileMode != 0 {
+		if err := prepareLogFile(cfg.fileName, cfg.fileMode); err != nil {
+			return fmt.Errorf("[logger] logger prepare log file fail: %w", err)
+		}
+	}
+
 	// 配置Lumberjack
 	logRotator := &lumberjack.Logger{
 		Filename:   cfg.fileName,
-		MaxSize:    cfg.maxSize,
+		MaxSize:    rotatorMaxSize(cfg.maxSize),
 		MaxBackups: cfg.maxBackups,
 		MaxAge:     cfg.maxAge,
 		Compress:   cfg.compress,
@@ -220,7 +383,7 @@ func setupFileOutput(logger *logrus.Logger, cfg *config) error {
 	if cfg.withConsole {
 		// 同时输出到文件和控制台
 		logger.SetOutput(logRotator)
-		addConsoleHook(logger, cfg.jsonFormat)
+		addConsoleHook(logger, cfg.consoleJSON(), cfg.colors(cfg.console()), cfg.consoleWriter)
 	} else {
 		// 只输出到文件
 		logger.SetOutput(logRotator)
@@ -229,16 +392,67 @@ func setupFileOutput(logger *logrus.Logger, cfg *config) error {
 	return nil
 }
 
+// prepareLogFile 创建日志文件并设置权限，chmod不受umask影响，已存在的文件同样修改权限
+func prepareLogFile(fileName string, mode os.FileMode) error {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(fileName, mode)
+}
+
+// consoleJSON 控制台输出是否使用JSON格式，未单独设置时与文件一致
+func (c *config) consoleJSON() bool {
+	if c.consoleJSONFormat != nil {
+		return *c.consoleJSONFormat
+	}
+	return c.jsonFormat
+}
+
+// console 控制台输出的目标，未设置时为os.Stdout
+func (c *config) console() io.Writer {
+	if c.consoleWriter != nil {
+		return c.consoleWriter
+	}
+	return os.Stdout
+}
+
+// colors 文本格式输出到w时是否使用颜色，未通过WithColor设置时只有终端使用颜色
+func (c *config) colors(w io.Writer) bool {
+	if c.color != nil {
+		return *c.color
+	}
+	return isTerminal(w)
+}
+
+// isTerminal w是否为终端
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// rotatorMaxSize 转换为lumberjack的MaxSize，0表示不限制文件大小
+func rotatorMaxSize(maxSize int) int {
+	if maxSize == 0 {
+		return unlimitedMaxSize
+	}
+	return maxSize
+}
+
 // addConsoleHook 添加控制台输出的Hook
-func addConsoleHook(logger *logrus.Logger, jsonFormat bool) {
+func addConsoleHook(logger *logrus.Logger, jsonFormat bool, colors bool, out io.Writer) {
 	// 创建一个控制台输出的hook
 	logger.AddHook(&consoleHook{
-		formatter: getConsoleFormatter(jsonFormat),
+		formatter: getConsoleFormatter(jsonFormat, colors),
+		out:       out,
 	})
 }
 
 // getConsoleFormatter 获取控制台格式化器
-func getConsoleFormatter(jsonFormat bool) logrus.Formatter {
+func getConsoleFormatter(jsonFormat bool, colors bool) logrus.Formatter {
 	if jsonFormat {
 		return &logrus.JSONFormatter{
 			TimestampFormat: "2006-01-02 15:04:05",
@@ -248,8 +462,8 @@ func getConsoleFormatter(jsonFormat bool) logrus.Formatter {
 	return &logrus.TextFormatter{
 		FullTimestamp:   true,
 		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-		DisableColors:   false,
+		ForceColors:     colors,
+		DisableColors:   !colors,
 	}
 }
 
@@ -337,6 +551,56 @@ func WithLevelString(level string) Option {
 	}
 }
 
+// WithLevelStringE 通过字符串设置日志级别，无效字符串返回错误
+//
+// 参数:
+//
+//	level - 日志级别的字符串表示，不区分大小写，有效值同WithLevelString
+//
+// 特点:
+//   - 与WithLevelString相同，但解析失败时返回错误而不是静默忽略
+//   - 适合部署配置，拼写错误(如"infoo")可以在启动时直接暴露
+//
+// 示例:
+//
+//	opt, err := WithLevelStringE(os.Getenv("LOG_LEVEL"))
+//	if err != nil {
+//		return err
+//	}
+//	Init(opt)
+func WithLevelStringE(level string) (Option, error) {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	return WithLevel(l), nil
+}
+
+// WithConsoleFormat 单独设置控制台输出的格式
+//
+// 参数:
+//
+//	jsonFormat - true: 控制台使用JSON格式
+//	             false: 控制台使用带颜色的文本格式
+//
+// 作用:
+//   - 默认控制台与文件使用相同的格式(WithJSONFormat)
+//   - 可以让文件写JSON便于日志采集，同时控制台输出文本便于人工查看
+//
+// 注意:
+//   - 只在设置了文件名且开启控制台输出时生效
+//   - 只输出到控制台时(fileName为空)，格式由WithJSONFormat决定
+//
+// 示例:
+//
+//	// 文件JSON格式，控制台文本格式
+//	WithFileName("app.log"), WithJSONFormat(true), WithConsoleFormat(false)
+func WithConsoleFormat(jsonFormat bool) Option {
+	return func(c *config) {
+		c.consoleJSONFormat = &jsonFormat
+	}
+}
+
 // WithJSONFormat 设置是否使用JSON格式输出日志
 //
 // 参数:
@@ -408,6 +672,7 @@ func WithConsoleOutput(console bool) Option {
 //	          - 当文件大小达到此值时，会触发日志分割
 //	          - 默认值: 32 (32MB)
 //	          - 设置为0表示不限制文件大小（不推荐，可能导致文件过大）
+//	            lumberjack本身会把0当作100MB，这里会转换为一个足够大的值，保证不会触发分割
 //
 // 工作原理:
 //  1. 当日志文件大小达到maxSize时，会关闭当前文件
@@ -557,3 +822,402 @@ func WithLineNumber(show bool) Option {
 		c.showLine = show
 	}
 }
+
+// WithCallerFunc 设置是否在日志中包含调用者的函数名
+//
+// 参数:
+//
+//	enable - true: 添加func字段，值为去掉包路径的函数名
+//	         false: 不添加（默认）
+//
+// 作用:
+//   - 函数名比文件行号更稳定，代码改动后依然可以直接按函数名检索日志
+//   - 方法的格式为 "(*Server).Handle"，闭包的格式为 "Handle.func1"
+//
+// 注意:
+//   - 只在WithLineNumber(true)时生效，与file字段指向同一个调用者
+//
+// 示例:
+//
+//	WithLineNumber(true), WithCallerFunc(true)
+func WithCallerFunc(enable bool) Option {
+	return func(c *config) {
+		c.callerFunc = enable
+	}
+}
+
+// WithErrorFieldExpander 设置error字段展开函数
+//
+// 参数:
+//
+//	fn - 将WithError传入的error转换为多个结构化字段，返回的字段会合并到日志Fields中
+//	     - 为nil时不展开，error字段只输出error.Error()字符串（默认）
+//	     - 返回的字段与已有字段同名时会覆盖已有字段
+//
+// 作用:
+//   - 自定义错误类型（如带错误码的错误）可以输出code、cause等多个字段
+//   - 配合WithJSONFormat(true)使用，便于日志系统按字段检索
+//
+// 示例:
+//
+//	WithErrorFieldExpander(func(err error) logrus.Fields {
+//		var codeErr *CodeError
+//		if errors.As(err, &codeErr) {
+//			return logrus.Fields{"error_code": codeErr.Code}
+//		}
+//		return nil
+//	})
+func WithErrorFieldExpander(fn func(error) logrus.Fields) Option {
+	return func(c *config) {
+		c.errorFieldExpander = fn
+	}
+}
+
+// WithCallerSkipPackages 设置查找调用者时额外跳过的包
+//
+// 参数:
+//
+//	packages - 包路径前缀列表，按函数全名(如 "example.com/app/log.Info")前缀匹配
+//
+// 作用:
+//   - 业务对logger做了一层封装时，file字段默认会指向封装层
+//   - 将封装层的包加入跳过列表后，file字段指向真正调用封装层的位置
+//   - logger包和logrus包始终会被跳过，无需配置
+//
+// 注意:
+//   - 只在WithLineNumber(true)时生效
+//   - 前缀匹配，"example.com/app/log" 也会匹配 "example.com/app/logx"，
+//     需要精确匹配时可以在末尾加上 "."
+//
+// 示例:
+//
+//	WithCallerSkipPackages([]string{"example.com/app/pkg/log"})
+func WithCallerSkipPackages(packages []string) Option {
+	return func(c *config) {
+		c.callerSkipPackages = packages
+	}
+}
+
+// WithContextDeadlineField 设置是否标记已超时的context
+//
+// 参数:
+//
+//	enable - true: 日志的context设置了deadline且已经过期时，添加以下字段
+//	           - ctx_expired: true
+//	           - ctx_deadline: context的deadline时间
+//	         false: 不添加（默认）
+//
+// 作用:
+//   - 便于排查超时相关的错误，区分是业务错误还是请求已超时
+//   - 只对通过Ctx/WithContext传入context的日志生效
+//
+// 示例:
+//
+//	WithContextDeadlineField(true)
+func WithContextDeadlineField(enable bool) Option {
+	return func(c *config) {
+		c.contextDeadlineField = enable
+	}
+}
+
+// WithGoroutineID 设置是否在日志中包含goroutine id
+//
+// 参数:
+//
+//	enable - true: 添加goid字段，值为打印日志的goroutine id
+//	         false: 不添加（默认）
+//
+// 作用:
+//   - 排查并发问题时，按goid关联同一个goroutine打印的日志
+//
+// 注意:
+//   - goroutine id通过解析runtime.Stack获取，每条日志有一定额外开销
+//   - goroutine结束后id可能被新的goroutine复用，不能作为全局唯一标识
+//
+// 示例:
+//
+//	WithGoroutineID(true)
+func WithGoroutineID(enable bool) Option {
+	return func(c *config) {
+		c.goroutineID = enable
+	}
+}
+
+// WithErrorStackTrace 设置是否为Error及以上级别的日志添加调用栈
+//
+// 参数:
+//
+//	enable - true: Error、Fatal、Panic级别的日志添加stack字段
+//	         false: 不添加（默认）
+//
+// 作用:
+//   - 排查错误时可以看到完整的调用链路，而不只是file字段的单个位置
+//   - 调用栈从业务调用者开始，跳过logger和logrus内部的栈帧，最多记录32层
+//
+// 注意:
+//   - 获取调用栈开销较大，错误频繁时可配合WithErrorStackTraceInterval限流
+//   - Info等低级别日志不受影响
+//
+// 示例:
+//
+//	WithErrorStackTrace(true)
+func WithErrorStackTrace(enable bool) Option {
+	return func(c *config) {
+		c.errorStackTrace = enable
+	}
+}
+
+// WithErrorStackTraceInterval 设置两次记录调用栈的最小间隔
+//
+// 参数:
+//
+//	interval - 最小间隔，间隔内的错误日志不添加stack字段，0表示不限制（默认）
+//
+// 作用:
+//   - 错误集中爆发时只为第一条日志记录调用栈，避免大量获取调用栈的开销和重复输出
+//
+// 注意:
+//   - 只在WithErrorStackTrace(true)时生效
+//   - 限流对整个logger生效，不区分错误内容
+//
+// 示例:
+//
+//	WithErrorStackTrace(true), WithErrorStackTraceInterval(time.Second)
+func WithErrorStackTraceInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.errorStackInterval = interval
+	}
+}
+
+// WithMaxFieldDepth 设置嵌套map字段展开的最大层数
+//
+// 参数:
+//
+//	depth - 展开的最大层数，0表示不展开（默认）
+//
+// 作用:
+//   - 将key为string的嵌套map展开为以"."连接的多个字段，便于日志系统建立索引
+//   - 超过最大层数的部分保持原样输出
+//
+// 示例:
+//
+//	WithMaxFieldDepth(2)
+//	logger.WithField("user", map[string]interface{}{
+//		"name": "John",
+//		"addr": map[string]interface{}{"city": "Beijing"},
+//	}).Info("login")
+//	// 输出字段: user.name=John user.addr.city=Beijing
+func WithMaxFieldDepth(depth int) Option {
+	return func(c *config) {
+		c.maxFieldDepth = depth
+	}
+}
+
+// WithJSONFieldOrder 设置JSON格式下字段的输出顺序
+//
+// 参数:
+//
+//	fields - 优先输出的字段名，按给定顺序输出，其余字段按key字母序排在后面
+//	         内置字段名: time、level、msg
+//
+// 作用:
+//   - 输出稳定的字段顺序，便于日志系统去重、比对
+//
+// 注意:
+//   - 只在WithJSONFormat(true)时生效
+//   - 只影响主输出，文件+控制台模式下控制台输出仍使用默认顺序
+//
+// 示例:
+//
+//	WithJSONFieldOrder([]string{"time", "level", "msg", "request_id"})
+//	// 输出: {"time":"...","level":"info","msg":"...","request_id":"...","a":1,"b":2}
+func WithJSONFieldOrder(fields []string) Option {
+	return func(c *config) {
+		c.jsonFieldOrder = fields
+	}
+}
+
+// WithRedactKeys 设置需要脱敏的字段名
+//
+// 参数:
+//
+//	keys - 字段名列表，不区分大小写，匹配的字段值替换为"***"
+//
+// 作用:
+//   - 避免密码、token等敏感信息被误写入日志
+//   - 配合WithMaxFieldDepth展开的嵌套字段(如"user.password")按最后一段匹配
+//
+// 示例:
+//
+//	WithRedactKeys([]string{"password", "token"})
+//	logger.WithField("password", "123456").Info("login")
+//	// 输出字段: password=***
+func WithRedactKeys(keys []string) Option {
+	return func(c *config) {
+		c.redactKeys = keys
+	}
+}
+
+// WithRedactValuePatterns 设置需要脱敏的值模式
+//
+// 参数:
+//
+//	patterns - 正则列表，字符串类型的字段值和日志消息中匹配的部分替换为"***"
+//
+// 注意:
+//   - 只处理string类型的字段值，其他类型的字段请使用WithRedactKeys
+//   - 每条日志都会执行所有正则，模式应尽量简单
+//
+// 示例:
+//
+//	WithRedactValuePatterns([]*regexp.Regexp{regexp.MustCompile(`Bearer \S+`)})
+//	logger.Infof("auth header: Bearer abc.def")
+//	// 输出消息: auth header: ***
+func WithRedactValuePatterns(patterns []*regexp.Regexp) Option {
+	return func(c *config) {
+		c.redactValuePatterns = patterns
+	}
+}
+
+// WithCrashFile 设置崩溃文件路径
+//
+// 参数:
+//
+//	path - 崩溃文件路径，为空字符串时不写入（默认）
+//	       如果包含目录路径，会自动创建不存在的目录
+//
+// 作用:
+//   - Panic、Fatal级别的日志除正常输出外，额外以JSON格式追加写入崩溃文件，并包含stack字段
+//   - 崩溃文件不参与日志分割，主日志被分割删除后仍可用于事后排查
+//
+// 注意:
+//   - 每条日志写入后立即落盘，保证Fatal退出进程前已写入
+//
+// 示例:
+//
+//	WithCrashFile("logs/crash.log")
+func WithCrashFile(path string) Option {
+	return func(c *config) {
+		c.crashFile = path
+	}
+}
+
+// WithTailOnError 设置出错时输出的上下文日志条数
+//
+// 参数:
+//
+//	n - 每个context缓存的最近n条低于日志级别的日志，为0时不缓存（默认）
+//
+// 作用:
+//   - 低于日志级别的日志(如Info级别下的Debug、Trace日志)不直接输出，而是缓存在内存中
+//   - 同一context出现Error及以上级别的日志时，先输出缓存的日志，再输出错误日志
+//   - 有request_id时按request_id区分context，否则按goroutine区分
+//   - 不出错时不输出调试日志，出错时又能看到错误前的上下文
+//
+// 注意:
+//   - 开启后logger的级别为Trace，所有日志都会经过hook处理，有一定性能开销
+//   - 内置的控制台输出、级别统计等hook按配置的级别过滤缓存的日志，之后通过AddHook添加的hook会收到缓存的日志，需要自行按级别过滤
+//   - 最多同时缓存1024个context，超过时丢弃最早的缓存
+//   - 日志级别为Trace时没有低于级别的日志，该选项不生效
+//
+// 示例:
+//
+//	WithLevel(logrus.InfoLevel), WithTailOnError(20)
+//	logger.Ctx(ctx).Debug("step 1")  // 不输出
+//	logger.Ctx(ctx).Error("failed")  // 先输出"step 1"，再输出"failed"
+func WithTailOnError(n int) Option {
+	return func(c *config) {
+		c.tailOnError = n
+	}
+}
+
+// WithFileMode 设置日志文件的权限
+//
+// 参数:
+//
+//	mode - 日志文件的权限，如0640，为0时使用lumberjack的默认权限0600（默认）
+//
+// 作用:
+//   - 日志采集等场景需要同组用户可读日志文件
+//   - 分割后的新文件和归档文件沿用该权限
+//
+// 注意:
+//   - 只在设置了文件名时生效
+//   - 已存在的日志文件同样会修改为该权限
+//
+// 示例:
+//
+//	WithFileName("logs/app.log"), WithFileMode(0640)
+func WithFileMode(mode os.FileMode) Option {
+	return func(c *config) {
+		c.fileMode = mode
+	}
+}
+
+// WithConsoleWriter 设置控制台输出的目标
+//
+// 参数:
+//
+//	w - 控制台日志写入的目标，为nil时使用os.Stdout（默认）
+//
+// 作用:
+//   - CLI等使用标准输出输出数据的程序，可以将日志输出到os.Stderr
+//   - 文本和JSON格式的控制台输出均生效
+//
+// 注意:
+//   - 只输出到控制台和同时输出到文件和控制台时均生效
+//
+// 示例:
+//
+//	WithConsoleWriter(os.Stderr)
+func WithConsoleWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.consoleWriter = w
+	}
+}
+
+// WithColor 设置文本格式是否使用颜色
+//
+// 参数:
+//
+//	color - 为true时始终使用颜色，为false时不使用颜色
+//
+// 作用:
+//   - 默认只有输出到终端时使用颜色，输出到文件、管道、容器日志时不使用颜色
+//   - 需要覆盖自动检测时使用，如日志采集支持解析颜色
+//
+// 注意:
+//   - 只对文本格式的控制台输出生效，写入日志文件时始终不使用颜色
+//
+// 示例:
+//
+//	WithColor(false)
+func WithColor(color bool) Option {
+	return func(c *config) {
+		c.color = &color
+	}
+}
+
+// WithLevelMetrics 设置按级别统计日志量的回调
+//
+// 参数:
+//
+//	fn - 每条日志按级别调用一次，为nil时不统计（默认）
+//
+// 作用:
+//   - 对接Prometheus等监控系统，按级别统计日志量，发现错误日志突增
+//   - 只统计达到日志级别的日志，开启WithTailOnError时缓存的低级别日志不计数
+//
+// 注意:
+//   - fn在打印日志的goroutine中同步调用，需要并发安全且足够轻量，如原子计数
+//
+// 示例:
+//
+//	WithLevelMetrics(func(level logrus.Level) {
+//		logCounter.WithLabelValues(level.String()).Inc()
+//	})
+func WithLevelMetrics(fn func(level logrus.Level)) Option {
+	return func(c *config) {
+		c.levelMetrics = fn
+	}
+}
@@ -1,13 +1,19 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/kakkk/gopkg/requestid"
 )
 
 // / TestInit 测试初始化函数
@@ -21,6 +27,23 @@ func TestInit(t *testing.T) {
 		assert.Equal(t, logrus.DebugLevel, globalLogger.GetLevel())
 	})
 
+	t.Run("初始化失败且globalLogger为nil", func(t *testing.T) {
+		resetGlobalState()
+		old := globalLogger
+		defer func() { globalLogger = old }()
+		globalLogger = nil
+
+		// 父路径是普通文件，无法创建日志目录
+		parent := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(parent, nil, 0644))
+
+		assert.NotPanics(t, func() {
+			Init(WithFileName(filepath.Join(parent, "app.log")))
+		})
+		require.NotNil(t, globalLogger)
+		assert.NotPanics(t, func() { Info("fallback logger usable") })
+	})
+
 	t.Run("包初始化成功", func(t *testing.T) {
 		// 验证包导入时init函数是否执行成功
 		assert.NotNil(t, globalLogger)
@@ -112,7 +135,9 @@ func TestFallbackLogger(t *testing.T) {
 		assert.True(t, ok)
 		assert.True(t, formatter.FullTimestamp)
 		assert.Equal(t, "2006-01-02 15:04:05", formatter.TimestampFormat)
-		assert.True(t, formatter.ForceColors)
+		// 测试中stdout不是终端，不使用颜色
+		assert.Equal(t, isTerminal(os.Stdout), formatter.ForceColors)
+		assert.Equal(t, !isTerminal(os.Stdout), formatter.DisableColors)
 	})
 }
 
@@ -160,6 +185,19 @@ func TestOptions(t *testing.T) {
 		}
 	})
 
+	t.Run("WithLevelStringE", func(t *testing.T) {
+		opt, err := WithLevelStringE("WARN")
+		require.NoError(t, err)
+		cfg := defaultConfig()
+		opt(cfg)
+		assert.Equal(t, logrus.WarnLevel, cfg.level)
+
+		opt, err = WithLevelStringE("infoo")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "infoo")
+		assert.Nil(t, opt)
+	})
+
 	t.Run("WithJSONFormat", func(t *testing.T) {
 		cfg := defaultConfig()
 		WithJSONFormat(true)(cfg)
@@ -265,3 +303,53 @@ func TestEdgeCases(t *testing.T) {
 		assert.Equal(t, logrus.WarnLevel, cfg.level)
 	})
 }
+
+// TestMaxSizeUnlimited 测试maxSize为0时不限制文件大小
+func TestMaxSizeUnlimited(t *testing.T) {
+	t.Run("maxSize为0", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "unlimited.log")
+		logger, err := newLogger(
+			WithFileName(fileName),
+			WithConsoleOutput(false),
+			WithMaxSize(0),
+		)
+		require.NoError(t, err)
+		rotator, ok := logger.Out.(*lumberjack.Logger)
+		require.True(t, ok)
+		assert.Equal(t, unlimitedMaxSize, rotator.MaxSize)
+	})
+
+	t.Run("maxSize非0", func(t *testing.T) {
+		fileName := filepath.Join(t.TempDir(), "limited.log")
+		logger, err := newLogger(
+			WithFileName(fileName),
+			WithConsoleOutput(false),
+			WithMaxSize(10),
+		)
+		require.NoError(t, err)
+		rotator, ok := logger.Out.(*lumberjack.Logger)
+		require.True(t, ok)
+		assert.Equal(t, 10, rotator.MaxSize)
+	})
+}
+
+// BenchmarkLevelFiltered 级别未开启时不触发hook
+func BenchmarkLevelFiltered(b *testing.B) {
+	logger, err := newLogger(WithLevel(logrus.InfoLevel), WithLineNumber(true))
+	require.NoError(b, err)
+	logger.SetOutput(io.Discard)
+	ctx := requestid.Ctx(context.Background())
+
+	b.Run("debug filtered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.WithContext(ctx).Debug("filtered")
+		}
+	})
+	b.Run("info emitted", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.WithContext(ctx).Info("emitted")
+		}
+	})
+}
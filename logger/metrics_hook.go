@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// levelMetricsHook 每条日志按级别回调一次，用于统计各级别的日志量
+type levelMetricsHook struct {
+	level logrus.Level // 日志级别，开启WithTailOnError时低于该级别的日志只缓存，不计数
+	fn    func(level logrus.Level)
+}
+
+func (h levelMetricsHook) Levels() []logrus.Level {
+	// AllLevels按Panic到Trace排列，只注册开启的级别，Fire中无需再判断
+	return logrus.AllLevels[:h.level+1]
+}
+
+func (h levelMetricsHook) Fire(entry *logrus.Entry) error {
+	h.fn(entry.Level)
+	return nil
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelMetrics(t *testing.T) {
+	var counts [logrus.TraceLevel + 1]int64
+	count := func(level logrus.Level) {
+		atomic.AddInt64(&counts[level], 1)
+	}
+
+	t.Run("按级别计数", func(t *testing.T) {
+		counts = [logrus.TraceLevel + 1]int64{}
+		l, err := newLogger(WithLevelMetrics(count), WithConsoleWriter(&bytes.Buffer{}))
+		require.NoError(t, err)
+
+		l.Trace("trace")
+		l.Info("info")
+		l.Info("info")
+		l.Warn("warn")
+		l.Error("error")
+
+		assert.Zero(t, counts[logrus.TraceLevel])
+		assert.Equal(t, int64(2), counts[logrus.InfoLevel])
+		assert.Equal(t, int64(1), counts[logrus.WarnLevel])
+		assert.Equal(t, int64(1), counts[logrus.ErrorLevel])
+	})
+
+	t.Run("缓存的日志不计数", func(t *testing.T) {
+		counts = [logrus.TraceLevel + 1]int64{}
+		l, err := newLogger(WithLevelMetrics(count), WithLevel(logrus.InfoLevel), WithTailOnError(10), WithConsoleWriter(&bytes.Buffer{}))
+		require.NoError(t, err)
+
+		l.Debug("debug")
+		l.Error("error")
+
+		assert.Zero(t, counts[logrus.DebugLevel])
+		assert.Equal(t, int64(1), counts[logrus.ErrorLevel])
+	})
+
+	t.Run("不分配内存", func(t *testing.T) {
+		hook := levelMetricsHook{level: logrus.InfoLevel, fn: count}
+		entry := &logrus.Entry{Level: logrus.InfoLevel}
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = hook.Fire(entry)
+		})
+		assert.Zero(t, allocs)
+		assert.Equal(t, []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel}, hook.Levels())
+	})
+}
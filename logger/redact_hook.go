@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redactedValue = "***" // 脱敏后的替换值
+
+// redactHook 将敏感字段的值替换为"***"，避免密码、token等写入日志
+type redactHook struct {
+	keys     map[string]struct{} // 需要脱敏的字段名，小写
+	patterns []*regexp.Regexp    // 需要脱敏的值模式，作用于字符串字段和日志消息
+}
+
+func newRedactHook(keys []string, patterns []*regexp.Regexp) *redactHook {
+	h := &redactHook{
+		keys:     make(map[string]struct{}, len(keys)),
+		patterns: patterns,
+	}
+	for _, k := range keys {
+		h.keys[strings.ToLower(k)] = struct{}{}
+	}
+	return h
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactString(entry.Message)
+
+	// entry.Data可能与其他entry共享，脱敏结果写入新的map
+	var data logrus.Fields
+	for k, v := range entry.Data {
+		redacted, ok := h.redactField(k, v)
+		if !ok {
+			continue
+		}
+		if data == nil {
+			data = make(logrus.Fields, len(entry.Data))
+			for kk, vv := range entry.Data {
+				data[kk] = vv
+			}
+		}
+		data[k] = redacted
+	}
+	if data != nil {
+		entry.Data = data
+	}
+	return nil
+}
+
+// redactField 返回脱敏后的字段值，字段无需脱敏时返回false
+func (h *redactHook) redactField(key string, val interface{}) (interface{}, bool) {
+	if h.isRedactKey(key) {
+		return redactedValue, true
+	}
+	s, ok := val.(string)
+	if !ok {
+		return nil, false
+	}
+	redacted := h.redactString(s)
+	return redacted, redacted != s
+}
+
+// isRedactKey 字段名是否需要脱敏，不区分大小写
+// 展开后的嵌套字段(如"user.password")按最后一段匹配
+func (h *redactHook) isRedactKey(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := h.keys[key]; ok {
+		return true
+	}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		_, ok := h.keys[key[i+1:]]
+		return ok
+	}
+	return false
+}
+
+func (h *redactHook) redactString(s string) string {
+	for _, p := range h.patterns {
+		s = p.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestRedactKeys(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		l, err := newLogger(WithJSONFormat(true), WithRedactKeys([]string{"password", "Token"}))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+
+		l.WithFields(logrus.Fields{
+			"password": "p@ssw0rd",
+			"TOKEN":    12345,
+			"user":     "alice",
+		}).Info("login")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "***", entries[0].Fields["password"])
+		assert.Equal(t, "***", entries[0].Fields["TOKEN"])
+		assert.Equal(t, "alice", entries[0].Fields["user"])
+		assert.NotContains(t, buf.String(), "p@ssw0rd")
+	})
+
+	t.Run("text", func(t *testing.T) {
+		l, err := newLogger(WithRedactKeys([]string{"password"}))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+
+		l.WithField("password", "p@ssw0rd").WithField("user", "alice").Info("login")
+
+		out := buf.String()
+		assert.Contains(t, out, "***")
+		assert.Contains(t, out, "alice")
+		assert.NotContains(t, out, "p@ssw0rd")
+	})
+
+	t.Run("flattened field", func(t *testing.T) {
+		l, err := newLogger(WithJSONFormat(true), WithMaxFieldDepth(2), WithRedactKeys([]string{"password"}))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+
+		l.WithField("user", map[string]interface{}{"name": "alice", "password": "p@ssw0rd"}).Info("login")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "***", entries[0].Fields["user.password"])
+		assert.Equal(t, "alice", entries[0].Fields["user.name"])
+	})
+
+	t.Run("shared fields untouched", func(t *testing.T) {
+		l, err := newLogger(WithJSONFormat(true), WithRedactKeys([]string{"password"}))
+		require.NoError(t, err)
+		l.SetOutput(&bytes.Buffer{})
+
+		entry := l.WithField("password", "p@ssw0rd")
+		entry.Info("login")
+		assert.Equal(t, "p@ssw0rd", entry.Data["password"])
+	})
+}
+
+func TestRedactValuePatterns(t *testing.T) {
+	pattern := regexp.MustCompile(`Bearer \S+`)
+	l, err := newLogger(WithJSONFormat(true), WithRedactValuePatterns([]*regexp.Regexp{pattern}))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	l.WithFields(logrus.Fields{
+		"header": "Authorization: Bearer abc.def",
+		"code":   401,
+		"path":   "/users",
+	}).Infof("auth failed, header: Bearer abc.def")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "auth failed, header: ***", entries[0].Msg)
+	assert.Equal(t, "Authorization: ***", entries[0].Fields["header"])
+	assert.Equal(t, json.Number("401"), entries[0].Fields["code"])
+	assert.Equal(t, "/users", entries[0].Fields["path"])
+}
+
+func TestRedactDisabled(t *testing.T) {
+	l, err := newLogger(WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	l.WithField("password", "p@ssw0rd").Info("login")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "p@ssw0rd", entries[0].Fields["password"])
+}
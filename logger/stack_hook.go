@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxStackDepth = 32 // stack字段最多记录的调用栈层数
+
+// stackHook 为Error及以上级别的日志添加stack字段
+type stackHook struct {
+	interval time.Duration // 两次记录调用栈的最小间隔，0表示不限制
+	last     atomic.Int64  // 上次记录调用栈的时间(UnixNano)
+	caller   callerHook    // 复用调用者判断，跳过logger和logrus的栈帧
+}
+
+func newStackHook(interval time.Duration, skipPackages ...string) *stackHook {
+	return &stackHook{
+		interval: interval,
+		caller:   callerHook{skipPackages: skipPackages},
+	}
+}
+
+func (h *stackHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (h *stackHook) Fire(entry *logrus.Entry) error {
+	if !h.allow(time.Now()) {
+		return nil
+	}
+	if stack := h.stack(); stack != "" {
+		entry.Data["stack"] = stack
+	}
+	return nil
+}
+
+// allow 是否超过了限流间隔，并发时只有一个调用方能获取本次记录的机会
+func (h *stackHook) allow(now time.Time) bool {
+	if h.interval <= 0 {
+		return true
+	}
+	last := h.last.Load()
+	if last != 0 && now.Sub(time.Unix(0, last)) < h.interval {
+		return false
+	}
+	return h.last.CompareAndSwap(last, now.UnixNano())
+}
+
+// stack 从第一个不在logger、logrus及跳过列表中的调用者开始格式化调用栈
+func (h *stackHook) stack() string {
+	pcs := make([]uintptr, maxStackDepth+16)
+	n := runtime.Callers(4, pcs)
+	if n == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs[:n])
+	depth := 0
+	for depth < maxStackDepth {
+		frame, more := frames.Next()
+		if depth > 0 || (!h.caller.isLoggerPackage(frame.Function) &&
+			!strings.Contains(frame.Function, "sirupsen/logrus") &&
+			!h.caller.isSkipPackage(frame.Function)) {
+			if depth > 0 {
+				sb.WriteByte('\n')
+			}
+			fmt.Fprintf(&sb, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+			depth++
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
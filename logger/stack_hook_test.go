@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestErrorStackTrace(t *testing.T) {
+	l, err := newLogger(WithErrorStackTrace(true), WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.NotContains(t, entries[0].Fields, "stack")
+	assert.NotContains(t, entries[1].Fields, "stack")
+
+	stack, ok := entries[2].Fields["stack"].(string)
+	require.True(t, ok)
+	// 测试函数本身在logger包内也会被跳过，调用栈从testing包开始
+	assert.Contains(t, stack, "testing.tRunner")
+	assert.NotContains(t, stack, "sirupsen/logrus")
+}
+
+func TestErrorStackTraceInterval(t *testing.T) {
+	l, err := newLogger(
+		WithErrorStackTrace(true),
+		WithErrorStackTraceInterval(100*time.Millisecond),
+		WithJSONFormat(true),
+	)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+
+	l.Error("first")
+	l.Error("limited")
+	time.Sleep(150 * time.Millisecond)
+	l.Error("after interval")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Contains(t, entries[0].Fields, "stack")
+	assert.NotContains(t, entries[1].Fields, "stack")
+	assert.Contains(t, entries[2].Fields, "stack")
+}
+
+func TestErrorStackTraceDisabled(t *testing.T) {
+	l, err := newLogger(WithJSONFormat(true))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	l.Error("error")
+
+	entries, err := testutil.ParseEntries(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Fields, "stack")
+}
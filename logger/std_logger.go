@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StdLogger 返回标准库的*log.Logger，输出转发到全局logger的指定级别
+// 用于只接受*log.Logger的第三方库，修改返回logger的prefix和flags后，转发时按新的配置去掉头部
+func StdLogger(level logrus.Level) *log.Logger {
+	w := &stdWriter{level: level}
+	w.logger = log.New(w, "", 0)
+	return w.logger
+}
+
+// RedirectStdLog 将标准库log包的默认logger转发到全局logger的指定级别，按其prefix和flags去掉头部
+func RedirectStdLog(level logrus.Level) {
+	std := log.Default()
+	std.SetOutput(&stdWriter{level: level, logger: std})
+}
+
+// stdWriter 将标准库log的每次输出作为一条日志写入全局logger
+type stdWriter struct {
+	level  logrus.Level
+	logger *log.Logger // 写入的标准库logger，用于获取prefix和flags
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	// 去掉标准库log添加的头部和换行，时间和调用位置由logrus记录
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+	msg = trimStdHeader(msg, w.logger.Prefix(), w.logger.Flags())
+	globalLogger.Log(w.level, string(msg))
+	return len(p), nil
+}
+
+// trimStdHeader 按标准库log的格式去掉prefix、日期时间和文件位置
+// 格式为: [prefix][日期 ][时间[.微秒] ][文件:行号: ][Lmsgprefix时的prefix]消息
+func trimStdHeader(msg []byte, prefix string, flags int) []byte {
+	if flags&log.Lmsgprefix == 0 {
+		msg = bytes.TrimPrefix(msg, []byte(prefix))
+	}
+	n := 0
+	if flags&log.Ldate != 0 {
+		n += len("2006/01/02 ")
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n += len("15:04:05 ")
+		if flags&log.Lmicroseconds != 0 {
+			n += len(".000000")
+		}
+	}
+	msg = msg[min(n, len(msg)):]
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := bytes.Index(msg, []byte(": ")); i >= 0 {
+			msg = msg[i+len(": "):]
+		}
+	}
+	if flags&log.Lmsgprefix != 0 {
+		msg = bytes.TrimPrefix(msg, []byte(prefix))
+	}
+	return msg
+}
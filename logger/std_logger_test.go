@@ -0,0 +1,102 @@
+package logger_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger"
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestStdLogger(t *testing.T) {
+	l, err := logger.NewLogger(logger.WithJSONFormat(true), logger.WithLineNumber(true), logger.WithLevel(logrus.InfoLevel))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	l.SetOutput(buf)
+	defer logger.ReplaceGlobalLogger(l)()
+
+	t.Run("print", func(t *testing.T) {
+		buf.Reset()
+		std := logger.StdLogger(logrus.WarnLevel)
+		_, _, line, _ := runtime.Caller(0)
+		std.Print("legacy message")
+		std.Printf("legacy %s", "format")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "warning", entries[0].Level)
+		assert.Equal(t, "legacy message", entries[0].Msg)
+		assert.Equal(t, "legacy format", entries[1].Msg)
+		// 跳过标准库log的调用栈，调用位置为业务代码
+		file, _ := entries[0].Fields["file"].(string)
+		assert.True(t, strings.HasSuffix(file, "std_logger_test.go:"+strconv.Itoa(line+1)), file)
+	})
+
+	t.Run("level filtered", func(t *testing.T) {
+		buf.Reset()
+		logger.StdLogger(logrus.DebugLevel).Print("debug message")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("header stripped", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			prefix string
+			flags  int
+		}{
+			{name: "std flags", flags: log.LstdFlags},
+			{name: "microseconds", flags: log.LstdFlags | log.Lmicroseconds},
+			{name: "prefix", prefix: "[legacy] ", flags: log.LstdFlags},
+			{name: "msg prefix", prefix: "[legacy] ", flags: log.LstdFlags | log.Lmsgprefix},
+			{name: "short file", prefix: "[legacy] ", flags: log.Ltime | log.Lshortfile},
+			{name: "long file", flags: log.LstdFlags | log.Llongfile | log.LUTC},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				buf.Reset()
+				std := logger.StdLogger(logrus.InfoLevel)
+				std.SetPrefix(c.prefix)
+				std.SetFlags(c.flags)
+				std.Println("with header")
+
+				entries, err := testutil.ParseEntries(buf)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+				assert.Equal(t, "info", entries[0].Level)
+				assert.Equal(t, "with header", entries[0].Msg)
+			})
+		}
+	})
+
+	t.Run("redirect std log", func(t *testing.T) {
+		buf.Reset()
+		std := log.Default()
+		defer func(w io.Writer, prefix string, flags int) {
+			std.SetOutput(w)
+			std.SetPrefix(prefix)
+			std.SetFlags(flags)
+		}(std.Writer(), std.Prefix(), std.Flags())
+
+		logger.RedirectStdLog(logrus.InfoLevel)
+		log.SetPrefix("[std] ")
+		_, _, line, _ := runtime.Caller(0)
+		log.Print("from std log")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "from std log", entries[0].Msg)
+		file, _ := entries[0].Fields["file"].(string)
+		assert.True(t, strings.HasSuffix(file, "std_logger_test.go:"+strconv.Itoa(line+1)), file)
+	})
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kakkk/gopkg/requestid"
+)
+
+// tailMaxContexts 最多同时缓存的context数量，超过时丢弃最早的缓存
+// 没有出现错误的请求不会清理缓存，需要限制总量
+const tailMaxContexts = 1024
+
+// tailHook 缓存低于输出级别的日志，同一context出现Error及以上级别的日志时先输出缓存的日志
+// 有request_id时按request_id区分context，否则按goroutine区分
+type tailHook struct {
+	level     logrus.Level     // 输出级别，低于该级别的日志只缓存
+	size      int              // 每个context缓存的日志条数
+	formatter logrus.Formatter // 主输出的formatter，输出缓存的日志时使用
+	console   *consoleHook     // 控制台输出，为nil时不输出到控制台
+
+	mu      sync.Mutex
+	buffers map[string]*list.Element // value为*tailBuffer
+	order   *list.List               // 按创建顺序排列，用于淘汰最早的缓存
+	pending map[*logrus.Entry][]byte // 待输出到主输出的缓存日志，由tailFormatter在logger锁内与错误日志一起写入
+}
+
+type tailBuffer struct {
+	key     string
+	entries []*logrus.Entry
+}
+
+func newTailHook(level logrus.Level, size int) *tailHook {
+	return &tailHook{
+		level:   level,
+		size:    size,
+		buffers: make(map[string]*list.Element),
+		order:   list.New(),
+		pending: make(map[*logrus.Entry][]byte),
+	}
+}
+
+func (h *tailHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *tailHook) Fire(entry *logrus.Entry) error {
+	if h.buffered(entry) {
+		h.add(entry)
+		return nil
+	}
+	if entry.Level > logrus.ErrorLevel {
+		return nil
+	}
+	// hook在写入当前日志前执行，缓存的日志先于错误日志输出
+	h.flush(entry, h.take(tailKey(entry)))
+	return nil
+}
+
+// buffered 日志是否只缓存不输出
+func (h *tailHook) buffered(entry *logrus.Entry) bool {
+	return entry.Level > h.level
+}
+
+func (h *tailHook) add(entry *logrus.Entry) {
+	// entry.Data可能被后续的hook修改，缓存副本
+	e := entry.Dup()
+	e.Level = entry.Level
+	e.Message = entry.Message
+	key := tailKey(entry)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elem, ok := h.buffers[key]
+	if !ok {
+		if h.order.Len() >= tailMaxContexts {
+			oldest := h.order.Front()
+			h.order.Remove(oldest)
+			delete(h.buffers, oldest.Value.(*tailBuffer).key)
+		}
+		elem = h.order.PushBack(&tailBuffer{key: key, entries: make([]*logrus.Entry, 0, h.size)})
+		h.buffers[key] = elem
+	}
+	buf := elem.Value.(*tailBuffer)
+	if len(buf.entries) == h.size {
+		copy(buf.entries, buf.entries[1:])
+		buf.entries = buf.entries[:h.size-1]
+	}
+	buf.entries = append(buf.entries, e)
+}
+
+// take 取出并清空context缓存的日志
+func (h *tailHook) take(key string) []*logrus.Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elem, ok := h.buffers[key]
+	if !ok {
+		return nil
+	}
+	h.order.Remove(elem)
+	delete(h.buffers, key)
+	return elem.Value.(*tailBuffer).entries
+}
+
+// flush 输出缓存的日志，绕过tailFormatter的过滤
+// hook执行时没有持有logger的锁，主输出的内容暂存到pending，由tailFormatter在写入错误日志时一起输出
+func (h *tailHook) flush(entry *logrus.Entry, entries []*logrus.Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	var lines []byte
+	for _, e := range entries {
+		if line, err := h.formatter.Format(e); err == nil {
+			lines = append(lines, line...)
+		}
+		if h.console != nil {
+			if line, err := h.console.formatter.Format(e); err == nil {
+				h.console.write(line)
+			}
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending[entry] = lines
+}
+
+// takePending 取出entry写入前需要输出的缓存日志
+func (h *tailHook) takePending(entry *logrus.Entry) []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	lines, ok := h.pending[entry]
+	if ok {
+		delete(h.pending, entry)
+	}
+	return lines
+}
+
+// attachConsole 关联已添加的控制台hook，控制台同样只输出未缓存的日志
+func (h *tailHook) attachConsole(logger *logrus.Logger) {
+	for _, hook := range logger.Hooks[logrus.ErrorLevel] {
+		if c, ok := hook.(*consoleHook); ok {
+			c.tail = h
+			h.console = c
+		}
+	}
+}
+
+// tailKey 日志所属的context，优先使用request_id，否则使用goroutine id
+func tailKey(entry *logrus.Entry) string {
+	if entry.Context != nil {
+		if id := requestid.Get(entry.Context); id != "" {
+			return "request_id:" + id
+		}
+	}
+	id, _ := goroutineID()
+	return "goid:" + strconv.FormatUint(id, 10)
+}
+
+// tailFormatter 过滤只缓存的日志，不写入主输出
+type tailFormatter struct {
+	logrus.Formatter
+	tail *tailHook
+}
+
+func (f *tailFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.tail.buffered(entry) {
+		return nil, nil
+	}
+	line, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	// Format在logger的锁内执行，缓存的日志与错误日志一次写入，不会与其他日志交错
+	if pending := f.tail.takePending(entry); len(pending) > 0 {
+		return append(pending, line...), nil
+	}
+	return line, nil
+}
@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+	"github.com/kakkk/gopkg/requestid"
+)
+
+func TestTailOnError(t *testing.T) {
+	newTailLogger := func(t *testing.T, n int) (*logrus.Logger, *bytes.Buffer) {
+		l, err := newLogger(WithJSONFormat(true), WithLevel(logrus.InfoLevel), WithTailOnError(n))
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		l.SetOutput(buf)
+		return l, buf
+	}
+
+	t.Run("flush on error", func(t *testing.T) {
+		l, buf := newTailLogger(t, 10)
+
+		l.Debug("step 1")
+		l.Trace("step 2")
+		l.Info("info")
+		assert.NotContains(t, buf.String(), "step")
+
+		l.Error("failed")
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 4)
+		assert.Equal(t, "info", entries[0].Msg)
+		assert.Equal(t, "step 1", entries[1].Msg)
+		assert.Equal(t, "debug", entries[1].Level)
+		assert.Contains(t, entries[1].Fields, "file")
+		assert.Equal(t, "step 2", entries[2].Msg)
+		assert.Equal(t, "failed", entries[3].Msg)
+
+		// 缓存已输出，再次出错不重复输出
+		buf.Reset()
+		l.Error("failed again")
+		entries, err = testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("no error", func(t *testing.T) {
+		l, buf := newTailLogger(t, 10)
+
+		l.Debug("step 1")
+		l.Warn("warn")
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "warn", entries[0].Msg)
+	})
+
+	t.Run("keep last n", func(t *testing.T) {
+		l, buf := newTailLogger(t, 2)
+
+		l.Debug("step 1")
+		l.Debug("step 2")
+		l.Debug("step 3")
+		l.Error("failed")
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "step 2", entries[0].Msg)
+		assert.Equal(t, "step 3", entries[1].Msg)
+	})
+
+	t.Run("per request", func(t *testing.T) {
+		l, buf := newTailLogger(t, 10)
+		ctx1 := requestid.Set(context.Background(), "req-1")
+		ctx2 := requestid.Set(context.Background(), "req-2")
+
+		l.WithContext(ctx1).Debug("req-1 step")
+		l.WithContext(ctx2).Debug("req-2 step")
+		l.WithContext(ctx1).Error("req-1 failed")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "req-1 step", entries[0].Msg)
+		assert.Equal(t, "req-1", entries[0].Fields["request_id"])
+		assert.Equal(t, "req-1 failed", entries[1].Msg)
+	})
+
+	t.Run("per goroutine", func(t *testing.T) {
+		l, buf := newTailLogger(t, 10)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l.Debug("other goroutine")
+		}()
+		<-done
+		l.Error("failed")
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "failed", entries[0].Msg)
+	})
+
+	t.Run("concurrent flush", func(t *testing.T) {
+		l, buf := newTailLogger(t, 10)
+
+		// 缓存的日志与其他goroutine的日志都在logger的锁内写入，不会交错
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx := requestid.Set(context.Background(), fmt.Sprintf("req-%d", i))
+				l.WithContext(ctx).Debug("step")
+				l.WithContext(ctx).Error("failed")
+			}()
+		}
+		wg.Wait()
+
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 20)
+		for i := 0; i < len(entries); i += 2 {
+			assert.Equal(t, "step", entries[i].Msg)
+			assert.Equal(t, "failed", entries[i+1].Msg)
+			assert.Equal(t, entries[i].Fields["request_id"], entries[i+1].Fields["request_id"])
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l, buf := newTailLogger(t, 0)
+
+		l.Debug("step 1")
+		l.Error("failed")
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, logrus.InfoLevel, l.GetLevel())
+	})
+}
@@ -0,0 +1,128 @@
+// Package testutil 提供日志相关的测试辅助工具
+// 将日志以JSON格式写入内存，并解析为结构化的Entry，便于在测试中断言日志内容
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Entry 解析后的单条日志
+type Entry struct {
+	Level  string                 // 日志级别，如info、error
+	Msg    string                 // 日志内容
+	Time   string                 // 日志时间
+	Fields map[string]interface{} // 其他字段，数字类型解析为json.Number
+}
+
+// CaptureLogger 将日志写入内存的logger，用于测试
+type CaptureLogger struct {
+	*logrus.Logger
+	buf *syncBuffer
+}
+
+// NewCaptureLogger 创建一个将日志以JSON格式写入内存的logger
+// 默认日志级别为Trace，可通过SetLevel调整
+func NewCaptureLogger() *CaptureLogger {
+	buf := &syncBuffer{}
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	l.SetOutput(buf)
+	l.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+	})
+	return &CaptureLogger{
+		Logger: l,
+		buf:    buf,
+	}
+}
+
+// Entries 返回目前为止写入的所有日志
+func (c *CaptureLogger) Entries() ([]Entry, error) {
+	return ParseEntries(bytes.NewReader(c.buf.Bytes()))
+}
+
+// Reset 清空已写入的日志
+func (c *CaptureLogger) Reset() {
+	c.buf.Reset()
+}
+
+// ParseEntries 解析JSON-lines格式的日志，空行会被忽略
+func ParseEntries(r io.Reader) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		entry, err := parseEntry(data)
+		if err != nil {
+			return nil, fmt.Errorf("[testutil] parse line %d fail: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[testutil] read log fail: %w", err)
+	}
+	return entries, nil
+}
+
+func parseEntry(data []byte) (Entry, error) {
+	fields := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		Level: popString(fields, logrus.FieldKeyLevel),
+		Msg:   popString(fields, logrus.FieldKeyMsg),
+		Time:  popString(fields, logrus.FieldKeyTime),
+	}
+	entry.Fields = fields
+	return entry, nil
+}
+
+func popString(fields map[string]interface{}, key string) string {
+	val, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	delete(fields, key)
+	s, _ := val.(string)
+	return s
+}
+
+// syncBuffer 并发安全的bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
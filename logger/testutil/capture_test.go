@@ -0,0 +1,73 @@
+package testutil
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureLogger(t *testing.T) {
+	l := NewCaptureLogger()
+	l.WithFields(logrus.Fields{
+		"str":   "hello",
+		"int":   42,
+		"float": 3.14,
+		"bool":  true,
+		"slice": []string{"a", "b"},
+		"map":   map[string]int{"x": 1},
+	}).Info("first")
+	l.WithError(errors.New("boom")).Error("second")
+	l.Debug("third")
+
+	entries, err := l.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	first := entries[0]
+	assert.Equal(t, "info", first.Level)
+	assert.Equal(t, "first", first.Msg)
+	assert.NotEmpty(t, first.Time)
+	assert.Equal(t, "hello", first.Fields["str"])
+	assert.Equal(t, json.Number("42"), first.Fields["int"])
+	assert.Equal(t, json.Number("3.14"), first.Fields["float"])
+	assert.Equal(t, true, first.Fields["bool"])
+	assert.Equal(t, []interface{}{"a", "b"}, first.Fields["slice"])
+	assert.Equal(t, map[string]interface{}{"x": json.Number("1")}, first.Fields["map"])
+	assert.NotContains(t, first.Fields, "level")
+	assert.NotContains(t, first.Fields, "msg")
+
+	assert.Equal(t, "error", entries[1].Level)
+	assert.Equal(t, "boom", entries[1].Fields[logrus.ErrorKey])
+	assert.Equal(t, "debug", entries[2].Level)
+	assert.Empty(t, entries[2].Fields)
+
+	l.Reset()
+	entries, err = l.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestParseEntries(t *testing.T) {
+	t.Run("skip empty lines", func(t *testing.T) {
+		input := `{"level":"warning","msg":"a","k":"v"}
+
+{"level":"info","msg":"b"}
+`
+		entries, err := ParseEntries(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "warning", entries[0].Level)
+		assert.Equal(t, "v", entries[0].Fields["k"])
+		assert.Equal(t, "b", entries[1].Msg)
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		_, err := ParseEntries(strings.NewReader("{\"msg\":\"a\"}\nnot json\n"))
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxWriterLineSize 单行最大长度，超过时不等换行直接输出，避免没有换行的输出无限占用内存
+const maxWriterLineSize = 64 * 1024
+
+// NewWriter 返回一个io.WriteCloser，写入的内容按行输出到全局logger的指定级别
+// 用于捕获exec.Cmd、http.Server.ErrorLog等的输出，不足一行的内容缓存到收到换行，Close时输出剩余内容
+func NewWriter(level logrus.Level) io.WriteCloser {
+	return &lineWriter{level: level}
+}
+
+// lineWriter 按行切分写入的内容，每行作为一条日志
+type lineWriter struct {
+	level logrus.Level
+	mu    sync.Mutex
+	buf   []byte // 不足一行的内容
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) >= maxWriterLineSize {
+				w.flush()
+			}
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close 输出剩余不足一行的内容
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	return nil
+}
+
+// flush 输出缓存的一行，忽略空行
+func (w *lineWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte("\r"))
+	if len(line) > 0 {
+		globalLogger.Log(w.level, string(line))
+	}
+	w.buf = w.buf[:0]
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kakkk/gopkg/logger/testutil"
+)
+
+func TestNewWriter(t *testing.T) {
+	buf, cleanup := setupTestLogger(t)
+	defer cleanup()
+	globalLogger.SetFormatter(&logrus.JSONFormatter{})
+
+	msgs := func(t *testing.T) []string {
+		entries, err := testutil.ParseEntries(buf)
+		require.NoError(t, err)
+		res := make([]string, 0, len(entries))
+		for _, e := range entries {
+			res = append(res, e.Level+":"+e.Msg)
+		}
+		buf.Reset()
+		return res
+	}
+
+	t.Run("multi line", func(t *testing.T) {
+		w := NewWriter(logrus.WarnLevel)
+		n, err := w.Write([]byte("line 1\nline 2\r\n\nline 3\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 23, n)
+		assert.Equal(t, []string{"warning:line 1", "warning:line 2", "warning:line 3"}, msgs(t))
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		w := NewWriter(logrus.InfoLevel)
+		_, _ = w.Write([]byte("hel"))
+		_, _ = w.Write([]byte("lo"))
+		assert.Empty(t, msgs(t))
+
+		_, _ = w.Write([]byte(" world\nnext"))
+		assert.Equal(t, []string{"info:hello world"}, msgs(t))
+
+		// Close输出剩余内容
+		require.NoError(t, w.Close())
+		assert.Equal(t, []string{"info:next"}, msgs(t))
+		require.NoError(t, w.Close())
+		assert.Empty(t, msgs(t))
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		w := NewWriter(logrus.InfoLevel)
+		_, _ = w.Write([]byte(strings.Repeat("a", maxWriterLineSize+1)))
+		got := msgs(t)
+		require.Len(t, got, 1)
+		assert.Len(t, got[0], len("info:")+maxWriterLineSize+1)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			t.Skip("sh not found")
+		}
+		w := NewWriter(logrus.ErrorLevel)
+		cmd := exec.Command(sh, "-c", "echo out 1; echo out 2 >&2; printf tail")
+		cmd.Stdout = w
+		cmd.Stderr = w
+		require.NoError(t, cmd.Run())
+		require.NoError(t, w.Close())
+
+		got := msgs(t)
+		assert.ElementsMatch(t, []string{"error:out 1", "error:out 2", "error:tail"}, got)
+	})
+}
@@ -32,3 +32,32 @@ func Set(ctx context.Context, requestID string) context.Context {
 	ctx = context.WithValue(ctx, keyRequestID, requestID)
 	return ctx
 }
+
+// MaxLength 外部传入的requestID最大长度
+const MaxLength = 128
+
+// With 设置外部传入(如X-Request-ID请求头)的requestID，不合法时使用新生成的requestID
+func With(ctx context.Context, requestID string) context.Context {
+	return Set(ctx, Normalize(requestID))
+}
+
+// Normalize 校验外部传入的requestID，为空、超过MaxLength或包含空格、换行等不可见字符时返回新生成的requestID，防止日志伪造
+func Normalize(requestID string) string {
+	if !Valid(requestID) {
+		return Gen()
+	}
+	return requestID
+}
+
+// Valid requestID非空、不超过MaxLength且只包含可见ASCII字符
+func Valid(requestID string) bool {
+	if requestID == "" || len(requestID) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if c := requestID[i]; c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
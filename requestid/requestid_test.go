@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestWith 测试外部传入的requestID校验
+func TestWith(t *testing.T) {
+	valid := []string{
+		"req-1",
+		Gen(),
+		"0af7651916cd43dd8448eb211c80319c",
+		strings.Repeat("a", MaxLength),
+	}
+	for _, id := range valid {
+		if got := Get(With(context.Background(), id)); got != id {
+			t.Errorf("With(%q) = %q, want unchanged", id, got)
+		}
+	}
+
+	malicious := []string{
+		"",
+		"req-1\nlevel=error msg=forged",
+		"req-1\r\nX-Injected: 1",
+		"req 1",
+		"req-1\t",
+		"req-\x00-1",
+		"req-\x1b[31m-1",
+		"req-\x7f",
+		"请求-1",
+		strings.Repeat("a", MaxLength+1),
+	}
+	for _, id := range malicious {
+		got := Get(With(context.Background(), id))
+		if got == id {
+			t.Errorf("With(%q) kept the invalid id", id)
+		}
+		if !Valid(got) || len(got) != 32 {
+			t.Errorf("With(%q) = %q, want a generated id", id, got)
+		}
+	}
+}
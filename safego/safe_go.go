@@ -23,10 +23,27 @@ func GoFn(ctx context.Context, fn func() error) func() error {
 	return func() (err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				logger.Ctx(ctx).Errorf("[safe.GoFn] panic recovered: %v, stack:\n%v", r, string(debug.Stack()))
-				err = fmt.Errorf("panic recovered: %v", r)
+				stack := debug.Stack()
+				logger.Ctx(ctx).Errorf("[safe.GoFn] panic recovered: %v, stack:\n%v", r, string(stack))
+				err = &PanicError{Value: r, Stack: stack}
 			}
 		}()
 		return fn()
 	}
 }
+
+// PanicError GoFn中fn panic时返回的错误，可通过errors.As获取panic的值和调用栈
+type PanicError struct {
+	Value interface{} // recover()得到的值
+	Stack []byte      // panic时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic recovered: %v", e.Value)
+}
+
+// Unwrap panic的值为error时返回该error，支持errors.Is判断
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
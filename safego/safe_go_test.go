@@ -3,6 +3,7 @@ package safego
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -68,4 +69,32 @@ func TestGoFn(t *testing.T) {
 			t.Errorf("expected error %q, got %q", expected, err.Error())
 		}
 	})
+
+	t.Run("panic error", func(t *testing.T) {
+		fn := GoFn(ctx, func() error {
+			panic(42)
+		})
+		err := fn()
+		var pErr *PanicError
+		if !errors.As(err, &pErr) {
+			t.Fatalf("expected *PanicError, got %T", err)
+		}
+		if pErr.Value != 42 {
+			t.Errorf("expected panic value 42, got %v", pErr.Value)
+		}
+		if !strings.Contains(string(pErr.Stack), "TestGoFn") {
+			t.Errorf("expected stack containing TestGoFn, got %q", pErr.Stack)
+		}
+	})
+
+	t.Run("panic with error value", func(t *testing.T) {
+		testErr := errors.New("test error")
+		fn := GoFn(ctx, func() error {
+			panic(testErr)
+		})
+		err := fn()
+		if !errors.Is(err, testErr) {
+			t.Errorf("expected error %v, got %v", testErr, err)
+		}
+	})
 }